@@ -14,11 +14,14 @@ require (
 	github.com/go-yaml/yaml v2.1.0+incompatible // indirect
 	github.com/golang/mock v0.0.0-20190508161146-9fa652df1129
 	github.com/google/uuid v1.1.1
+	github.com/klauspost/compress v1.9.8
+	github.com/oschwald/maxminddb-golang v1.6.0
 	github.com/rs/xhandler v0.0.0-20151224012956-d9d9599b6aaf // indirect
 	github.com/rs/xstats v0.0.0-20170813190920-c67367528e16
 	github.com/rs/zerolog v1.14.3 // indirect
 	github.com/spf13/cast v1.3.0 // indirect
-	github.com/stretchr/testify v1.3.0
+	github.com/stretchr/testify v1.4.0
 	golang.org/x/net v0.0.0-20190514140710-3ec191127204 // indirect
-	gonum.org/v1/gonum v0.0.0-20181210083604-572d9101fe4f // indirect
+	gonum.org/v1/gonum v0.0.0-20181210083604-572d9101fe4f
+	gopkg.in/yaml.v2 v2.2.2
 )
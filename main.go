@@ -2,15 +2,31 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/asecurityteam/logevent"
 	"github.com/asecurityteam/runhttp"
 	"github.com/asecurityteam/settings"
 	grapherd "github.com/asecurityteam/vpcflow-grapherd/pkg"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/backfill"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/render"
 	"github.com/go-chi/chi"
+	"github.com/rs/xstats"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backfill":
+			os.Exit(runBackfill(os.Args[2:]))
+		case "render":
+			os.Exit(runRender(os.Args[2:]))
+		}
+	}
+
 	router := chi.NewRouter()
 	service := &grapherd.Service{}
 	if err := service.BindRoutes(router); err != nil {
@@ -28,8 +44,65 @@ func main() {
 		panic(err.Error())
 	}
 
+	// Run the scheduler and the retention job in the background, with the runtime logger and stats.
+	ctx, cancel := context.WithCancel(xstats.NewContext(logevent.NewContext(context.Background(), rt.Logger), rt.Stats))
+	defer cancel()
+	go service.RunScheduler(ctx)
+	go service.RunRetention(ctx)
+
 	// Run the HTTP server.
 	if err := rt.Run(); err != nil {
 		panic(err.Error())
 	}
 }
+
+// runBackfill graphs a range of past windows without the HTTP server, and returns the exit code of the command.
+// The backfill stops after the windows in progress when the process is interrupted.
+func runBackfill(args []string) int {
+	opts, err := backfill.ParseArgs(args, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 2
+	}
+
+	ctx, cancel := context.WithCancel(logevent.NewContext(context.Background(), logevent.New(logevent.Config{Output: os.Stderr})))
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	service := &grapherd.Service{}
+	if err := service.Backfill(ctx, opts, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	return 0
+}
+
+// runRender writes the graph of a digest file, or of stdin, to stdout, and returns the exit code of the command
+func runRender(args []string) int {
+	opts, err := render.ParseArgs(args, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 2
+	}
+
+	digest := os.Stdin
+	if !opts.Stdin() {
+		if digest, err = os.Open(opts.Input); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+	}
+	defer digest.Close()
+
+	service := &grapherd.Service{}
+	if err := service.Render(context.Background(), digest, opts, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,326 @@
+package annotator
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+const (
+	// KindNewEdge identifies connections which were never observed during the baseline window
+	KindNewEdge = "new-edge"
+
+	// KindUnusualPort identifies connections to a destination port which was never observed on
+	// that destination during the baseline window
+	KindUnusualPort = "unusual-port"
+
+	// KindHighVolume identifies known connections whose byte rate far exceeds their baseline rate
+	KindHighVolume = "high-volume"
+
+	// BaselineSummarySuffix is appended to a graph ID to form the key of the graph's baseline summary
+	BaselineSummarySuffix = ".baseline.json"
+
+	// DefaultBaselineWindow is the default length of the rolling baseline
+	DefaultBaselineWindow = 14 * 24 * time.Hour
+
+	// DefaultVolumeFactor is the default multiple of the baseline byte rate above which an edge is flagged
+	DefaultVolumeFactor = 10
+
+	baselinePrefix = "baseline/"
+	accountPrefix  = "account-"
+)
+
+// Baseline is an Annotator which maintains a rolling baseline of the connections observed in each
+// VPC, and flags the edges of new graphs which deviate from it. Edges to a destination never contacted
+// on that port are flagged as new edges, destination ports never seen on a destination are flagged as
+// unusual ports, and known edges carrying far more bytes per second than usual are flagged as high volume.
+//
+// The VPC of an edge is given by the graph.AttrVPC attribute of its source node or, failing that, of its
+// destination node, as set by an Enricher. Edges whose nodes have no VPC are baselined per AWS account.
+//
+// The baseline of each VPC is persisted in Storage, and is only updated once the annotated graph is stored,
+// see types.Committer. Updates are serialized with Lock, so that concurrent jobs do not lose each other's
+// observations. A summary of the findings for each graph is stored alongside the graph using
+// BaselineSummarySuffix. VPCs without any baseline yet are only learned, and never flagged.
+type Baseline struct {
+	Storage types.Storage
+	// Window is how long observations are kept in the baseline. Defaults to DefaultBaselineWindow.
+	Window time.Duration
+	// VolumeFactor is the multiple of the baseline byte rate above which an edge is flagged as high
+	// volume. Defaults to DefaultVolumeFactor.
+	VolumeFactor float64
+	// Lock serializes the updates of each baseline. Defaults to a storage.Lock of Storage.
+	Lock *storage.Lock
+}
+
+// BaselineSummary is the summary of the baseline findings for a single graph
+type BaselineSummary struct {
+	GraphID string    `json:"graphID"`
+	Start   time.Time `json:"start"`
+	Stop    time.Time `json:"stop"`
+	// Baselines lists the baselines the graph was compared to, which are either VPC IDs or, for the
+	// edges without a VPC, AWS account IDs prefixed with account-
+	Baselines []string        `json:"baselines"`
+	Counts    map[string]int  `json:"counts"`
+	Findings  []types.Finding `json:"findings"`
+}
+
+// volume is the traffic observed on a connection in a single graph
+type volume struct {
+	Bytes   int64 `json:"bytes"`
+	Seconds int64 `json:"seconds"`
+}
+
+// baselineDocument is the persisted baseline of a VPC
+type baselineDocument struct {
+	// Graphs records the graphs applied to the baseline, along with the end of their window
+	Graphs map[string]time.Time `json:"graphs"`
+	// Edges holds the volume of each connection in each graph, keyed by connection then by graph ID, so
+	// that regenerating a graph replaces its observations rather than counting them twice
+	Edges map[string]map[string]*volume `json:"edges"`
+}
+
+// Annotate flags the edges of the graph which deviate from the baseline of their VPC. Observations of a
+// previous version of the graph are ignored, so that a regenerated graph is compared to the same baseline.
+func (b *Baseline) Annotate(ctx context.Context, id string, g *graph.Graph) ([]types.Finding, error) {
+	start, stop := g.Window()
+	summary := BaselineSummary{
+		GraphID:   id,
+		Start:     start,
+		Stop:      stop,
+		Baselines: []string{},
+		Counts:    make(map[string]int),
+		Findings:  []types.Finding{},
+	}
+	byBaseline := groupBaselines(g)
+	for _, name := range sortedGroups(byBaseline) {
+		doc, err := b.load(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range b.compare(doc, id, name, groupConnections(g, byBaseline[name])) {
+			summary.Counts[f.Kind]++
+			summary.Findings = append(summary.Findings, f)
+		}
+		summary.Baselines = append(summary.Baselines, name)
+	}
+	if err := storage.StoreJSON(ctx, b.Storage, id+BaselineSummarySuffix, summary); err != nil {
+		return nil, err
+	}
+	return summary.Findings, nil
+}
+
+// Commit adds the stored graph to the baseline of its VPCs, replacing the observations of any previous
+// version of the graph
+func (b *Baseline) Commit(ctx context.Context, id string, g *graph.Graph) error {
+	_, stop := g.Window()
+	lock := b.Lock
+	if lock == nil {
+		lock = &storage.Lock{Storage: b.Storage}
+	}
+	byBaseline := groupBaselines(g)
+	for _, name := range sortedGroups(byBaseline) {
+		connections := groupConnections(g, byBaseline[name])
+		err := lock.Do(ctx, baselineKey(name), func() error {
+			doc, err := b.load(ctx, name)
+			if err != nil {
+				return err
+			}
+			b.update(doc, id, stop, connections)
+			return storage.StoreJSON(ctx, b.Storage, baselineKey(name), doc)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load returns the stored baseline, which is empty if it was never stored
+func (b *Baseline) load(ctx context.Context, name string) (*baselineDocument, error) {
+	doc := &baselineDocument{}
+	if _, err := storage.LoadJSON(ctx, b.Storage, baselineKey(name), doc); err != nil {
+		return nil, err
+	}
+	if doc.Graphs == nil {
+		doc.Graphs = make(map[string]time.Time)
+	}
+	if doc.Edges == nil {
+		doc.Edges = make(map[string]map[string]*volume)
+	}
+	return doc, nil
+}
+
+// compare flags the connections which deviate from the baseline, ignoring the observations of the graph
+// itself, and returns the resulting findings
+func (b *Baseline) compare(doc *baselineDocument, id, name string, connections []*connection) []types.Finding {
+	learned := false
+	for graphID := range doc.Graphs {
+		if graphID != id {
+			learned = true
+			break
+		}
+	}
+	if !learned {
+		return nil
+	}
+	factor := b.VolumeFactor
+	if factor <= 0 {
+		factor = DefaultVolumeFactor
+	}
+	ports := make(map[string]bool)
+	for key, graphs := range doc.Edges {
+		if observedElsewhere(graphs, id) {
+			ports[portKey(key)] = true
+		}
+	}
+	var findings []types.Finding
+	for _, c := range connections {
+		key := c.key()
+		graphs := doc.Edges[key]
+		if !observedElsewhere(graphs, id) {
+			c.flag(graph.AttrAnomaly, KindNewEdge)
+			findings = append(findings, b.finding(c, id, name, KindNewEdge,
+				fmt.Sprintf("%s connected to %s on port %d for the first time", c.source, c.destination, c.port)))
+			if !c.response && !ports[portKey(key)] {
+				c.flag(graph.AttrAnomaly, KindUnusualPort)
+				findings = append(findings, b.finding(c, id, name, KindUnusualPort,
+					fmt.Sprintf("port %d was never used on %s", c.port, c.destination)))
+			}
+			continue
+		}
+		var baseBytes, baseSeconds int64
+		for graphID, v := range graphs {
+			if graphID != id {
+				baseBytes += v.Bytes
+				baseSeconds += v.Seconds
+			}
+		}
+		if baseBytes == 0 || baseSeconds == 0 {
+			continue
+		}
+		baseRate := float64(baseBytes) / float64(baseSeconds)
+		rate := float64(c.bytes) / float64(c.seconds)
+		if rate > factor*baseRate {
+			c.flag(graph.AttrAnomaly, KindHighVolume)
+			f := b.finding(c, id, name, KindHighVolume,
+				fmt.Sprintf("%s sent %.0f bytes/s to %s on port %d, compared to a baseline of %.0f bytes/s",
+					c.source, rate, c.destination, c.port, baseRate))
+			f.Attributes["rate"] = strconv.FormatFloat(rate, 'f', 0, 64)
+			f.Attributes["baseline_rate"] = strconv.FormatFloat(baseRate, 'f', 0, 64)
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// finding returns a finding of the given kind describing the connection, which records the VPC of the
+// baseline it deviates from
+func (b *Baseline) finding(c *connection, id, name, kind, description string) types.Finding {
+	f := c.finding(id, c.edges[0].AccountID, kind, description)
+	if !strings.HasPrefix(name, accountPrefix) {
+		f.Key = strings.Join([]string{kind, name, c.key()}, "|")
+		f.Attributes["vpcID"] = name
+	}
+	return f
+}
+
+// update adds the connections of a graph to the baseline, replacing the observations of any previous version
+// of the graph, and drops the graphs which fell out of the baseline window
+func (b *Baseline) update(doc *baselineDocument, id string, stop time.Time, connections []*connection) {
+	window := b.Window
+	if window <= 0 {
+		window = DefaultBaselineWindow
+	}
+	dropGraphs(doc, map[string]bool{id: true})
+	for _, c := range connections {
+		graphs, ok := doc.Edges[c.key()]
+		if !ok {
+			graphs = make(map[string]*volume)
+			doc.Edges[c.key()] = graphs
+		}
+		graphs[id] = &volume{Bytes: c.bytes, Seconds: c.seconds}
+	}
+	doc.Graphs[id] = stop
+
+	// the window is counted back from the latest graph, so that backfilling past graphs does not expire
+	// the observations of the recent ones
+	latest := stop
+	for _, end := range doc.Graphs {
+		if end.After(latest) {
+			latest = end
+		}
+	}
+	cutoff := latest.Add(-window)
+	expired := make(map[string]bool)
+	for graphID, end := range doc.Graphs {
+		if end.Before(cutoff) {
+			expired[graphID] = true
+		}
+	}
+	dropGraphs(doc, expired)
+}
+
+// dropGraphs removes the given graphs, and their observations, from the baseline
+func dropGraphs(doc *baselineDocument, ids map[string]bool) {
+	if len(ids) == 0 {
+		return
+	}
+	for key, graphs := range doc.Edges {
+		for graphID := range graphs {
+			if ids[graphID] {
+				delete(graphs, graphID)
+			}
+		}
+		if len(graphs) == 0 {
+			delete(doc.Edges, key)
+		}
+	}
+	for graphID := range ids {
+		delete(doc.Graphs, graphID)
+	}
+}
+
+// observedElsewhere reports whether a connection was observed by any graph other than id
+func observedElsewhere(graphs map[string]*volume, id string) bool {
+	for graphID := range graphs {
+		if graphID != id {
+			return true
+		}
+	}
+	return false
+}
+
+// groupBaselines groups the edges of the graph by the baseline they belong to, which is the VPC of their
+// source or destination node, or their account if neither node has a VPC
+func groupBaselines(g *graph.Graph) map[string][]*graph.Edge {
+	byBaseline := make(map[string][]*graph.Edge)
+	for _, e := range g.Edges {
+		name := accountPrefix + e.AccountID
+		for _, id := range []string{e.From, e.To} {
+			if n, ok := g.Nodes[id]; ok && n.Attrs[graph.AttrVPC] != "" {
+				name = n.Attrs[graph.AttrVPC]
+				break
+			}
+		}
+		byBaseline[name] = append(byBaseline[name], e)
+	}
+	return byBaseline
+}
+
+// baselineKey returns the key of a baseline in Storage
+func baselineKey(name string) string {
+	return baselinePrefix + name + ".json"
+}
+
+// portKey converts a connection key into a destination|port|protocol key
+func portKey(key string) string {
+	parts := strings.SplitN(key, "|", 2)
+	return parts[len(parts)-1]
+}
@@ -0,0 +1,259 @@
+package annotator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/go-vpcflow"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage/storagetest"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const account = "123456789010"
+
+var day = time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+
+type flow struct {
+	src, dst         string
+	srcPort, dstPort int
+	bytes            int64
+}
+
+// newFlowGraph returns a graph of the flows observed during the hour starting at start
+func newFlowGraph(start time.Time, flows ...flow) *graph.Graph {
+	g := graph.New()
+	for _, f := range flows {
+		g.Edges = append(g.Edges, &graph.Edge{
+			From:      g.AddNode(f.src).ID,
+			To:        g.AddNode(f.dst).ID,
+			AccountID: account,
+			SrcPort:   f.srcPort,
+			DstPort:   f.dstPort,
+			Protocol:  6,
+			Bytes:     f.bytes,
+			Start:     start,
+			End:       start.Add(time.Hour),
+			Action:    graph.ActionAccept,
+			Attrs:     make(map[string]string),
+		})
+	}
+	return g
+}
+
+// newDigestGraph converts digest lines of the form "src dst srcPort dstPort bytes", observed during the hour
+// starting at start, as the go-vpcflow digester and DOT converter do. The digester records the ephemeral
+// ports of clients as 0.
+func newDigestGraph(t *testing.T, start time.Time, lines ...string) *graph.Graph {
+	var digest strings.Builder
+	for _, line := range lines {
+		f := strings.Fields(line)
+		require.Len(t, f, 5)
+		fmt.Fprintf(&digest, "2 %s eni-abc123de %s %s %s %s 6 10 %s %d %d ACCEPT OK\n",
+			account, f[0], f[1], f[2], f[3], f[4], start.Unix(), start.Add(time.Hour).Unix())
+	}
+	r, err := vpcflow.DOTConverter(ioutil.NopCloser(strings.NewReader(digest.String())))
+	require.Nil(t, err)
+	defer r.Close()
+	g, err := graph.FromDOT(r)
+	require.Nil(t, err)
+	return g
+}
+
+// digestEdge returns the edge of the graph from src to dst
+func digestEdge(t *testing.T, g *graph.Graph, src, dst string) *graph.Edge {
+	for _, e := range g.Edges {
+		if e.From == graph.NodeID(src) && e.To == graph.NodeID(dst) {
+			return e
+		}
+	}
+	require.FailNow(t, "missing edge", "%s -> %s", src, dst)
+	return nil
+}
+
+func newBaseline(memory *storagetest.Memory) *Baseline {
+	return &Baseline{Storage: memory, Lock: &storage.Lock{Storage: memory, Wait: time.Millisecond, Settle: 20 * time.Millisecond}}
+}
+
+// learn annotates the graph, then commits it as the grapher does once the graph is stored
+func learn(t *testing.T, b *Baseline, id string, g *graph.Graph) []types.Finding {
+	findings, err := b.Annotate(context.Background(), id, g)
+	require.Nil(t, err)
+	require.Nil(t, b.Commit(context.Background(), id, g))
+	return findings
+}
+
+func loadBaseline(t *testing.T, storage *storagetest.Memory, name string) baselineDocument {
+	var doc baselineDocument
+	require.Nil(t, json.Unmarshal(storage.Objects["baseline/"+name+".json"], &doc))
+	return doc
+}
+
+func TestBaselineLearnsFirstGraph(t *testing.T) {
+	storage := storagetest.NewMemory()
+	b := newBaseline(storage)
+	g := newFlowGraph(day, flow{"10.0.0.1", "10.0.0.2", 40000, 443, 3600})
+
+	findings, err := b.Annotate(context.Background(), "first", g)
+	require.Nil(t, err)
+	assert.Empty(t, findings)
+	assert.Empty(t, g.Edges[0].Attrs[graph.AttrAnomaly])
+	assert.Contains(t, storage.Objects, "first"+BaselineSummarySuffix)
+	// the baseline is only updated once the graph is stored and committed
+	assert.NotContains(t, storage.Objects, "baseline/account-"+account+".json")
+
+	require.Nil(t, b.Commit(context.Background(), "first", g))
+	assert.Contains(t, loadBaseline(t, storage, "account-"+account).Graphs, "first")
+}
+
+func TestBaselineAnomalies(t *testing.T) {
+	storage := storagetest.NewMemory()
+	b := newBaseline(storage)
+	learn(t, b, "first", newFlowGraph(day,
+		flow{"10.0.0.1", "10.0.0.2", 40000, 443, 3600},
+		flow{"10.0.0.2", "10.0.0.1", 443, 40000, 3600},
+	))
+
+	g := newFlowGraph(day.Add(24*time.Hour),
+		flow{"10.0.0.1", "10.0.0.2", 40001, 443, 360000},
+		flow{"10.0.0.2", "10.0.0.1", 443, 40001, 3600},
+		flow{"10.0.0.3", "10.0.0.2", 40000, 443, 3600},
+		flow{"10.0.0.1", "10.0.0.2", 40000, 22, 3600},
+	)
+	findings := learn(t, b, "second", g)
+
+	assert.Equal(t, "high-volume", g.Edges[0].Attrs[graph.AttrAnomaly])
+	assert.Empty(t, g.Edges[1].Attrs[graph.AttrAnomaly])
+	assert.Equal(t, "new-edge", g.Edges[2].Attrs[graph.AttrAnomaly])
+	assert.Equal(t, "new-edge,unusual-port", g.Edges[3].Attrs[graph.AttrAnomaly])
+	require.Len(t, findings, 4)
+	assert.Equal(t, "second", findings[0].GraphID)
+	assert.Equal(t, account, findings[0].Attributes["accountID"])
+
+	var summary BaselineSummary
+	require.Nil(t, json.Unmarshal(storage.Objects["second"+BaselineSummarySuffix], &summary))
+	assert.Equal(t, []string{"account-" + account}, summary.Baselines)
+	assert.Equal(t, map[string]int{KindHighVolume: 1, KindNewEdge: 2, KindUnusualPort: 1}, summary.Counts)
+	assert.Len(t, summary.Findings, 4)
+}
+
+func TestBaselineDigestResponses(t *testing.T) {
+	storage := storagetest.NewMemory()
+	b := newBaseline(storage)
+	learn(t, b, "first", newDigestGraph(t, day, "10.0.0.1 10.0.0.2 0 443 3600", "10.0.0.2 10.0.0.1 443 0 3600"))
+
+	// the responses to a new client are a new edge, but they are keyed by the service port, which is not unusual
+	g := newDigestGraph(t, day.Add(time.Hour), "10.0.0.3 10.0.0.2 0 443 3600", "10.0.0.2 10.0.0.3 443 0 3600")
+	findings := learn(t, b, "second", g)
+	assert.Equal(t, "new-edge", digestEdge(t, g, "10.0.0.3", "10.0.0.2").Attrs[graph.AttrAnomaly])
+	assert.Equal(t, "new-edge", digestEdge(t, g, "10.0.0.2", "10.0.0.3").Attrs[graph.AttrAnomaly])
+	require.Len(t, findings, 2)
+	for _, finding := range findings {
+		assert.Equal(t, KindNewEdge, finding.Kind)
+		assert.Equal(t, "443", finding.Attributes["port"])
+	}
+}
+
+func TestBaselinePerVPC(t *testing.T) {
+	storage := storagetest.NewMemory()
+	b := newBaseline(storage)
+	inVPC := func(g *graph.Graph, vpc string) *graph.Graph {
+		for _, n := range g.Nodes {
+			n.Attrs[graph.AttrVPC] = vpc
+		}
+		return g
+	}
+	learn(t, b, "first", inVPC(newFlowGraph(day, flow{"10.0.0.1", "10.0.0.2", 40000, 443, 3600}), "vpc-a"))
+	learn(t, b, "second", inVPC(newFlowGraph(day, flow{"10.0.0.3", "10.0.0.4", 40000, 443, 3600}), "vpc-b"))
+	assert.Contains(t, loadBaseline(t, storage, "vpc-a").Edges, "10.0.0.1|10.0.0.2|443|6")
+	assert.NotContains(t, loadBaseline(t, storage, "vpc-b").Edges, "10.0.0.1|10.0.0.2|443|6")
+
+	// the same connection is known in the VPC which observed it, and new in the other one
+	assert.Empty(t, learn(t, b, "third", inVPC(newFlowGraph(day.Add(time.Hour), flow{"10.0.0.1", "10.0.0.2", 40000, 443, 3600}), "vpc-a")))
+	findings := learn(t, b, "fourth", inVPC(newFlowGraph(day.Add(time.Hour), flow{"10.0.0.1", "10.0.0.2", 40000, 443, 3600}), "vpc-b"))
+	require.Len(t, findings, 2)
+	assert.Equal(t, KindNewEdge, findings[0].Kind)
+	assert.Equal(t, "vpc-b", findings[0].Attributes["vpcID"])
+}
+
+func TestBaselineRegeneratedGraphReplaced(t *testing.T) {
+	storage := storagetest.NewMemory()
+	b := newBaseline(storage)
+	learn(t, b, "first", newFlowGraph(day, flow{"10.0.0.1", "10.0.0.2", 40000, 443, 3600}))
+	learn(t, b, "second", newFlowGraph(day.Add(time.Hour), flow{"10.0.0.1", "10.0.0.2", 40000, 443, 3600}))
+
+	// the regenerated graph is compared without its previous observations, which it then replaces
+	findings := learn(t, b, "second", newFlowGraph(day.Add(time.Hour),
+		flow{"10.0.0.1", "10.0.0.2", 40000, 443, 7200},
+		flow{"10.0.0.3", "10.0.0.2", 40000, 443, 3600},
+	))
+	require.Len(t, findings, 1)
+	assert.Equal(t, KindNewEdge, findings[0].Kind)
+
+	doc := loadBaseline(t, storage, "account-"+account)
+	assert.Equal(t, int64(3600), doc.Edges["10.0.0.1|10.0.0.2|443|6"]["first"].Bytes)
+	assert.Equal(t, int64(7200), doc.Edges["10.0.0.1|10.0.0.2|443|6"]["second"].Bytes)
+	assert.Contains(t, doc.Edges, "10.0.0.3|10.0.0.2|443|6")
+}
+
+func TestBaselineWindowExpires(t *testing.T) {
+	storage := storagetest.NewMemory()
+	b := newBaseline(storage)
+	b.Window = 48 * time.Hour
+	learn(t, b, "first", newFlowGraph(day, flow{"10.0.0.1", "10.0.0.2", 40000, 443, 3600}))
+	learn(t, b, "second", newFlowGraph(day.Add(72*time.Hour), flow{"10.0.0.3", "10.0.0.2", 40000, 443, 3600}))
+	// backfilled graphs do not expire the recent ones
+	learn(t, b, "third", newFlowGraph(day.Add(-72*time.Hour), flow{"10.0.0.4", "10.0.0.2", 40000, 443, 3600}))
+
+	doc := loadBaseline(t, storage, "account-"+account)
+	assert.NotContains(t, doc.Edges, "10.0.0.1|10.0.0.2|443|6")
+	assert.NotContains(t, doc.Edges, "10.0.0.4|10.0.0.2|443|6")
+	assert.Contains(t, doc.Edges, "10.0.0.3|10.0.0.2|443|6")
+	assert.Equal(t, []string{"second"}, sortedGraphs(doc))
+}
+
+func TestBaselineConcurrentCommits(t *testing.T) {
+	storage := storagetest.NewMemory()
+	b := newBaseline(storage)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			assert.Nil(t, b.Commit(context.Background(), id, newFlowGraph(day, flow{"10.0.0.1", "10.0.0.2", 40000, 443, 3600})))
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+	assert.Equal(t, []string{"0", "1", "2", "3", "4"}, sortedGraphs(loadBaseline(t, storage, "account-"+account)))
+}
+
+func TestBaselineStorageError(t *testing.T) {
+	storage := storagetest.NewMemory()
+	storage.Err = errors.New("oops")
+	b := newBaseline(storage)
+	g := newFlowGraph(day, flow{"10.0.0.1", "10.0.0.2", 40000, 443, 3600})
+	_, err := b.Annotate(context.Background(), "first", g)
+	assert.NotNil(t, err)
+	assert.NotNil(t, b.Commit(context.Background(), "first", g))
+}
+
+func sortedGraphs(doc baselineDocument) []string {
+	ids := make([]string, 0, len(doc.Graphs))
+	for id := range doc.Graphs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
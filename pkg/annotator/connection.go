@@ -0,0 +1,117 @@
+package annotator
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+// connection is the set of edges in a graph which share the same source, destination, service port
+// and protocol
+type connection struct {
+	source      string
+	destination string
+	port        int
+	protocol    int
+	response    bool
+	bytes       int64
+	seconds     int64
+	edges       []*graph.Edge
+}
+
+// groupConnections aggregates edges by connection. Flows which appear to be
+// responses, where the source port is a service port and the destination port is a client port,
+// are keyed by their source port. See graph.Edge.IsResponse.
+func groupConnections(g *graph.Graph, edges []*graph.Edge) []*connection {
+	byKey := make(map[string]*connection)
+	var connections []*connection
+	for _, e := range edges {
+		c := &connection{
+			source:      nodeAddr(g, e.From),
+			destination: nodeAddr(g, e.To),
+			port:        e.DstPort,
+			protocol:    e.Protocol,
+		}
+		if e.IsResponse() {
+			c.port, c.response = e.SrcPort, true
+		}
+		if existing, ok := byKey[c.key()]; ok {
+			c = existing
+		} else {
+			byKey[c.key()] = c
+			connections = append(connections, c)
+		}
+		c.bytes += e.Bytes
+		if seconds := int64(e.End.Sub(e.Start).Seconds()); seconds > c.seconds {
+			c.seconds = seconds
+		}
+		c.edges = append(c.edges, e)
+	}
+	for _, c := range connections {
+		if c.seconds < 1 {
+			c.seconds = 1
+		}
+	}
+	return connections
+}
+
+// key identifies the connection as source|destination|port|protocol
+func (c *connection) key() string {
+	return strings.Join([]string{c.source, c.destination, strconv.Itoa(c.port), strconv.Itoa(c.protocol)}, "|")
+}
+
+// flag adds value to the given attribute of each edge of the connection
+func (c *connection) flag(attr, value string) {
+	for _, e := range c.edges {
+		e.Flag(attr, value)
+	}
+}
+
+// finding returns a finding of the given kind describing the connection
+func (c *connection) finding(id, account, kind, description string) types.Finding {
+	var start, stop time.Time
+	for _, e := range c.edges {
+		if start.IsZero() || e.Start.Before(start) {
+			start = e.Start
+		}
+		if e.End.After(stop) {
+			stop = e.End
+		}
+	}
+	return types.Finding{
+		Key:         strings.Join([]string{kind, account, c.key()}, "|"),
+		Kind:        kind,
+		GraphID:     id,
+		Start:       start,
+		Stop:        stop,
+		Source:      c.source,
+		Destination: c.destination,
+		Description: description,
+		Attributes: map[string]string{
+			"accountID": account,
+			"port":      strconv.Itoa(c.port),
+			"protocol":  strconv.Itoa(c.protocol),
+		},
+	}
+}
+
+func nodeAddr(g *graph.Graph, id string) string {
+	if n, ok := g.Nodes[id]; ok && n.Addr != "" {
+		return n.Addr
+	}
+	return id
+}
+
+// sortedGroups returns the keys of the groups of edges in order
+func sortedGroups(groups map[string][]*graph.Edge) []string {
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
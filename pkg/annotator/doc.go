@@ -0,0 +1,4 @@
+// Package annotator contains the built in types.Annotator implementations which analyze each
+// graph before it is stored, flagging notable edges and reporting their findings.
+//
+package annotator
@@ -0,0 +1,263 @@
+package annotator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// KindPolicyViolation identifies accepted connections which are not allowed by the network policy
+	KindPolicyViolation = "policy-violation"
+
+	// PolicyReportSuffix is appended to a graph ID to form the key of the graph's policy report
+	PolicyReportSuffix = ".policy.json"
+
+	// policyAny matches any source, destination, port or protocol in a policy rule
+	policyAny = "any"
+)
+
+// PolicyRule is a single allowed flow of a network policy. Source and Destination are either the name
+// of a policy group, an IP address, a CIDR block or "any". Port is a single port, a range such as
+// "1024-2048" or "any", and Protocol is a protocol name, such as tcp, a protocol number or "any".
+// Empty fields match anything.
+type PolicyRule struct {
+	Source      string `yaml:"source" json:"source"`
+	Destination string `yaml:"destination" json:"destination"`
+	Port        string `yaml:"port" json:"port"`
+	Protocol    string `yaml:"protocol" json:"protocol"`
+	Description string `yaml:"description" json:"description,omitempty"`
+}
+
+// NetworkPolicy is a declared set of allowed flows between named groups of addresses
+type NetworkPolicy struct {
+	Groups map[string][]string `yaml:"groups"`
+	Allow  []PolicyRule        `yaml:"allow"`
+	rules  []compiledRule
+}
+
+type compiledRule struct {
+	sources      []*net.IPNet
+	destinations []*net.IPNet
+	fromPort     int
+	toPort       int
+	protocol     int
+}
+
+// PolicyReport is the result of evaluating a graph against the network policy
+type PolicyReport struct {
+	GraphID     string          `json:"graphID"`
+	Start       time.Time       `json:"start"`
+	Stop        time.Time       `json:"stop"`
+	Rules       int             `json:"rules"`
+	Connections int             `json:"connections"`
+	Violations  int             `json:"violations"`
+	Findings    []types.Finding `json:"findings"`
+}
+
+// ParsePolicy reads a YAML network policy of the form:
+//
+//	groups:
+//	  dmz: [10.0.1.0/24]
+//	  database: [10.0.3.0/24, 10.0.4.10]
+//	allow:
+//	  - source: dmz
+//	    destination: database
+//	    port: 5432
+//	    protocol: tcp
+func ParsePolicy(r io.Reader) (*NetworkPolicy, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &NetworkPolicy{}
+	if err := yaml.UnmarshalStrict(b, p); err != nil {
+		return nil, err
+	}
+	for idx, rule := range p.Allow {
+		compiled, err := p.compile(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy rule %d: %s", idx+1, err.Error())
+		}
+		p.rules = append(p.rules, compiled)
+	}
+	return p, nil
+}
+
+// LoadPolicy reads the YAML network policy at path
+func LoadPolicy(path string) (*NetworkPolicy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p, err := ParsePolicy(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read policy %s: %s", path, err.Error())
+	}
+	return p, nil
+}
+
+// Allows reports whether the policy allows traffic from source to destination on the given port and protocol
+func (p *NetworkPolicy) Allows(source, destination net.IP, port, protocol int) bool {
+	for _, r := range p.rules {
+		if (r.protocol < 0 || r.protocol == protocol) &&
+			(r.fromPort < 0 || (port >= r.fromPort && port <= r.toPort)) &&
+			containsIP(r.sources, source) && containsIP(r.destinations, destination) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *NetworkPolicy) compile(rule PolicyRule) (compiledRule, error) {
+	var c compiledRule
+	var err error
+	if c.sources, err = p.networks(rule.Source); err != nil {
+		return c, err
+	}
+	if c.destinations, err = p.networks(rule.Destination); err != nil {
+		return c, err
+	}
+	if c.fromPort, c.toPort, err = parsePortRange(rule.Port); err != nil {
+		return c, err
+	}
+	c.protocol, err = parseProtocol(rule.Protocol)
+	return c, err
+}
+
+// networks resolves a group name, address or CIDR block. A nil result matches any address.
+func (p *NetworkPolicy) networks(s string) ([]*net.IPNet, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, policyAny) {
+		return nil, nil
+	}
+	members, ok := p.Groups[s]
+	if !ok {
+		members = []string{s}
+	}
+	networks := make([]*net.IPNet, 0, len(members))
+	for _, m := range members {
+		n, err := graph.ParseNetwork(strings.TrimSpace(m))
+		if err != nil {
+			return nil, fmt.Errorf("unknown group or address %q", m)
+		}
+		networks = append(networks, n)
+	}
+	return networks, nil
+}
+
+// Policy is an Annotator which evaluates the accepted connections of each graph against a network
+// policy. Edges of connections which are not allowed are flagged with a policy attribute, and drawn
+// in red in the DOT output. Connections which appear to be responses are allowed when the policy
+// allows the request in the opposite direction. A report of the evaluation is stored alongside the
+// graph using PolicyReportSuffix.
+type Policy struct {
+	Storage types.Storage
+	Policy  *NetworkPolicy
+}
+
+// Annotate flags the edges of the graph which violate the policy, and stores the policy report
+func (p *Policy) Annotate(ctx context.Context, id string, g *graph.Graph) ([]types.Finding, error) {
+	start, stop := g.Window()
+	report := PolicyReport{
+		GraphID:  id,
+		Start:    start,
+		Stop:     stop,
+		Rules:    len(p.Policy.rules),
+		Findings: []types.Finding{},
+	}
+	byAccount := make(map[string][]*graph.Edge)
+	for _, e := range g.Edges {
+		if e.Action == graph.ActionReject {
+			continue
+		}
+		byAccount[e.AccountID] = append(byAccount[e.AccountID], e)
+	}
+	for _, account := range sortedGroups(byAccount) {
+		for _, c := range groupConnections(g, byAccount[account]) {
+			report.Connections++
+			source, destination := net.ParseIP(c.source), net.ParseIP(c.destination)
+			allowed := p.Policy.Allows(source, destination, c.port, c.protocol)
+			if !allowed && c.response {
+				allowed = p.Policy.Allows(destination, source, c.port, c.protocol)
+			}
+			if allowed {
+				continue
+			}
+			c.flag(graph.AttrPolicy, KindPolicyViolation)
+			report.Findings = append(report.Findings, c.finding(id, account, KindPolicyViolation,
+				fmt.Sprintf("%s connected to %s on port %d, which is not allowed by the policy", c.source, c.destination, c.port)))
+		}
+	}
+	report.Violations = len(report.Findings)
+	if err := storage.StoreJSON(ctx, p.Storage, id+PolicyReportSuffix, report); err != nil {
+		return nil, err
+	}
+	return report.Findings, nil
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	if networks == nil {
+		return true
+	}
+	for _, n := range networks {
+		if ip != nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePortRange parses a port, port range or "any", which is returned as -1
+func parsePortRange(s string) (int, int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, policyAny) {
+		return -1, -1, nil
+	}
+	bounds := strings.SplitN(s, "-", 2)
+	from, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port %q", s)
+	}
+	to := from
+	if len(bounds) == 2 {
+		if to, err = strconv.Atoi(strings.TrimSpace(bounds[1])); err != nil || to < from {
+			return 0, 0, fmt.Errorf("invalid port range %q", s)
+		}
+	}
+	return from, to, nil
+}
+
+// parseProtocol parses a protocol name or number, or "any", which is returned as -1
+func parseProtocol(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "", policyAny:
+		return -1, nil
+	case "icmp":
+		return 1, nil
+	case "tcp":
+		return 6, nil
+	case "udp":
+		return 17, nil
+	case "icmpv6":
+		return 58, nil
+	}
+	protocol, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid protocol %q", s)
+	}
+	return protocol, nil
+}
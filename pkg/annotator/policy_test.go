@@ -0,0 +1,117 @@
+package annotator
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `
+groups:
+  dmz: [10.0.1.0/24]
+  app: [10.0.2.0/24]
+  database: [10.0.3.0/24, 10.0.4.10]
+allow:
+  - source: dmz
+    destination: app
+    port: 440-450
+    protocol: tcp
+  - source: app
+    destination: database
+    port: 5432
+  - source: any
+    destination: 10.0.0.2
+    port: any
+    protocol: udp
+`
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy(strings.NewReader(testPolicy))
+	require.Nil(t, err)
+	assert.True(t, p.Allows(net.ParseIP("10.0.1.5"), net.ParseIP("10.0.2.5"), 445, 6))
+	assert.False(t, p.Allows(net.ParseIP("10.0.1.5"), net.ParseIP("10.0.2.5"), 445, 17))
+	assert.False(t, p.Allows(net.ParseIP("10.0.1.5"), net.ParseIP("10.0.3.5"), 5432, 6))
+	assert.True(t, p.Allows(net.ParseIP("10.0.2.5"), net.ParseIP("10.0.4.10"), 5432, 17))
+	assert.True(t, p.Allows(net.ParseIP("8.8.8.8"), net.ParseIP("10.0.0.2"), 53, 17))
+}
+
+func TestParsePolicyInvalid(t *testing.T) {
+	tc := []struct {
+		Name   string
+		Policy string
+	}{
+		{"unknown_group", "allow:\n  - source: nope\n"},
+		{"bad_port", "allow:\n  - port: http\n"},
+		{"bad_range", "allow:\n  - port: 90-80\n"},
+		{"bad_protocol", "allow:\n  - protocol: carrier-pigeon\n"},
+		{"unknown_field", "allowed:\n  - port: 80\n"},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			_, err := ParsePolicy(strings.NewReader(tt.Policy))
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestLoadPolicyMissing(t *testing.T) {
+	_, err := LoadPolicy("/does/not/exist.yaml")
+	assert.NotNil(t, err)
+}
+
+func TestPolicyAnnotate(t *testing.T) {
+	p, err := ParsePolicy(strings.NewReader(testPolicy))
+	require.Nil(t, err)
+	storage := storagetest.NewMemory()
+	a := &Policy{Storage: storage, Policy: p}
+	g := newFlowGraph(day,
+		flow{"10.0.1.5", "10.0.2.5", 40000, 443, 100},
+		flow{"10.0.2.5", "10.0.1.5", 443, 40000, 100},
+		flow{"10.0.1.5", "10.0.3.5", 40000, 5432, 100},
+	)
+	g.Edges = append(g.Edges, &graph.Edge{From: graph.NodeID("10.0.1.5"), To: graph.NodeID("10.0.3.5"), DstPort: 22, Protocol: 6, Action: graph.ActionReject})
+
+	findings, err := a.Annotate(context.Background(), "abc", g)
+	require.Nil(t, err)
+	require.Len(t, findings, 1)
+	assert.Equal(t, KindPolicyViolation, findings[0].Kind)
+	assert.Equal(t, "10.0.3.5", findings[0].Destination)
+	assert.Empty(t, g.Edges[0].Attrs[graph.AttrPolicy])
+	assert.Empty(t, g.Edges[1].Attrs[graph.AttrPolicy])
+	assert.Equal(t, KindPolicyViolation, g.Edges[2].Attrs[graph.AttrPolicy])
+	assert.Empty(t, g.Edges[3].Attrs[graph.AttrPolicy])
+
+	var report PolicyReport
+	require.Nil(t, json.Unmarshal(storage.Objects["abc"+PolicyReportSuffix], &report))
+	assert.Equal(t, 3, report.Rules)
+	assert.Equal(t, 3, report.Connections)
+	assert.Equal(t, 1, report.Violations)
+}
+
+func TestPolicyDigestResponses(t *testing.T) {
+	p, err := ParsePolicy(strings.NewReader(testPolicy))
+	require.Nil(t, err)
+	a := &Policy{Storage: storagetest.NewMemory(), Policy: p}
+	// the responses of an allowed service are recorded by the digester with a destination port of 0
+	g := newDigestGraph(t, day,
+		"10.0.1.5 10.0.2.5 0 443 100",
+		"10.0.2.5 10.0.1.5 443 0 100",
+		"10.0.3.5 10.0.1.5 5432 0 100",
+	)
+
+	findings, err := a.Annotate(context.Background(), "abc", g)
+	require.Nil(t, err)
+	assert.Empty(t, digestEdge(t, g, "10.0.1.5", "10.0.2.5").Attrs[graph.AttrPolicy])
+	assert.Empty(t, digestEdge(t, g, "10.0.2.5", "10.0.1.5").Attrs[graph.AttrPolicy])
+	// responses are only allowed when the policy allows the request
+	assert.Equal(t, KindPolicyViolation, digestEdge(t, g, "10.0.3.5", "10.0.1.5").Attrs[graph.AttrPolicy])
+	require.Len(t, findings, 1)
+	assert.Equal(t, "5432", findings[0].Attributes["port"])
+}
@@ -0,0 +1,172 @@
+package annotator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+const (
+	// KindFanOut identifies sources which contacted an unusually large number of distinct destinations
+	KindFanOut = "fan-out"
+
+	// KindPortScan identifies sources which contacted an unusually large number of distinct ports
+	KindPortScan = "port-scan"
+
+	// KindRejectedProbe identifies sources whose attempts were mostly rejected
+	KindRejectedProbe = "rejected-probe"
+
+	// ScanReportSuffix is appended to a graph ID to form the key of the graph's scan findings
+	ScanReportSuffix = ".scan.json"
+
+	// DefaultScanDestinationThreshold is the default number of distinct destinations above which a source is flagged
+	DefaultScanDestinationThreshold = 50
+
+	// DefaultScanPortThreshold is the default number of distinct destination ports above which a source is flagged
+	DefaultScanPortThreshold = 25
+
+	// DefaultScanRejectRatio is the default ratio of rejected flows above which a source is flagged
+	DefaultScanRejectRatio = 0.5
+
+	// DefaultScanRejectMinimum is the default number of distinct destination and port pairs a source must
+	// attempt before its reject ratio is considered
+	DefaultScanRejectMinimum = 10
+)
+
+// ScanReport holds the scanning behaviors detected in a graph
+type ScanReport struct {
+	GraphID  string          `json:"graphID"`
+	Start    time.Time       `json:"start"`
+	Stop     time.Time       `json:"stop"`
+	Findings []types.Finding `json:"findings"`
+}
+
+// sourceActivity is the outbound activity of a single source address
+type sourceActivity struct {
+	addr         string
+	destinations map[string]bool
+	ports        map[string]bool
+	targets      map[string]bool
+	flows        int
+	rejected     int
+	start        time.Time
+	stop         time.Time
+}
+
+// Scan is an Annotator which detects sources scanning the network. Sources contacting more than
+// DestinationThreshold distinct destinations are flagged as fanning out, sources contacting more than
+// PortThreshold distinct destination ports are flagged as port scanning, and sources which attempted at
+// least RejectMinimum distinct destination and port pairs, with more than RejectRatio of their flows being
+// rejected, are flagged as probing. Flows which appear to be responses, including those to the port 0 of
+// digested clients, are ignored.
+//
+// Flagged nodes carry a scan attribute listing the detected behaviors, and are outlined in red in the
+// DOT output. The findings are stored alongside the graph using ScanReportSuffix. Zero thresholds use
+// their respective defaults.
+type Scan struct {
+	Storage              types.Storage
+	DestinationThreshold int
+	PortThreshold        int
+	RejectRatio          float64
+	RejectMinimum        int
+}
+
+// Annotate flags the scanning sources of the graph, and stores the scan findings
+func (s *Scan) Annotate(ctx context.Context, id string, g *graph.Graph) ([]types.Finding, error) {
+	start, stop := g.Window()
+	report := ScanReport{GraphID: id, Start: start, Stop: stop, Findings: []types.Finding{}}
+
+	activity := make(map[string]*sourceActivity)
+	for _, e := range g.Edges {
+		if e.IsResponse() {
+			continue
+		}
+		a, ok := activity[e.From]
+		if !ok {
+			a = &sourceActivity{
+				addr:         nodeAddr(g, e.From),
+				destinations: make(map[string]bool),
+				ports:        make(map[string]bool),
+				targets:      make(map[string]bool),
+			}
+			activity[e.From] = a
+		}
+		port := strconv.Itoa(e.DstPort) + "/" + strconv.Itoa(e.Protocol)
+		a.destinations[e.To] = true
+		a.ports[port] = true
+		a.targets[e.To+"|"+port] = true
+		a.flows++
+		if e.Action == graph.ActionReject {
+			a.rejected++
+		}
+		if a.start.IsZero() || e.Start.Before(a.start) {
+			a.start = e.Start
+		}
+		if e.End.After(a.stop) {
+			a.stop = e.End
+		}
+	}
+
+	ids := make([]string, 0, len(activity))
+	for nodeID := range activity {
+		ids = append(ids, nodeID)
+	}
+	sort.Strings(ids)
+	for _, nodeID := range ids {
+		a := activity[nodeID]
+		ratio := float64(a.rejected) / float64(a.flows)
+		var kinds []string
+		if len(a.destinations) > valueOrDefault(s.DestinationThreshold, DefaultScanDestinationThreshold) {
+			kinds = append(kinds, KindFanOut)
+		}
+		if len(a.ports) > valueOrDefault(s.PortThreshold, DefaultScanPortThreshold) {
+			kinds = append(kinds, KindPortScan)
+		}
+		rejectRatio := s.RejectRatio
+		if rejectRatio <= 0 {
+			rejectRatio = DefaultScanRejectRatio
+		}
+		if len(a.targets) >= valueOrDefault(s.RejectMinimum, DefaultScanRejectMinimum) && ratio > rejectRatio {
+			kinds = append(kinds, KindRejectedProbe)
+		}
+		for _, kind := range kinds {
+			if n, ok := g.Nodes[nodeID]; ok {
+				n.Flag(graph.AttrScan, kind)
+			}
+			report.Findings = append(report.Findings, types.Finding{
+				Key:     strings.Join([]string{kind, a.addr}, "|"),
+				Kind:    kind,
+				GraphID: id,
+				Start:   a.start,
+				Stop:    a.stop,
+				Source:  a.addr,
+				Description: fmt.Sprintf("%s contacted %d destinations on %d ports, %.0f%% of its flows were rejected",
+					a.addr, len(a.destinations), len(a.ports), ratio*100),
+				Attributes: map[string]string{
+					"destinations": strconv.Itoa(len(a.destinations)),
+					"ports":        strconv.Itoa(len(a.ports)),
+					"flows":        strconv.Itoa(a.flows),
+					"rejected":     strconv.Itoa(a.rejected),
+				},
+			})
+		}
+	}
+	if err := storage.StoreJSON(ctx, s.Storage, id+ScanReportSuffix, report); err != nil {
+		return nil, err
+	}
+	return report.Findings, nil
+}
+
+func valueOrDefault(value, defaultValue int) int {
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,87 @@
+package annotator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScan(t *testing.T) {
+	var flows []flow
+	for i := 1; i <= 5; i++ {
+		flows = append(flows, flow{"10.0.0.1", fmt.Sprintf("10.0.1.%d", i), 40000, 443, 100})
+	}
+	for port := 20; port < 25; port++ {
+		flows = append(flows, flow{"10.0.0.2", "10.0.1.1", 40000, port, 100})
+	}
+	for i := 1; i <= 5; i++ {
+		flows = append(flows, flow{"10.0.1.1", fmt.Sprintf("10.0.2.%d", i), 443, 40000, 100})
+	}
+	g := newFlowGraph(day, flows...)
+	for i := 0; i < 4; i++ {
+		g.Edges[5+i].Action = graph.ActionReject
+	}
+
+	storage := storagetest.NewMemory()
+	s := &Scan{Storage: storage, DestinationThreshold: 4, PortThreshold: 4, RejectMinimum: 5, RejectRatio: 0.75}
+	findings, err := s.Annotate(context.Background(), "abc", g)
+	require.Nil(t, err)
+	require.Len(t, findings, 3)
+	assert.Equal(t, KindFanOut, g.Nodes[graph.NodeID("10.0.0.1")].Attrs[graph.AttrScan])
+	assert.Equal(t, "port-scan,rejected-probe", g.Nodes[graph.NodeID("10.0.0.2")].Attrs[graph.AttrScan])
+	assert.Empty(t, g.Nodes[graph.NodeID("10.0.1.1")].Attrs[graph.AttrScan])
+
+	var report ScanReport
+	require.Nil(t, json.Unmarshal(storage.Objects["abc"+ScanReportSuffix], &report))
+	assert.Len(t, report.Findings, 3)
+	assert.Equal(t, "fan-out|10.0.0.1", report.Findings[0].Key)
+}
+
+func TestScanThresholdsExclusive(t *testing.T) {
+	var flows []flow
+	for i := 1; i <= 5; i++ {
+		flows = append(flows, flow{"10.0.0.1", fmt.Sprintf("10.0.1.%d", i), 40000, 20 + i, 100})
+	}
+	s := &Scan{Storage: storagetest.NewMemory(), DestinationThreshold: 5, PortThreshold: 5}
+	findings, err := s.Annotate(context.Background(), "abc", newFlowGraph(day, flows...))
+	require.Nil(t, err)
+	assert.Empty(t, findings)
+}
+
+func TestScanDigestResponses(t *testing.T) {
+	// a server answering many clients sends its responses to their port 0
+	var lines []string
+	for i := 1; i <= 6; i++ {
+		lines = append(lines, fmt.Sprintf("10.0.1.%d 10.0.0.1 0 443 100", i), fmt.Sprintf("10.0.0.1 10.0.1.%d 443 0 100", i))
+	}
+	g := newDigestGraph(t, day, lines...)
+	s := &Scan{Storage: storagetest.NewMemory(), DestinationThreshold: 5, PortThreshold: 5}
+	findings, err := s.Annotate(context.Background(), "abc", g)
+	require.Nil(t, err)
+	assert.Empty(t, findings)
+	assert.Empty(t, g.Nodes[graph.NodeID("10.0.0.1")].Attrs[graph.AttrScan])
+}
+
+func TestScanDefaults(t *testing.T) {
+	storage := storagetest.NewMemory()
+	s := &Scan{Storage: storage}
+	findings, err := s.Annotate(context.Background(), "abc", newFlowGraph(day, flow{"10.0.0.1", "10.0.0.2", 40000, 443, 100}))
+	require.Nil(t, err)
+	assert.Empty(t, findings)
+	assert.Contains(t, storage.Objects, "abc"+ScanReportSuffix)
+}
+
+func TestScanStorageError(t *testing.T) {
+	storage := storagetest.NewMemory()
+	storage.Err = errors.New("oops")
+	s := &Scan{Storage: storage}
+	_, err := s.Annotate(context.Background(), "abc", newFlowGraph(day))
+	assert.NotNil(t, err)
+}
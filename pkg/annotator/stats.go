@@ -0,0 +1,35 @@
+package annotator
+
+import (
+	"context"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+const (
+	// StatsSuffix is appended to a graph ID to form the key of the graph's statistics
+	StatsSuffix = ".stats.json"
+
+	// DefaultStatsLimit is the default number of entries kept in each statistics ranking
+	DefaultStatsLimit = 10
+)
+
+// Stats is an Annotator which computes the metrics of each graph, such as top talkers, node degree,
+// betweenness centrality, connected components and most used ports, and stores them as JSON alongside
+// the graph using StatsSuffix. It does not modify the graph or report any findings.
+type Stats struct {
+	Storage types.Storage
+	// Limit is the number of entries kept in each ranking. Defaults to DefaultStatsLimit.
+	Limit int
+}
+
+// Annotate computes and stores the statistics of the graph
+func (s *Stats) Annotate(ctx context.Context, id string, g *graph.Graph) ([]types.Finding, error) {
+	limit := s.Limit
+	if limit <= 0 {
+		limit = DefaultStatsLimit
+	}
+	return nil, storage.StoreJSON(ctx, s.Storage, id+StatsSuffix, graph.Summarize(g, limit))
+}
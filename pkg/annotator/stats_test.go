@@ -0,0 +1,38 @@
+package annotator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	storage := storagetest.NewMemory()
+	s := &Stats{Storage: storage, Limit: 1}
+	findings, err := s.Annotate(context.Background(), "abc", newFlowGraph(day,
+		flow{"10.0.0.1", "10.0.0.2", 40000, 443, 3600},
+		flow{"10.0.0.3", "10.0.0.2", 40000, 443, 100},
+	))
+	require.Nil(t, err)
+	assert.Empty(t, findings)
+
+	var summary graph.Summary
+	require.Nil(t, json.Unmarshal(storage.Objects["abc"+StatsSuffix], &summary))
+	assert.Equal(t, 3, summary.Nodes)
+	assert.Equal(t, 2, summary.Edges)
+	assert.Equal(t, []graph.Talker{{Addr: "10.0.0.2", Bytes: 3700}}, summary.TopTalkersByBytes)
+}
+
+func TestStatsStorageError(t *testing.T) {
+	storage := storagetest.NewMemory()
+	storage.Err = errors.New("oops")
+	s := &Stats{Storage: storage}
+	_, err := s.Annotate(context.Background(), "abc", newFlowGraph(day))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,125 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/parallel"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+const (
+	statusGraphed    = "graphed"
+	statusExists     = "exists"
+	statusInProgress = "in_progress"
+	statusFailed     = "failed"
+)
+
+// Result counts the windows of a backfill by outcome
+type Result struct {
+	Graphed int
+	Skipped int
+	Failed  int
+}
+
+// Backfill graphs windows of time by calling the Digester and Grapher directly, rather than queuing the
+// graphs. Windows whose graph already exists or is in progress are skipped, so an interrupted backfill
+// resumes where it stopped when it is run again. A line is written to Output as each window completes.
+//
+// The findings made while graphing past windows are not notified, see types.WithoutNotify. The annotators
+// still learn from the graphs, and serialize their own updates, so windows may be graphed concurrently.
+type Backfill struct {
+	Storage  types.Storage
+	Marker   types.Marker
+	Digester types.Digester
+	Grapher  types.Grapher
+	// Concurrency is the number of windows graphed at the same time. Defaults to DefaultConcurrency.
+	Concurrency int
+	Output      io.Writer
+}
+
+// Run graphs the windows, and returns an error if any of them failed or if the context was cancelled
+func (b *Backfill) Run(ctx context.Context, windows [][2]time.Time) (Result, error) {
+	ctx = types.WithoutNotify(ctx)
+	concurrency := b.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	var result Result
+	var done int
+	var lock sync.Mutex
+	// a failed window does not abandon the others, only the cancellation of the context does
+	err := parallel.ForEach(ctx, len(windows), concurrency, func(ctx context.Context, idx int) error {
+		window := windows[idx]
+		status, err := b.graph(ctx, window[0], window[1])
+		lock.Lock()
+		defer lock.Unlock()
+		done++
+		switch status {
+		case statusGraphed:
+			result.Graphed++
+		case statusFailed:
+			result.Failed++
+		default:
+			result.Skipped++
+		}
+		line := fmt.Sprintf("[%d/%d] %s %s %s", done, len(windows), window[0].Format(time.RFC3339Nano), window[1].Format(time.RFC3339Nano), status)
+		if err != nil {
+			line += ": " + err.Error()
+		}
+		fmt.Fprintln(b.Output, line)
+		return nil
+	})
+
+	fmt.Fprintf(b.Output, "%d graphed, %d skipped, %d failed\n", result.Graphed, result.Skipped, result.Failed)
+	if err != nil {
+		return result, err
+	}
+	if result.Failed > 0 {
+		return result, fmt.Errorf("%d of %d windows failed", result.Failed, len(windows))
+	}
+	return result, nil
+}
+
+// graph creates the graph of a single window, unless it already exists or is in progress, and returns its status
+func (b *Backfill) graph(ctx context.Context, start, stop time.Time) (string, error) {
+	id := graph.ID(start, stop)
+	exists, err := b.Storage.Exists(ctx, id)
+	switch err.(type) {
+	case nil:
+	case types.ErrInProgress:
+		return statusInProgress, nil
+	default:
+		return statusFailed, err
+	}
+	if exists {
+		return statusExists, nil
+	}
+
+	if err := b.Marker.Mark(ctx, id); err != nil {
+		return statusFailed, err
+	}
+	graphErr := b.digestAndGraph(ctx, id, start, stop)
+	// the window is unmarked even when it failed, so that it is retried rather than skipped on the next run
+	if err := b.Marker.Unmark(ctx, id); err != nil && graphErr == nil {
+		graphErr = err
+	}
+	if graphErr != nil {
+		return statusFailed, graphErr
+	}
+	return statusGraphed, nil
+}
+
+func (b *Backfill) digestAndGraph(ctx context.Context, id string, start, stop time.Time) error {
+	digest, err := b.Digester.Digest(ctx, start, stop)
+	if err != nil {
+		return err
+	}
+	defer digest.Close()
+	return b.Grapher.Graph(ctx, id, start, stop, digest)
+}
@@ -0,0 +1,106 @@
+package backfill
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testWindows(count int) [][2]time.Time {
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	opts := Options{Start: start, Stop: start.Add(time.Duration(count) * time.Hour), Step: time.Hour}
+	return opts.Windows()
+}
+
+func TestBackfill(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	windows := testWindows(4)
+	ids := make([]string, len(windows))
+	for i, window := range windows {
+		ids[i] = graph.ID(window[0], window[1])
+	}
+	storage := NewMockStorage(ctrl)
+	marker := NewMockMarker(ctrl)
+	digester := NewMockDigester(ctrl)
+	grapher := NewMockGrapher(ctrl)
+
+	storage.EXPECT().Exists(gomock.Any(), ids[0]).Return(true, nil)
+	storage.EXPECT().Exists(gomock.Any(), ids[1]).Return(false, types.ErrInProgress{Key: ids[1]})
+	for _, i := range []int{2, 3} {
+		storage.EXPECT().Exists(gomock.Any(), ids[i]).Return(false, nil)
+		marker.EXPECT().Mark(gomock.Any(), ids[i]).Return(nil)
+		digester.EXPECT().Digest(gomock.Any(), windows[i][0], windows[i][1]).Return(ioutil.NopCloser(bytes.NewReader([]byte("digest"))), nil)
+		// the findings of past windows are not notified
+		grapher.EXPECT().Graph(gomock.Any(), ids[i], windows[i][0], windows[i][1], gomock.Any()).DoAndReturn(func(ctx context.Context, _ string, _, _ time.Time, _ io.ReadCloser) error {
+			assert.False(t, types.NotifyFromContext(ctx))
+			return nil
+		})
+		marker.EXPECT().Unmark(gomock.Any(), ids[i]).Return(nil)
+	}
+
+	var output bytes.Buffer
+	b := &Backfill{Storage: storage, Marker: marker, Digester: digester, Grapher: grapher, Concurrency: 2, Output: &output}
+	result, err := b.Run(context.Background(), windows)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Graphed: 2, Skipped: 2}, result)
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Len(t, lines, 5)
+	assert.True(t, strings.HasPrefix(lines[3], "[4/4] "))
+	assert.Contains(t, output.String(), "2019-05-01T00:00:00Z 2019-05-01T01:00:00Z exists")
+	assert.Contains(t, output.String(), "2019-05-01T01:00:00Z 2019-05-01T02:00:00Z in_progress")
+	assert.Equal(t, "2 graphed, 2 skipped, 0 failed", lines[4])
+}
+
+func TestBackfillFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	windows := testWindows(2)
+	storage := NewMockStorage(ctrl)
+	marker := NewMockMarker(ctrl)
+	digester := NewMockDigester(ctrl)
+	grapher := NewMockGrapher(ctrl)
+
+	storage.EXPECT().Exists(gomock.Any(), graph.ID(windows[0][0], windows[0][1])).Return(false, errors.New("storage"))
+	id := graph.ID(windows[1][0], windows[1][1])
+	storage.EXPECT().Exists(gomock.Any(), id).Return(false, nil)
+	marker.EXPECT().Mark(gomock.Any(), id).Return(nil)
+	digester.EXPECT().Digest(gomock.Any(), windows[1][0], windows[1][1]).Return(nil, errors.New("digester"))
+	// the failed window is unmarked so that it is retried on the next run
+	marker.EXPECT().Unmark(gomock.Any(), id).Return(nil)
+
+	var output bytes.Buffer
+	b := &Backfill{Storage: storage, Marker: marker, Digester: digester, Grapher: grapher, Concurrency: 1, Output: &output}
+	result, err := b.Run(context.Background(), windows)
+	assert.Error(t, err)
+	assert.Equal(t, Result{Failed: 2}, result)
+	assert.Contains(t, output.String(), "failed: storage")
+	assert.Contains(t, output.String(), "failed: digester")
+}
+
+func TestBackfillCancelled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var output bytes.Buffer
+	b := &Backfill{Storage: NewMockStorage(ctrl), Output: &output}
+	result, err := b.Run(ctx, testWindows(3))
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, Result{}, result)
+}
@@ -0,0 +1,4 @@
+// Package backfill contains the backfill command, which graphs a range of past windows by driving the
+// Digester and Grapher directly, without the event bus.
+//
+package backfill
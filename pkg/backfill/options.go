@@ -0,0 +1,72 @@
+package backfill
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+)
+
+const (
+	// DefaultStep is the default length of each backfilled window
+	DefaultStep = time.Hour
+
+	// DefaultConcurrency is the default number of windows graphed at the same time
+	DefaultConcurrency = 4
+)
+
+// Options describe the windows of a backfill, as a start/stop range split into steps
+type Options struct {
+	Start       time.Time
+	Stop        time.Time
+	Step        time.Duration
+	Concurrency int
+}
+
+// ParseArgs parses the command line arguments of the backfill command. Usage and parsing errors are
+// written to output.
+func ParseArgs(args []string, output io.Writer) (Options, error) {
+	var opts Options
+	var start, stop string
+	flags := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.StringVar(&start, "start", "", "start of the range to backfill, in RFC3339 format")
+	flags.StringVar(&stop, "stop", "", "stop of the range to backfill, in RFC3339 format")
+	flags.DurationVar(&opts.Step, "step", DefaultStep, "length of each graphed window, a whole number of minutes")
+	flags.IntVar(&opts.Concurrency, "concurrency", DefaultConcurrency, "number of windows graphed at the same time")
+	if err := flags.Parse(args); err != nil {
+		return Options{}, err
+	}
+	if flags.NArg() > 0 {
+		return Options{}, errors.New("unexpected arguments")
+	}
+
+	var err error
+	if opts.Start, err = time.Parse(time.RFC3339Nano, start); err != nil {
+		return Options{}, err
+	}
+	if opts.Stop, err = time.Parse(time.RFC3339Nano, stop); err != nil {
+		return Options{}, err
+	}
+	if err := opts.validate(); err != nil {
+		return Options{}, err
+	}
+	return opts, nil
+}
+
+// Windows returns the windows of the range, in chronological order
+func (o Options) Windows() [][2]time.Time {
+	return graph.Steps(o.Start, o.Stop, o.Step)
+}
+
+func (o Options) validate() error {
+	if err := graph.ValidateSteps(o.Start, o.Stop, o.Step); err != nil {
+		return err
+	}
+	if o.Concurrency < 1 {
+		return errors.New("concurrency should be at least 1")
+	}
+	return nil
+}
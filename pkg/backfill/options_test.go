@@ -0,0 +1,53 @@
+package backfill
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseArgs(t *testing.T) {
+	opts, err := ParseArgs([]string{"-start", "2019-05-01T00:00:00Z", "-stop", "2019-05-02T00:00:00Z", "-step", "6h", "-concurrency", "2"}, ioutil.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, 6*time.Hour, opts.Step)
+	assert.Equal(t, 2, opts.Concurrency)
+
+	windows := opts.Windows()
+	require.Len(t, windows, 4)
+	assert.Equal(t, time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC), windows[0][0].UTC())
+	assert.Equal(t, time.Date(2019, 5, 1, 6, 0, 0, 0, time.UTC), windows[0][1].UTC())
+	assert.Equal(t, time.Date(2019, 5, 2, 0, 0, 0, 0, time.UTC), windows[3][1].UTC())
+}
+
+func TestParseArgsDefaults(t *testing.T) {
+	opts, err := ParseArgs([]string{"-start", "2019-05-01T00:00:00Z", "-stop", "2019-05-01T03:00:00Z"}, ioutil.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultStep, opts.Step)
+	assert.Equal(t, DefaultConcurrency, opts.Concurrency)
+	assert.Len(t, opts.Windows(), 3)
+}
+
+func TestParseArgsInvalid(t *testing.T) {
+	tc := []struct {
+		Name string
+		Args []string
+	}{
+		{"missing_start", []string{"-stop", "2019-05-01T03:00:00Z"}},
+		{"bad_stop", []string{"-start", "2019-05-01T00:00:00Z", "-stop", "tomorrow"}},
+		{"unknown_flag", []string{"-start", "2019-05-01T00:00:00Z", "-stop", "2019-05-01T03:00:00Z", "-force"}},
+		{"extra_argument", []string{"-start", "2019-05-01T00:00:00Z", "-stop", "2019-05-01T03:00:00Z", "now"}},
+		{"bad_step", []string{"-start", "2019-05-01T00:00:00Z", "-stop", "2019-05-01T03:00:00Z", "-step", "90s"}},
+		{"partial_step", []string{"-start", "2019-05-01T00:00:00Z", "-stop", "2019-05-01T03:00:00Z", "-step", "2h"}},
+		{"reversed_range", []string{"-start", "2019-05-01T03:00:00Z", "-stop", "2019-05-01T00:00:00Z"}},
+		{"bad_concurrency", []string{"-start", "2019-05-01T00:00:00Z", "-stop", "2019-05-01T03:00:00Z", "-concurrency", "0"}},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			_, err := ParseArgs(tt.Args, ioutil.Discard)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -68,6 +68,10 @@ func (c *HTTP) waitForDigest(ctx context.Context, start, stop time.Time) (io.Rea
 			data, _ := ioutil.ReadAll(res.Body)
 			return nil, fmt.Errorf("Received unexpected response while polling digester %d: %s", res.StatusCode, data)
 		}
+		// check for cancellation first, since a ready timer would otherwise win the select half of the time
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("request time out reached after %d attempt(s): %s", attempts, ctx.Err().Error())
+		}
 		select {
 		case <-ctx.Done():
 			return nil, fmt.Errorf("request time out reached after %d attempt(s): %s", attempts, ctx.Err().Error())
@@ -83,6 +83,22 @@ func TestDigestContextCancelled(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestDigestCancelledWhileWaitingToPoll(t *testing.T) {
+	// the polling interval has always elapsed, so the cancellation must be noticed before waiting to poll again
+	for i := 0; i < 20; i++ {
+		ctrl := gomock.NewController(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		mockRT := NewMockRoundTripper(ctrl)
+		setClientExpectations(mockRT, http.MethodPost, nil, response{statusCode: 202})
+		mockRT.EXPECT().RoundTrip(&requestMethodMatcher{method: http.MethodGet}).Do(func(r *http.Request) {
+			cancel()
+		}).Return(&http.Response{StatusCode: 204, Body: ioutil.NopCloser(bytes.NewReader([]byte("")))}, nil)
+		_, err := execute(ctx, mockRT)
+		assert.EqualError(t, err, "request time out reached after 1 attempt(s): context canceled")
+		ctrl.Finish()
+	}
+}
+
 func TestDigestRetriesSuceeded(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
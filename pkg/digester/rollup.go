@@ -0,0 +1,230 @@
+package digester
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/parallel"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+const (
+	// DefaultRollupPeriod is the default length of the windows covered by each rollup
+	DefaultRollupPeriod = time.Hour
+
+	// DefaultRollupDelay is the default amount of time to wait after the end of a period before its rollup
+	// is stored, which leaves time for late flow logs to be delivered
+	DefaultRollupDelay = 15 * time.Minute
+
+	// DefaultRollupConcurrency is the default number of periods digested at the same time
+	DefaultRollupConcurrency = 4
+
+	// RollupPrefix is the prefix of the keys of the rollups in Storage
+	RollupPrefix = "rollup/"
+
+	rollupSuffix = ".digest"
+	rollupLayout = "2006-01-02T15:04Z"
+)
+
+// Each digest line is a space delimited VPC flow log record. When tokenized, the fields can be accessed by
+// the below index values.
+const (
+	idxPackets   = 8
+	idxBytes     = 9
+	idxStart     = 10
+	idxEnd       = 11
+	digestFields = 14
+)
+
+// Rollup is a Digester which decorates another Digester, and builds the digests of large windows by merging
+// the digests of each of the periods the window covers. The digest of each period, or rollup, is kept in
+// Storage once the period is over, so that only the periods which were never digested are requested from the
+// decorated Digester, up to Concurrency at the same time. Windows of a single aligned period, such as the
+// hourly windows of the scheduler, are digested as a rollup so that they are stored for the larger windows
+// which cover them. Windows which are not aligned on the period, or which are shorter, are passed through to
+// the decorated Digester.
+//
+// Rollups are merged by summing the bytes and packets of the records they share, and by keeping the earliest
+// start and latest end of each record.
+//
+// When the context is forced, see types.WithForce, the stored rollups are ignored and replaced, so that the
+// flow logs delivered after a rollup was stored are included.
+type Rollup struct {
+	Storage types.Storage
+	// Period is the length of the window of each rollup. Defaults to DefaultRollupPeriod.
+	Period time.Duration
+	// Delay is how long after the end of its period a rollup may be stored. Defaults to DefaultRollupDelay.
+	Delay time.Duration
+	// Concurrency is the number of periods digested at the same time. Defaults to DefaultRollupConcurrency.
+	Concurrency int
+	types.Digester
+}
+
+// Digest returns the digest of the window from start to stop
+func (d *Rollup) Digest(ctx context.Context, start, stop time.Time) (io.ReadCloser, error) {
+	period := d.Period
+	if period <= 0 {
+		period = DefaultRollupPeriod
+	}
+	if !start.Truncate(period).Equal(start) || !stop.Truncate(period).Equal(stop) || stop.Sub(start) < period {
+		return d.Digester.Digest(ctx, start, stop)
+	}
+	var starts []time.Time
+	for s := start; s.Before(stop); s = s.Add(period) {
+		starts = append(starts, s)
+	}
+	rollups, err := d.rollups(ctx, starts, period)
+	if err != nil {
+		return nil, err
+	}
+	if len(rollups) == 1 {
+		return ioutil.NopCloser(bytes.NewReader(rollups[0])), nil
+	}
+	merged := newDigestMerger()
+	for _, rollup := range rollups {
+		if err := merged.add(rollup); err != nil {
+			return nil, err
+		}
+	}
+	return ioutil.NopCloser(merged.reader()), nil
+}
+
+// rollups returns the rollup of the period beginning at each of the starts, in the same order. The remaining
+// periods are abandoned as soon as one of them fails.
+func (d *Rollup) rollups(ctx context.Context, starts []time.Time, period time.Duration) ([][]byte, error) {
+	concurrency := d.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultRollupConcurrency
+	}
+	rollups := make([][]byte, len(starts))
+	err := parallel.ForEach(ctx, len(starts), concurrency, func(ctx context.Context, idx int) error {
+		var err error
+		rollups[idx], err = d.rollup(ctx, starts[idx], starts[idx].Add(period))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rollups, nil
+}
+
+// rollup returns the digest of a single period, from Storage if available or from the decorated Digester
+func (d *Rollup) rollup(ctx context.Context, start, stop time.Time) ([]byte, error) {
+	key := RollupPrefix + start.UTC().Format(rollupLayout) + rollupSuffix
+	if !types.ForceFromContext(ctx) {
+		stored, err := d.Storage.Get(ctx, key)
+		switch err.(type) {
+		case nil:
+			defer stored.Close()
+			return ioutil.ReadAll(stored)
+		case types.ErrNotFound:
+		default:
+			return nil, err
+		}
+	}
+	digest, err := d.Digester.Digest(ctx, start, stop)
+	if err != nil {
+		return nil, err
+	}
+	defer digest.Close()
+	b, err := ioutil.ReadAll(digest)
+	if err != nil {
+		return nil, err
+	}
+	delay := d.Delay
+	if delay <= 0 {
+		delay = DefaultRollupDelay
+	}
+	if stop.Add(delay).Before(time.Now()) {
+		if err := d.Storage.Store(ctx, key, ioutil.NopCloser(bytes.NewReader(b))); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// RollupTime returns the start of the period of the rollup stored under key
+func RollupTime(_ context.Context, _ types.Storage, key string) (time.Time, error) {
+	return time.Parse(rollupLayout, strings.TrimSuffix(strings.TrimPrefix(key, RollupPrefix), rollupSuffix))
+}
+
+// digestMerger merges digest records which only differ by their volume and time range
+type digestMerger struct {
+	keys    []string
+	records map[string][]string
+}
+
+func newDigestMerger() *digestMerger {
+	return &digestMerger{records: make(map[string][]string)}
+}
+
+func (m *digestMerger) add(digest []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(digest))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimSpace(scanner.Text()), " ")
+		if len(fields) != digestFields {
+			continue
+		}
+		key := recordKey(fields)
+		existing, ok := m.records[key]
+		if !ok {
+			m.keys = append(m.keys, key)
+			m.records[key] = fields
+			continue
+		}
+		if err := mergeRecord(existing, fields); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (m *digestMerger) reader() io.Reader {
+	var buf bytes.Buffer
+	for _, key := range m.keys {
+		buf.WriteString(strings.Join(m.records[key], " "))
+		buf.WriteString("\n")
+	}
+	return &buf
+}
+
+// recordKey identifies a digest record by every field but its volume and time range
+func recordKey(fields []string) string {
+	key := make([]string, len(fields))
+	copy(key, fields)
+	for _, idx := range []int{idxPackets, idxBytes, idxStart, idxEnd} {
+		key[idx] = "-"
+	}
+	return strings.Join(key, " ")
+}
+
+// mergeRecord adds the volume of fields to existing, and extends its time range to cover fields
+func mergeRecord(existing, fields []string) error {
+	var values [2][digestFields]int64
+	for i, record := range [][]string{existing, fields} {
+		for _, idx := range []int{idxPackets, idxBytes, idxStart, idxEnd} {
+			v, err := strconv.ParseInt(record[idx], 10, 64)
+			if err != nil {
+				return err
+			}
+			values[i][idx] = v
+		}
+	}
+	current, other := values[0], values[1]
+	existing[idxPackets] = strconv.FormatInt(current[idxPackets]+other[idxPackets], 10)
+	existing[idxBytes] = strconv.FormatInt(current[idxBytes]+other[idxBytes], 10)
+	if other[idxStart] < current[idxStart] {
+		existing[idxStart] = fields[idxStart]
+	}
+	if other[idxEnd] > current[idxEnd] {
+		existing[idxEnd] = fields[idxEnd]
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package digester
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readCloser(s string) io.ReadCloser {
+	return ioutil.NopCloser(bytes.NewReader([]byte(s)))
+}
+
+func TestRollupPassThrough(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	tc := []struct {
+		Name  string
+		Start time.Time
+		Stop  time.Time
+	}{
+		{Name: "shorter_than_period", Start: start, Stop: start.Add(time.Minute)},
+		{Name: "unaligned_start", Start: start.Add(time.Minute), Stop: start.Add(3 * time.Hour)},
+		{Name: "unaligned_stop", Start: start, Stop: start.Add(3*time.Hour + time.Minute)},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			mockDigester := NewMockDigester(ctrl)
+			mockDigester.EXPECT().Digest(gomock.Any(), tt.Start, tt.Stop).Return(readCloser("digest"), nil)
+			d := &Rollup{Storage: NewMockStorage(ctrl), Digester: mockDigester}
+			r, err := d.Digest(context.Background(), tt.Start, tt.Stop)
+			require.Nil(t, err)
+			data, _ := ioutil.ReadAll(r)
+			assert.Equal(t, "digest", string(data))
+		})
+	}
+}
+
+func TestRollupSinglePeriod(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// a window of a single aligned period is digested as a rollup, so that it is stored for larger windows
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Get(gomock.Any(), "rollup/2019-05-01T00:00Z.digest").Return(nil, types.ErrNotFound{})
+	mockStorage.EXPECT().Store(gomock.Any(), "rollup/2019-05-01T00:00Z.digest", gomock.Any()).Return(nil)
+	mockDigester := NewMockDigester(ctrl)
+	mockDigester.EXPECT().Digest(gomock.Any(), start, start.Add(time.Hour)).Return(readCloser("digest"), nil)
+
+	d := &Rollup{Storage: mockStorage, Digester: mockDigester}
+	r, err := d.Digest(context.Background(), start, start.Add(time.Hour))
+	require.Nil(t, err)
+	data, _ := ioutil.ReadAll(r)
+	assert.Equal(t, "digest", string(data))
+}
+
+func TestRollupConcurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, types.ErrNotFound{}).Times(8)
+	mockStorage.EXPECT().Store(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(8)
+
+	var lock sync.Mutex
+	var running, maxRunning int
+	mockDigester := NewMockDigester(ctrl)
+	mockDigester.EXPECT().Digest(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _, _ time.Time) (io.ReadCloser, error) {
+		lock.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		lock.Lock()
+		running--
+		lock.Unlock()
+		return readCloser(""), nil
+	}).Times(8)
+
+	d := &Rollup{Storage: mockStorage, Digester: mockDigester, Concurrency: 2}
+	_, err := d.Digest(context.Background(), start, start.Add(8*time.Hour))
+	require.Nil(t, err)
+	assert.Equal(t, 2, maxRunning)
+}
+
+func TestRollupMerge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	first := "2 123456789010 eni-abc123de 10.0.0.1 10.0.0.2 0 443 6 10 1000 1556668800 1556670000 ACCEPT OK\n"
+	second := "2 123456789010 eni-abc123de 10.0.0.1 10.0.0.2 0 443 6 5 500 1556672400 1556674000 ACCEPT OK\n" +
+		"2 123456789010 eni-abc123de 10.0.0.3 10.0.0.2 0 22 6 1 60 1556672400 1556674000 REJECT OK\n"
+
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Get(gomock.Any(), "rollup/2019-05-01T00:00Z.digest").Return(readCloser(first), nil)
+	mockStorage.EXPECT().Get(gomock.Any(), "rollup/2019-05-01T01:00Z.digest").Return(nil, types.ErrNotFound{})
+	mockStorage.EXPECT().Store(gomock.Any(), "rollup/2019-05-01T01:00Z.digest", gomock.Any()).DoAndReturn(func(_ context.Context, _ string, r io.ReadCloser) error {
+		data, _ := ioutil.ReadAll(r)
+		assert.Equal(t, second, string(data))
+		return nil
+	})
+	mockDigester := NewMockDigester(ctrl)
+	mockDigester.EXPECT().Digest(gomock.Any(), start.Add(time.Hour), start.Add(2*time.Hour)).Return(readCloser(second), nil)
+
+	d := &Rollup{Storage: mockStorage, Digester: mockDigester}
+	r, err := d.Digest(context.Background(), start, start.Add(2*time.Hour))
+	require.Nil(t, err)
+	data, _ := ioutil.ReadAll(r)
+	assert.Equal(t, "2 123456789010 eni-abc123de 10.0.0.1 10.0.0.2 0 443 6 15 1500 1556668800 1556674000 ACCEPT OK\n"+
+		"2 123456789010 eni-abc123de 10.0.0.3 10.0.0.2 0 22 6 1 60 1556672400 1556674000 REJECT OK\n", string(data))
+}
+
+func TestRollupForced(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Store(gomock.Any(), "rollup/2019-05-01T00:00Z.digest", gomock.Any()).Return(nil)
+	mockStorage.EXPECT().Store(gomock.Any(), "rollup/2019-05-01T01:00Z.digest", gomock.Any()).Return(nil)
+	mockDigester := NewMockDigester(ctrl)
+	mockDigester.EXPECT().Digest(gomock.Any(), gomock.Any(), gomock.Any()).Return(readCloser(""), nil).Times(2)
+
+	// the stored rollups are not read, and are replaced with the new digests
+	d := &Rollup{Storage: mockStorage, Digester: mockDigester}
+	_, err := d.Digest(types.WithForce(context.Background()), start, start.Add(2*time.Hour))
+	require.Nil(t, err)
+}
+
+func TestRollupRecentPeriodNotStored(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Now().Truncate(time.Hour).Add(-time.Hour)
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, types.ErrNotFound{}).Times(2)
+	mockStorage.EXPECT().Store(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).MaxTimes(1)
+	mockDigester := NewMockDigester(ctrl)
+	mockDigester.EXPECT().Digest(gomock.Any(), gomock.Any(), gomock.Any()).Return(readCloser(""), nil).Times(2)
+
+	d := &Rollup{Storage: mockStorage, Digester: mockDigester}
+	_, err := d.Digest(context.Background(), start, start.Add(2*time.Hour))
+	require.Nil(t, err)
+}
+
+func TestRollupErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(2 * time.Hour)
+	mockStorage := NewMockStorage(ctrl)
+	mockDigester := NewMockDigester(ctrl)
+	// the periods are digested one at a time, so that the remaining period is abandoned after a failure
+	d := &Rollup{Storage: mockStorage, Digester: mockDigester, Concurrency: 1}
+
+	mockStorage.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, errors.New("oops"))
+	_, err := d.Digest(context.Background(), start, stop)
+	assert.NotNil(t, err)
+
+	mockStorage.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, types.ErrNotFound{})
+	mockDigester.EXPECT().Digest(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("oops"))
+	_, err = d.Digest(context.Background(), start, stop)
+	assert.NotNil(t, err)
+
+	mockStorage.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, types.ErrNotFound{})
+	mockDigester.EXPECT().Digest(gomock.Any(), gomock.Any(), gomock.Any()).Return(readCloser(""), nil)
+	mockStorage.EXPECT().Store(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("oops"))
+	_, err = d.Digest(context.Background(), start, stop)
+	assert.NotNil(t, err)
+
+	mockStorage.EXPECT().Get(gomock.Any(), "rollup/2019-05-01T00:00Z.digest").Return(readCloser("2 1 eni 10.0.0.1 10.0.0.2 0 443 6 x 1 1 1 ACCEPT OK\n2 1 eni 10.0.0.1 10.0.0.2 0 443 6 1 1 1 1 ACCEPT OK\n"), nil)
+	mockStorage.EXPECT().Get(gomock.Any(), "rollup/2019-05-01T01:00Z.digest").Return(readCloser(""), nil)
+	_, err = d.Digest(context.Background(), start, stop)
+	assert.NotNil(t, err)
+}
+
+func TestRollupTime(t *testing.T) {
+	ts, err := RollupTime(context.Background(), nil, "rollup/2019-05-01T01:00Z.digest")
+	require.Nil(t, err)
+	assert.Equal(t, time.Date(2019, 5, 1, 1, 0, 0, 0, time.UTC), ts)
+
+	_, err = RollupTime(context.Background(), nil, "rollup/latest.digest")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,4 @@
+// Package enricher contains the built in types.Enricher implementations which annotate
+// graph nodes with additional context about the observed addresses.
+//
+package enricher
@@ -0,0 +1,92 @@
+package enricher
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+const (
+	attrASN   = "asn"
+	attrASOrg = "as_org"
+)
+
+// MMDBReader is the subset of the MaxMind DB reader used to look up the record for an address
+type MMDBReader interface {
+	Lookup(ip net.IP, result interface{}) error
+}
+
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+}
+
+type asnRecord struct {
+	AutonomousSystemNumber       uint   `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+// GeoIP is an Enricher which annotates internet facing addresses with their country, autonomous system number
+// and autonomous system organization using local MaxMind format databases. Either database may be omitted.
+// Private, loopback and link local addresses are never annotated.
+type GeoIP struct {
+	// Country is a GeoIP2/GeoLite2 Country or City database
+	Country MMDBReader
+	// ASN is a GeoLite2 ASN database
+	ASN MMDBReader
+}
+
+// LoadGeoIP opens the MaxMind databases at the given paths. An empty path leaves the respective database unset.
+func LoadGeoIP(countryPath, asnPath string) (*GeoIP, error) {
+	g := &GeoIP{}
+	if countryPath != "" {
+		country, err := maxminddb.Open(countryPath)
+		if err != nil {
+			return nil, err
+		}
+		g.Country = country
+	}
+	if asnPath != "" {
+		asn, err := maxminddb.Open(asnPath)
+		if err != nil {
+			return nil, err
+		}
+		g.ASN = asn
+	}
+	return g, nil
+}
+
+// Enrich returns the country, ASN and AS organization of an internet facing address
+func (g *GeoIP) Enrich(_ context.Context, addr string, _, _ time.Time) (map[string]string, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil || isPrivate(ip) || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+		return nil, nil
+	}
+	attrs := make(map[string]string)
+	if g.Country != nil {
+		var record countryRecord
+		if err := g.Country.Lookup(ip, &record); err != nil {
+			return nil, err
+		}
+		setIfPresent(attrs, graph.AttrCountry, record.Country.ISOCode)
+	}
+	if g.ASN != nil {
+		var record asnRecord
+		if err := g.ASN.Lookup(ip, &record); err != nil {
+			return nil, err
+		}
+		if record.AutonomousSystemNumber != 0 {
+			attrs[attrASN] = strconv.FormatUint(uint64(record.AutonomousSystemNumber), 10)
+		}
+		setIfPresent(attrs, attrASOrg, record.AutonomousSystemOrganization)
+	}
+	if len(attrs) == 0 {
+		return nil, nil
+	}
+	return attrs, nil
+}
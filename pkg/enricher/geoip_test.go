@@ -0,0 +1,83 @@
+package enricher
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeMMDB struct {
+	country string
+	asn     uint
+	org     string
+	err     error
+	lookups int
+}
+
+func (f *fakeMMDB) Lookup(_ net.IP, result interface{}) error {
+	f.lookups++
+	switch r := result.(type) {
+	case *countryRecord:
+		r.Country.ISOCode = f.country
+	case *asnRecord:
+		r.AutonomousSystemNumber = f.asn
+		r.AutonomousSystemOrganization = f.org
+	}
+	return f.err
+}
+
+func TestGeoIPEnrich(t *testing.T) {
+	country := &fakeMMDB{country: "US"}
+	asn := &fakeMMDB{asn: 15169, org: "Google LLC"}
+	g := &GeoIP{Country: country, ASN: asn}
+
+	attrs, err := g.Enrich(context.Background(), "8.8.8.8", windowStart, windowStop)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"country": "US", "asn": "15169", "as_org": "Google LLC"}, attrs)
+
+	for _, addr := range []string{"10.0.0.1", "127.0.0.1", "169.254.169.254", "-"} {
+		attrs, err = g.Enrich(context.Background(), addr, windowStart, windowStop)
+		assert.Nil(t, err)
+		assert.Nil(t, attrs)
+	}
+	assert.Equal(t, 1, country.lookups)
+	assert.Equal(t, 1, asn.lookups)
+}
+
+func TestGeoIPEnrichPartial(t *testing.T) {
+	g := &GeoIP{Country: &fakeMMDB{country: "DE"}}
+	attrs, err := g.Enrich(context.Background(), "85.214.132.117", windowStart, windowStop)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"country": "DE"}, attrs)
+
+	g = &GeoIP{ASN: &fakeMMDB{}}
+	attrs, err = g.Enrich(context.Background(), "85.214.132.117", windowStart, windowStop)
+	assert.Nil(t, err)
+	assert.Nil(t, attrs)
+}
+
+func TestGeoIPEnrichError(t *testing.T) {
+	g := &GeoIP{Country: &fakeMMDB{err: errors.New("oops")}}
+	_, err := g.Enrich(context.Background(), "8.8.8.8", windowStart, windowStop)
+	assert.NotNil(t, err)
+
+	g = &GeoIP{ASN: &fakeMMDB{err: errors.New("oops")}}
+	_, err = g.Enrich(context.Background(), "8.8.8.8", windowStart, windowStop)
+	assert.NotNil(t, err)
+}
+
+func TestLoadGeoIP(t *testing.T) {
+	g, err := LoadGeoIP("", "")
+	assert.Nil(t, err)
+	assert.Nil(t, g.Country)
+	assert.Nil(t, g.ASN)
+
+	_, err = LoadGeoIP("/does/not/exist.mmdb", "")
+	assert.NotNil(t, err)
+
+	_, err = LoadGeoIP("", "/does/not/exist.mmdb")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,176 @@
+package enricher
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+)
+
+const (
+	columnAddress     = "address"
+	columnStart       = "start"
+	columnStop        = "stop"
+	columnService     = "service"
+	columnTeam        = "team"
+	columnEnvironment = "environment"
+)
+
+// InventoryEntry describes an asset which owned an address, or range of addresses, for some period of time.
+// A zero Start or Stop value leaves that side of the time range unbounded.
+type InventoryEntry struct {
+	Address     string            `json:"address"`
+	Start       time.Time         `json:"start"`
+	Stop        time.Time         `json:"stop"`
+	Service     string            `json:"service"`
+	Team        string            `json:"team"`
+	Environment string            `json:"environment"`
+	Attributes  map[string]string `json:"attributes"`
+	network     *net.IPNet
+}
+
+// Inventory is an Enricher backed by a static asset inventory. Addresses are labeled with the service,
+// owning team and environment of the most specific inventory entry which was active during the time range.
+type Inventory struct {
+	entries []InventoryEntry
+}
+
+// NewInventory validates the given entries and returns an Inventory. Each entry address must be either
+// a single IP address or a CIDR block.
+func NewInventory(entries []InventoryEntry) (*Inventory, error) {
+	inv := &Inventory{entries: make([]InventoryEntry, 0, len(entries))}
+	for _, entry := range entries {
+		network, err := graph.ParseNetwork(entry.Address)
+		if err != nil {
+			return nil, err
+		}
+		entry.network = network
+		inv.entries = append(inv.entries, entry)
+	}
+	return inv, nil
+}
+
+// LoadInventory reads an inventory from the file at path. Files ending in .csv are read as CSV with a
+// header row, and all other files are read as a JSON array of entries.
+//
+// The CSV columns address, start, stop, service, team and environment populate the matching entry
+// fields. Any other column is added to the entry attributes using the column name as the key.
+func LoadInventory(path string) (*Inventory, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []InventoryEntry
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		entries, err = readInventoryCSV(f)
+	} else {
+		err = json.NewDecoder(f).Decode(&entries)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read inventory %s: %s", path, err.Error())
+	}
+	return NewInventory(entries)
+}
+
+// Enrich returns the labels and attributes of the most specific inventory entry containing addr which
+// was active at any point between start and stop.
+func (inv *Inventory) Enrich(_ context.Context, addr string, start, stop time.Time) (map[string]string, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil, nil
+	}
+	var match *InventoryEntry
+	var matchSize int
+	for offset := range inv.entries {
+		entry := &inv.entries[offset]
+		if !entry.network.Contains(ip) || !entry.activeBetween(start, stop) {
+			continue
+		}
+		size, _ := entry.network.Mask.Size()
+		if match == nil || size > matchSize || (size == matchSize && entry.Start.After(match.Start)) {
+			match, matchSize = entry, size
+		}
+	}
+	if match == nil {
+		return nil, nil
+	}
+	attrs := make(map[string]string, len(match.Attributes)+3)
+	for k, v := range match.Attributes {
+		attrs[k] = v
+	}
+	setIfPresent(attrs, columnService, match.Service)
+	setIfPresent(attrs, columnTeam, match.Team)
+	setIfPresent(attrs, columnEnvironment, match.Environment)
+	return attrs, nil
+}
+
+func (e *InventoryEntry) activeBetween(start, stop time.Time) bool {
+	if !e.Start.IsZero() && !stop.IsZero() && stop.Before(e.Start) {
+		return false
+	}
+	if !e.Stop.IsZero() && !start.IsZero() && start.After(e.Stop) {
+		return false
+	}
+	return true
+}
+
+func readInventoryCSV(r io.Reader) ([]InventoryEntry, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := records[0]
+	entries := make([]InventoryEntry, 0, len(records)-1)
+	for _, record := range records[1:] {
+		entry := InventoryEntry{Attributes: make(map[string]string)}
+		for idx, column := range header {
+			val := strings.TrimSpace(record[idx])
+			switch strings.ToLower(strings.TrimSpace(column)) {
+			case columnAddress:
+				entry.Address = val
+			case columnStart:
+				entry.Start, err = parseOptionalTime(val)
+			case columnStop:
+				entry.Stop, err = parseOptionalTime(val)
+			case columnService:
+				entry.Service = val
+			case columnTeam:
+				entry.Team = val
+			case columnEnvironment:
+				entry.Environment = val
+			default:
+				setIfPresent(entry.Attributes, strings.TrimSpace(column), val)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func parseOptionalTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+func setIfPresent(attrs map[string]string, key, val string) {
+	if val != "" {
+		attrs[key] = val
+	}
+}
@@ -0,0 +1,118 @@
+package enricher
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	windowStart = time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	windowStop  = time.Date(2019, 5, 1, 1, 0, 0, 0, time.UTC)
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	dir, err := ioutil.TempDir("", "inventory")
+	require.Nil(t, err)
+	path := filepath.Join(dir, name)
+	require.Nil(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestInventoryEnrich(t *testing.T) {
+	inv, err := NewInventory([]InventoryEntry{
+		{Address: "10.0.0.0/16", Service: "shared", Team: "network", Environment: "prod"},
+		{Address: "10.0.1.0/24", Service: "payments", Team: "billing", Environment: "prod", Attributes: map[string]string{"tier": "1"}},
+		{Address: "10.0.1.5", Service: "old-host", Stop: windowStart.Add(-time.Hour)},
+		{Address: "10.0.1.6", Service: "new-host", Start: windowStop.Add(time.Hour)},
+	})
+	require.Nil(t, err)
+
+	tc := []struct {
+		Name     string
+		Addr     string
+		Expected map[string]string
+	}{
+		{
+			Name:     "most_specific",
+			Addr:     "10.0.1.10",
+			Expected: map[string]string{"service": "payments", "team": "billing", "environment": "prod", "tier": "1"},
+		},
+		{
+			Name:     "broader_network",
+			Addr:     "10.0.2.10",
+			Expected: map[string]string{"service": "shared", "team": "network", "environment": "prod"},
+		},
+		{
+			Name:     "expired_entry",
+			Addr:     "10.0.1.5",
+			Expected: map[string]string{"service": "payments", "team": "billing", "environment": "prod", "tier": "1"},
+		},
+		{
+			Name:     "future_entry",
+			Addr:     "10.0.1.6",
+			Expected: map[string]string{"service": "payments", "team": "billing", "environment": "prod", "tier": "1"},
+		},
+		{
+			Name:     "no_match",
+			Addr:     "192.168.0.1",
+			Expected: nil,
+		},
+		{
+			Name:     "not_an_address",
+			Addr:     "-",
+			Expected: nil,
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			attrs, err := inv.Enrich(context.Background(), tt.Addr, windowStart, windowStop)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.Expected, attrs)
+		})
+	}
+}
+
+func TestNewInventoryInvalidAddress(t *testing.T) {
+	_, err := NewInventory([]InventoryEntry{{Address: "not-an-ip"}})
+	assert.NotNil(t, err)
+}
+
+func TestLoadInventoryJSON(t *testing.T) {
+	path := writeFile(t, "inventory.json", `[{"address": "10.0.1.0/24", "service": "payments", "start": "2019-01-01T00:00:00Z"}]`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	inv, err := LoadInventory(path)
+	require.Nil(t, err)
+	attrs, err := inv.Enrich(context.Background(), "10.0.1.1", windowStart, windowStop)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"service": "payments"}, attrs)
+}
+
+func TestLoadInventoryCSV(t *testing.T) {
+	path := writeFile(t, "inventory.csv", "address,start,stop,service,team,environment,cost center\n"+
+		"10.0.1.0/24,,2019-06-01T00:00:00Z,payments,billing,prod,cc-42\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	inv, err := LoadInventory(path)
+	require.Nil(t, err)
+	attrs, err := inv.Enrich(context.Background(), "10.0.1.1", windowStart, windowStop)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"service": "payments", "team": "billing", "environment": "prod", "cost center": "cc-42"}, attrs)
+}
+
+func TestLoadInventoryErrors(t *testing.T) {
+	_, err := LoadInventory("/does/not/exist.json")
+	assert.NotNil(t, err)
+
+	path := writeFile(t, "inventory.csv", "address,start\n10.0.0.1,yesterday\n")
+	defer os.RemoveAll(filepath.Dir(path))
+	_, err = LoadInventory(path)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,160 @@
+package enricher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+)
+
+const (
+	// genericAWSService is the service name used by ip-ranges.json for every AWS owned prefix.
+	// More specific services are preferred when a prefix is also listed under them.
+	genericAWSService = "AMAZON"
+
+	attrAWSService = "aws_service"
+	attrAWSRegion  = "aws_region"
+)
+
+var privateNetworks = mustParseNetworks("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7")
+
+type ipRangesDocument struct {
+	Prefixes []struct {
+		IPPrefix string `json:"ip_prefix"`
+		Region   string `json:"region"`
+		Service  string `json:"service"`
+	} `json:"prefixes"`
+	IPv6Prefixes []struct {
+		IPv6Prefix string `json:"ipv6_prefix"`
+		Region     string `json:"region"`
+		Service    string `json:"service"`
+	} `json:"ipv6_prefixes"`
+}
+
+type awsPrefix struct {
+	service string
+	region  string
+}
+
+// IPRanges is an Enricher which classifies addresses as private, AWS owned or internet addresses using
+// the AWS published ip-ranges.json document. AWS owned addresses are also tagged with the service and region
+// of the most specific matching prefix.
+type IPRanges struct {
+	// prefixes are indexed by mask length, then by network address, so that lookups can walk
+	// from the most to the least specific prefix length
+	prefixes map[int]map[string]awsPrefix
+	lengths  []int
+}
+
+// ParseIPRanges reads an ip-ranges.json document and returns an IPRanges enricher
+func ParseIPRanges(r io.Reader) (*IPRanges, error) {
+	var doc ipRangesDocument
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	ranges := &IPRanges{prefixes: make(map[int]map[string]awsPrefix)}
+	for _, p := range doc.Prefixes {
+		if err := ranges.add(p.IPPrefix, p.Service, p.Region); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range doc.IPv6Prefixes {
+		if err := ranges.add(p.IPv6Prefix, p.Service, p.Region); err != nil {
+			return nil, err
+		}
+	}
+	for length := 128; length >= 0; length-- {
+		if _, ok := ranges.prefixes[length]; ok {
+			ranges.lengths = append(ranges.lengths, length)
+		}
+	}
+	return ranges, nil
+}
+
+// LoadIPRanges reads the ip-ranges.json document at path and returns an IPRanges enricher
+func LoadIPRanges(path string) (*IPRanges, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ranges, err := ParseIPRanges(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read ip ranges %s: %s", path, err.Error())
+	}
+	return ranges, nil
+}
+
+// Enrich returns the class of the address and, for AWS owned addresses, the AWS service and region
+func (r *IPRanges) Enrich(_ context.Context, addr string, _, _ time.Time) (map[string]string, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil, nil
+	}
+	if isPrivate(ip) {
+		return map[string]string{graph.AttrClass: graph.ClassPrivate}, nil
+	}
+	bits := 128
+	if v4 := ip.To4(); v4 != nil {
+		ip, bits = v4, 32
+	}
+	for _, length := range r.lengths {
+		if length > bits {
+			continue
+		}
+		key := ip.Mask(net.CIDRMask(length, bits)).String()
+		if p, ok := r.prefixes[length][key]; ok {
+			return map[string]string{
+				graph.AttrClass: graph.ClassAWS,
+				attrAWSService:  p.service,
+				attrAWSRegion:   p.region,
+			}, nil
+		}
+	}
+	return map[string]string{graph.AttrClass: graph.ClassInternet}, nil
+}
+
+func (r *IPRanges) add(prefix, service, region string) error {
+	_, network, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return err
+	}
+	length, _ := network.Mask.Size()
+	byNetwork, ok := r.prefixes[length]
+	if !ok {
+		byNetwork = make(map[string]awsPrefix)
+		r.prefixes[length] = byNetwork
+	}
+	key := network.IP.String()
+	if existing, ok := byNetwork[key]; ok && existing.service != genericAWSService {
+		return nil
+	}
+	byNetwork[key] = awsPrefix{service: service, region: region}
+	return nil
+}
+
+func isPrivate(ip net.IP) bool {
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func mustParseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err.Error())
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -0,0 +1,105 @@
+package enricher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ipRangesDoc = `{
+	"syncToken": "1558729992",
+	"createDate": "2019-05-24-20-33-12",
+	"prefixes": [
+		{"ip_prefix": "52.92.0.0/16", "region": "us-west-2", "service": "AMAZON"},
+		{"ip_prefix": "52.92.16.0/20", "region": "us-west-2", "service": "AMAZON"},
+		{"ip_prefix": "52.92.16.0/20", "region": "us-west-2", "service": "S3"},
+		{"ip_prefix": "52.94.0.0/22", "region": "us-east-1", "service": "DYNAMODB"},
+		{"ip_prefix": "52.94.0.0/22", "region": "us-east-1", "service": "AMAZON"}
+	],
+	"ipv6_prefixes": [
+		{"ipv6_prefix": "2600:1f14::/35", "region": "us-west-2", "service": "EC2"}
+	]
+}`
+
+func TestIPRangesEnrich(t *testing.T) {
+	ranges, err := ParseIPRanges(strings.NewReader(ipRangesDoc))
+	require.Nil(t, err)
+
+	tc := []struct {
+		Name     string
+		Addr     string
+		Expected map[string]string
+	}{
+		{
+			Name:     "private",
+			Addr:     "172.31.16.139",
+			Expected: map[string]string{"class": "private"},
+		},
+		{
+			Name:     "private_ipv6",
+			Addr:     "fd00::1",
+			Expected: map[string]string{"class": "private"},
+		},
+		{
+			Name:     "specific_service",
+			Addr:     "52.92.16.10",
+			Expected: map[string]string{"class": "aws", "aws_service": "S3", "aws_region": "us-west-2"},
+		},
+		{
+			Name:     "specific_service_listed_first",
+			Addr:     "52.94.1.10",
+			Expected: map[string]string{"class": "aws", "aws_service": "DYNAMODB", "aws_region": "us-east-1"},
+		},
+		{
+			Name:     "generic_service",
+			Addr:     "52.92.200.1",
+			Expected: map[string]string{"class": "aws", "aws_service": "AMAZON", "aws_region": "us-west-2"},
+		},
+		{
+			Name:     "ipv6",
+			Addr:     "2600:1f14::1",
+			Expected: map[string]string{"class": "aws", "aws_service": "EC2", "aws_region": "us-west-2"},
+		},
+		{
+			Name:     "internet",
+			Addr:     "8.8.8.8",
+			Expected: map[string]string{"class": "internet"},
+		},
+		{
+			Name:     "not_an_address",
+			Addr:     "-",
+			Expected: nil,
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			attrs, err := ranges.Enrich(context.Background(), tt.Addr, windowStart, windowStop)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.Expected, attrs)
+		})
+	}
+}
+
+func TestParseIPRangesInvalid(t *testing.T) {
+	_, err := ParseIPRanges(strings.NewReader(`{"prefixes": [{"ip_prefix": "nope"}]}`))
+	assert.NotNil(t, err)
+
+	_, err = ParseIPRanges(strings.NewReader(`[`))
+	assert.NotNil(t, err)
+}
+
+func TestLoadIPRanges(t *testing.T) {
+	path := writeFile(t, "ip-ranges.json", ipRangesDoc)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	_, err := LoadIPRanges(path)
+	assert.Nil(t, err)
+
+	_, err = LoadIPRanges("/does/not/exist.json")
+	assert.NotNil(t, err)
+}
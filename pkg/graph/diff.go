@@ -0,0 +1,107 @@
+package graph
+
+import "strconv"
+
+const (
+	// AttrDiff is the edge attribute which flags how an edge differs between two graphs
+	AttrDiff = "diff"
+
+	// DiffAdded flags edges which only appear in the newer graph
+	DiffAdded = "added"
+
+	// DiffRemoved flags edges which only appear in the older graph
+	DiffRemoved = "removed"
+
+	// DiffChanged flags edges which appear in both graphs, but with a different action or volume
+	DiffChanged = "changed"
+
+	attrBytesBefore   = "bytes_before"
+	attrPacketsBefore = "packets_before"
+)
+
+// edgeKey identifies a connection independently of the volume of traffic it carried
+type edgeKey struct {
+	from     string
+	to       string
+	srcPort  int
+	dstPort  int
+	protocol int
+}
+
+func keyOf(e *Edge) edgeKey {
+	return edgeKey{from: e.From, to: e.To, srcPort: e.SrcPort, dstPort: e.DstPort, protocol: e.Protocol}
+}
+
+type flowSummary struct {
+	bytes   int64
+	packets int64
+	actions map[string]bool
+}
+
+func summarize(g *Graph) map[edgeKey]*flowSummary {
+	summaries := make(map[edgeKey]*flowSummary)
+	for _, e := range g.Edges {
+		k := keyOf(e)
+		s, ok := summaries[k]
+		if !ok {
+			s = &flowSummary{actions: make(map[string]bool)}
+			summaries[k] = s
+		}
+		s.bytes += e.Bytes
+		s.packets += e.Packets
+		s.actions[e.Action] = true
+	}
+	return summaries
+}
+
+// Diff returns a graph containing every edge of after along with the edges of before which no longer
+// appear. Edges are matched by their nodes, ports and protocol. Edges only found in after are flagged as
+// added, edges only found in before are flagged as removed, and edges found in both are flagged as changed
+// when their actions differ or when their byte count at least doubled or halved. The input graphs are not
+// modified.
+func Diff(before, after *Graph) *Graph {
+	diff := New()
+	for id, n := range before.Nodes {
+		diff.Nodes[id] = n.clone()
+	}
+	for id, n := range after.Nodes {
+		diff.Nodes[id] = n.clone()
+	}
+	beforeFlows := summarize(before)
+	afterFlows := summarize(after)
+	for _, e := range after.Edges {
+		edge := e.clone()
+		k := keyOf(e)
+		prev, ok := beforeFlows[k]
+		switch {
+		case !ok:
+			edge.Attrs[AttrDiff] = DiffAdded
+		case changed(prev, afterFlows[k]):
+			edge.Attrs[AttrDiff] = DiffChanged
+			edge.Attrs[attrBytesBefore] = strconv.FormatInt(prev.bytes, 10)
+			edge.Attrs[attrPacketsBefore] = strconv.FormatInt(prev.packets, 10)
+		}
+		diff.Edges = append(diff.Edges, edge)
+	}
+	for _, e := range before.Edges {
+		if _, ok := afterFlows[keyOf(e)]; ok {
+			continue
+		}
+		edge := e.clone()
+		edge.Attrs[AttrDiff] = DiffRemoved
+		diff.Edges = append(diff.Edges, edge)
+	}
+	return diff
+}
+
+func changed(before, after *flowSummary) bool {
+	if len(before.actions) != len(after.actions) {
+		return true
+	}
+	for action := range after.actions {
+		if !before.actions[action] {
+			return true
+		}
+	}
+	return after.bytes >= 2*before.bytes || before.bytes >= 2*after.bytes
+}
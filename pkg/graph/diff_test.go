@@ -0,0 +1,56 @@
+package graph
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	before := New()
+	after := New()
+	for _, g := range []*Graph{before, after} {
+		g.AddNode("10.0.0.1")
+		g.AddNode("10.0.0.2")
+	}
+	after.AddNode("10.0.0.3")
+	a, b, c := NodeID("10.0.0.1"), NodeID("10.0.0.2"), NodeID("10.0.0.3")
+
+	before.Edges = []*Edge{
+		{From: a, To: b, DstPort: 443, Protocol: 6, Bytes: 100, Action: ActionAccept},
+		{From: a, To: b, DstPort: 22, Protocol: 6, Bytes: 100, Action: ActionAccept},
+		{From: a, To: b, DstPort: 80, Protocol: 6, Bytes: 100, Action: ActionAccept},
+		{From: b, To: a, DstPort: 5432, Protocol: 6, Bytes: 100, Action: ActionAccept},
+	}
+	after.Edges = []*Edge{
+		{From: a, To: b, DstPort: 443, Protocol: 6, Bytes: 150, Action: ActionAccept},
+		{From: a, To: b, DstPort: 22, Protocol: 6, Bytes: 100, Action: ActionReject},
+		{From: a, To: b, DstPort: 80, Protocol: 6, Bytes: 300, Packets: 3, Action: ActionAccept},
+		{From: a, To: c, DstPort: 443, Protocol: 6, Bytes: 100, Action: ActionAccept},
+	}
+
+	diff := Diff(before, after)
+	require.Len(t, diff.Edges, 5)
+	assert.Len(t, diff.Nodes, 3)
+	assert.Equal(t, "", diff.Edges[0].Attrs[AttrDiff])
+	assert.Equal(t, DiffChanged, diff.Edges[1].Attrs[AttrDiff])
+	assert.Equal(t, DiffChanged, diff.Edges[2].Attrs[AttrDiff])
+	assert.Equal(t, "100", diff.Edges[2].Attrs[attrBytesBefore])
+	assert.Equal(t, "0", diff.Edges[2].Attrs[attrPacketsBefore])
+	assert.Equal(t, DiffAdded, diff.Edges[3].Attrs[AttrDiff])
+	assert.Equal(t, DiffRemoved, diff.Edges[4].Attrs[AttrDiff])
+	assert.Equal(t, 5432, diff.Edges[4].DstPort)
+
+	// inputs are left untouched
+	for _, e := range append(before.Edges, after.Edges...) {
+		assert.Empty(t, e.Attrs[AttrDiff])
+	}
+
+	var buf bytes.Buffer
+	require.Nil(t, EncodeDOT(&buf, diff))
+	assert.Contains(t, buf.String(), `grapherd_diff="removed" color=gray style=dashed`)
+	assert.Contains(t, buf.String(), `grapherd_diff="added" color=blue`)
+	assert.Contains(t, buf.String(), `grapherd_diff="changed" grapherd_packets_before="0" color=orange`)
+}
@@ -0,0 +1,4 @@
+// Package graph contains the in-memory representation of a VPC flow graph
+// along with the codecs used to read and write the supported output formats.
+//
+package graph
@@ -0,0 +1,284 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gonum.org/v1/gonum/graph/formats/dot"
+	"gonum.org/v1/gonum/graph/formats/dot/ast"
+)
+
+const (
+	// flowNamespace is the attribute namespace used by the go-vpcflow DOT converter for flow data
+	flowNamespace = "govpc_"
+
+	// attrNamespace is the attribute namespace used for annotations added by this service
+	attrNamespace = "grapherd_"
+
+	actionAttr = "action"
+)
+
+// classColors are the DOT fill colors used to render nodes of each address class
+var classColors = map[string]string{
+	ClassPrivate:  "lightblue",
+	ClassAWS:      "orange",
+	ClassInternet: "lightcoral",
+}
+
+// flowAttrs is the ordered set of flow data attributes emitted by the go-vpcflow DOT converter
+var flowAttrs = []string{"accountID", "eniID", "srcPort", "dstPort", "protocol", "packets", "bytes", "start", "end"}
+
+// FromDOT parses a DOT graph, as produced by the go-vpcflow DOT converter or by EncodeDOT, into a Graph
+func FromDOT(r io.Reader) (*Graph, error) {
+	f, err := dot.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	g := New()
+	if len(f.Graphs) == 0 {
+		return g, nil
+	}
+	for _, stmt := range f.Graphs[0].Stmts {
+		switch s := stmt.(type) {
+		case *ast.NodeStmt:
+			n := nodeFromID(g, s.Node.ID)
+			for _, attr := range s.Attrs {
+				switch {
+				case attr.Key == "label":
+					// enriched labels carry additional lines after the address
+					n.Addr = strings.SplitN(unquote(attr.Val), `\n`, 2)[0]
+				case strings.HasPrefix(attr.Key, attrNamespace):
+					n.Attrs[strings.TrimPrefix(attr.Key, attrNamespace)] = unquote(attr.Val)
+				}
+			}
+		case *ast.EdgeStmt:
+			from, ok := s.From.(*ast.Node)
+			if !ok {
+				return nil, fmt.Errorf("unsupported edge source %s", s.From)
+			}
+			to, ok := s.To.Vertex.(*ast.Node)
+			if !ok || s.To.To != nil {
+				return nil, fmt.Errorf("unsupported edge destination %s", s.To)
+			}
+			e, err := edgeFromAttrs(s.Attrs)
+			if err != nil {
+				return nil, err
+			}
+			e.From = nodeFromID(g, from.ID).ID
+			e.To = nodeFromID(g, to.ID).ID
+			g.Edges = append(g.Edges, e)
+		}
+	}
+	return g, nil
+}
+
+func nodeFromID(g *Graph, id string) *Node {
+	if n, ok := g.Nodes[id]; ok {
+		return n
+	}
+	n := &Node{ID: id, Attrs: make(map[string]string)}
+	g.Nodes[id] = n
+	return n
+}
+
+func edgeFromAttrs(attrs []*ast.Attr) (*Edge, error) {
+	e := &Edge{Action: ActionAccept, Attrs: make(map[string]string)}
+	var action string
+	for _, attr := range attrs {
+		val := unquote(attr.Val)
+		var err error
+		switch attr.Key {
+		case "color":
+			if val == "red" && action == "" {
+				e.Action = ActionReject
+			}
+		case flowNamespace + "accountID":
+			e.AccountID = val
+		case flowNamespace + "eniID":
+			e.InterfaceID = val
+		case flowNamespace + "srcPort":
+			e.SrcPort, err = strconv.Atoi(val)
+		case flowNamespace + "dstPort":
+			e.DstPort, err = strconv.Atoi(val)
+		case flowNamespace + "protocol":
+			e.Protocol, err = strconv.Atoi(val)
+		case flowNamespace + "packets":
+			e.Packets, err = strconv.ParseInt(val, 10, 64)
+		case flowNamespace + "bytes":
+			e.Bytes, err = strconv.ParseInt(val, 10, 64)
+		case flowNamespace + "start":
+			e.Start, err = parseUnix(val)
+		case flowNamespace + "end":
+			e.End, err = parseUnix(val)
+		case attrNamespace + actionAttr:
+			action = val
+			e.Action = val
+		default:
+			if strings.HasPrefix(attr.Key, attrNamespace) {
+				e.Attrs[strings.TrimPrefix(attr.Key, attrNamespace)] = val
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %s", attr.Key, err.Error())
+		}
+	}
+	return e, nil
+}
+
+// EncodeDOT writes the graph in DOT format. Flow data is written using the same attributes as the
+// go-vpcflow DOT converter, and annotations are written as additional attributes.
+func EncodeDOT(w io.Writer, g *Graph) error {
+	ag := &ast.Graph{Directed: true}
+	for _, e := range g.Edges {
+		values := []string{
+			e.AccountID,
+			e.InterfaceID,
+			strconv.Itoa(e.SrcPort),
+			strconv.Itoa(e.DstPort),
+			strconv.Itoa(e.Protocol),
+			strconv.FormatInt(e.Packets, 10),
+			strconv.FormatInt(e.Bytes, 10),
+			strconv.FormatInt(e.Start.Unix(), 10),
+			strconv.FormatInt(e.End.Unix(), 10),
+		}
+		attrs := make([]*ast.Attr, 0, len(values)+len(e.Attrs)+3)
+		labels := make([]string, 0, len(values))
+		for idx, val := range values {
+			attrs = append(attrs, &ast.Attr{Key: flowNamespace + flowAttrs[idx], Val: quote(val)})
+			labels = append(labels, flowAttrs[idx]+"="+val)
+		}
+		attrs = append(attrs, &ast.Attr{Key: attrNamespace + actionAttr, Val: quote(e.Action)})
+		attrs = append(attrs, annotationAttrs(e.Attrs)...)
+		attrs = append(attrs, &ast.Attr{Key: "color", Val: edgeColor(e)})
+		if e.Attrs[AttrDiff] == DiffRemoved {
+			attrs = append(attrs, &ast.Attr{Key: "style", Val: "dashed"})
+		}
+		if e.Attrs[AttrAnomaly] != "" || e.Attrs[AttrPolicy] != "" {
+			attrs = append(attrs, &ast.Attr{Key: "penwidth", Val: "3"})
+		}
+		attrs = append(attrs, &ast.Attr{Key: "label", Val: quoteLines(labels)})
+		ag.Stmts = append(ag.Stmts, &ast.EdgeStmt{
+			From:  &ast.Node{ID: e.From},
+			To:    &ast.Edge{Directed: true, Vertex: &ast.Node{ID: e.To}},
+			Attrs: attrs,
+		})
+	}
+	for _, n := range sortedNodes(g) {
+		label := []string{n.Addr}
+		for _, k := range sortedKeys(n.Attrs) {
+			label = append(label, k+"="+n.Attrs[k])
+		}
+		attrs := []*ast.Attr{{Key: "label", Val: quoteLines(label)}}
+		attrs = append(attrs, annotationAttrs(n.Attrs)...)
+		if color, ok := classColors[n.Attrs[AttrClass]]; ok {
+			attrs = append(attrs, &ast.Attr{Key: "style", Val: "filled"}, &ast.Attr{Key: "fillcolor", Val: color})
+		}
+		if n.Attrs[AttrScan] != "" {
+			attrs = append(attrs, &ast.Attr{Key: "color", Val: "red"}, &ast.Attr{Key: "penwidth", Val: "3"})
+		}
+		ag.Stmts = append(ag.Stmts, &ast.NodeStmt{
+			Node:  &ast.Node{ID: n.ID},
+			Attrs: attrs,
+		})
+	}
+	_, err := io.WriteString(w, ag.String())
+	return err
+}
+
+// diffColors are the DOT colors used to render edges flagged by a diff
+var diffColors = map[string]string{
+	DiffAdded:   "blue",
+	DiffRemoved: "gray",
+	DiffChanged: "orange",
+}
+
+// edgeColor returns the DOT color used to render the edge
+func edgeColor(e *Edge) string {
+	if color, ok := diffColors[e.Attrs[AttrDiff]]; ok {
+		return color
+	}
+	if e.Action == ActionReject || e.Attrs[AttrPolicy] != "" {
+		return "red"
+	}
+	return "green"
+}
+
+func annotationAttrs(values map[string]string) []*ast.Attr {
+	attrs := make([]*ast.Attr, 0, len(values))
+	for _, k := range sortedKeys(values) {
+		attrs = append(attrs, &ast.Attr{Key: attrNamespace + attrKey(k), Val: quote(values[k])})
+	}
+	return attrs
+}
+
+func sortedNodes(g *Graph) []*Node {
+	nodes := make([]*Node, 0, len(g.Nodes))
+	for _, n := range g.Nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// attrKey replaces any characters which are not valid in an unquoted DOT identifier
+func attrKey(k string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, k)
+}
+
+// quoteEscaper escapes the backslashes and quotes of a value, so that it reads back as is from a DOT string
+var quoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func quote(s string) string {
+	return `"` + quoteEscaper.Replace(s) + `"`
+}
+
+// quoteLines quotes the lines of a label, which are separated by DOT line breaks
+func quoteLines(lines []string) string {
+	escaped := make([]string, 0, len(lines))
+	for _, line := range lines {
+		escaped = append(escaped, quoteEscaper.Replace(line))
+	}
+	return `"` + strings.Join(escaped, `\n`) + `"`
+}
+
+// unquote reverses quote. Other escape sequences, such as the line breaks of labels, are left as they are.
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	s = s[1 : len(s)-1]
+	var b strings.Builder
+	for idx := 0; idx < len(s); idx++ {
+		if s[idx] == '\\' && idx+1 < len(s) && (s[idx+1] == '\\' || s[idx+1] == '"') {
+			idx++
+		}
+		b.WriteByte(s[idx])
+	}
+	return b.String()
+}
+
+func parseUnix(s string) (time.Time, error) {
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
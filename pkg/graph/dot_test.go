@@ -0,0 +1,151 @@
+package graph
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/go-vpcflow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const digest = `2 123456789010 eni-abc123de 172.31.16.139 172.31.16.21 0 80 6 20 1000 1418530010 1818530070 REJECT OK
+2 123456789010 eni-abc123de 172.31.16.139 172.31.16.21 0 443 6 40 2000 1418530010 1818530070 ACCEPT OK
+`
+
+func convert(t *testing.T, data string) *Graph {
+	r, err := vpcflow.DOTConverter(ioutil.NopCloser(strings.NewReader(data)))
+	require.Nil(t, err)
+	defer r.Close()
+	g, err := FromDOT(r)
+	require.Nil(t, err)
+	return g
+}
+
+func TestFromDOT(t *testing.T) {
+	g := convert(t, digest)
+	require.Len(t, g.Nodes, 2)
+	require.Len(t, g.Edges, 2)
+	assert.Equal(t, "172.31.16.139", g.Nodes["n1723116139"].Addr)
+	assert.Equal(t, "172.31.16.21", g.Nodes["n172311621"].Addr)
+
+	byPort := map[int]*Edge{}
+	for _, e := range g.Edges {
+		byPort[e.DstPort] = e
+	}
+	reject := byPort[80]
+	assert.Equal(t, ActionReject, reject.Action)
+	assert.Equal(t, "n1723116139", reject.From)
+	assert.Equal(t, "n172311621", reject.To)
+	assert.Equal(t, "123456789010", reject.AccountID)
+	assert.Equal(t, "eni-abc123de", reject.InterfaceID)
+	assert.Equal(t, 6, reject.Protocol)
+	assert.Equal(t, int64(20), reject.Packets)
+	assert.Equal(t, int64(1000), reject.Bytes)
+	assert.True(t, time.Unix(1418530010, 0).Equal(reject.Start))
+	assert.True(t, time.Unix(1818530070, 0).Equal(reject.End))
+	assert.Equal(t, ActionAccept, byPort[443].Action)
+}
+
+func TestFromDOTEmpty(t *testing.T) {
+	g := convert(t, "")
+	assert.Empty(t, g.Nodes)
+	assert.Empty(t, g.Edges)
+}
+
+func TestFromDOTInvalid(t *testing.T) {
+	_, err := FromDOT(strings.NewReader(`digraph { n1 -> n2 [govpc_bytes="lots"] }`))
+	assert.NotNil(t, err)
+
+	_, err = FromDOT(strings.NewReader(`not a graph`))
+	assert.NotNil(t, err)
+}
+
+func TestEncodeDOTRoundTrip(t *testing.T) {
+	g := convert(t, digest)
+	g.Nodes["n1723116139"].Attrs["service"] = "payments"
+	g.Nodes["n1723116139"].Attrs["owning team"] = "core"
+	g.Edges[0].Attrs["note"] = `say "hi"`
+
+	var buf bytes.Buffer
+	require.Nil(t, EncodeDOT(&buf, g))
+	assert.Contains(t, buf.String(), `n1723116139 [label="172.31.16.139\nowning team=core\nservice=payments" grapherd_owning_team="core" grapherd_service="payments"]`)
+
+	decoded, err := FromDOT(&buf)
+	require.Nil(t, err)
+	assert.Equal(t, g.Nodes["n1723116139"].Addr, decoded.Nodes["n1723116139"].Addr)
+	assert.Equal(t, "payments", decoded.Nodes["n1723116139"].Attrs["service"])
+	require.Len(t, decoded.Edges, len(g.Edges))
+	for idx, e := range g.Edges {
+		assert.Equal(t, e.Action, decoded.Edges[idx].Action)
+		assert.Equal(t, e.DstPort, decoded.Edges[idx].DstPort)
+		assert.Equal(t, e.Bytes, decoded.Edges[idx].Bytes)
+	}
+	assert.Equal(t, `say "hi"`, decoded.Edges[0].Attrs["note"])
+}
+
+func TestEncodeDOTBackslashRoundTrip(t *testing.T) {
+	g := convert(t, digest)
+	values := []string{`C:\share\`, `ends with \`, `escaped \"quote\"`, `line\nbreak`}
+	for idx, value := range values {
+		g.Nodes["n1723116139"].Attrs[string('a'+rune(idx))] = value
+	}
+	g.Edges[0].Attrs["note"] = `\`
+
+	var buf bytes.Buffer
+	require.Nil(t, EncodeDOT(&buf, g))
+	decoded, err := FromDOT(&buf)
+	require.Nil(t, err)
+	assert.Equal(t, "172.31.16.139", decoded.Nodes["n1723116139"].Addr)
+	for idx, value := range values {
+		assert.Equal(t, value, decoded.Nodes["n1723116139"].Attrs[string('a'+rune(idx))])
+	}
+	assert.Equal(t, `\`, decoded.Edges[0].Attrs["note"])
+}
+
+func TestEncodeDOTClassColors(t *testing.T) {
+	g := New()
+	g.AddNode("10.0.0.1").Attrs[AttrClass] = ClassPrivate
+	g.AddNode("52.95.110.1").Attrs[AttrClass] = ClassAWS
+	g.AddNode("8.8.8.8").Attrs[AttrClass] = ClassInternet
+	g.AddNode("1.1.1.1")
+
+	var buf bytes.Buffer
+	require.Nil(t, EncodeDOT(&buf, g))
+	assert.Contains(t, buf.String(), `n10001 [label="10.0.0.1\nclass=private" grapherd_class="private" style=filled fillcolor=lightblue]`)
+	assert.Contains(t, buf.String(), `n52951101 [label="52.95.110.1\nclass=aws" grapherd_class="aws" style=filled fillcolor=orange]`)
+	assert.Contains(t, buf.String(), `n8888 [label="8.8.8.8\nclass=internet" grapherd_class="internet" style=filled fillcolor=lightcoral]`)
+	assert.Contains(t, buf.String(), `n1111 [label="1.1.1.1"]`)
+
+	g.Nodes[NodeID("1.1.1.1")].Flag(AttrScan, "fan-out")
+	buf.Reset()
+	require.Nil(t, EncodeDOT(&buf, g))
+	assert.Contains(t, buf.String(), `n1111 [label="1.1.1.1\nscan=fan-out" grapherd_scan="fan-out" color=red penwidth=3]`)
+}
+
+func TestEncodeDOTAnomalyWidth(t *testing.T) {
+	g := New()
+	a, b := g.AddNode("10.0.0.1"), g.AddNode("10.0.0.2")
+	e := &Edge{From: a.ID, To: b.ID, Action: ActionAccept}
+	e.Flag(AttrAnomaly, "new-edge")
+	g.Edges = append(g.Edges, e)
+
+	var buf bytes.Buffer
+	require.Nil(t, EncodeDOT(&buf, g))
+	assert.Contains(t, buf.String(), `grapherd_anomaly="new-edge" color=green penwidth=3`)
+}
+
+func TestEncodeDOTPolicyViolation(t *testing.T) {
+	g := New()
+	a, b := g.AddNode("10.0.0.1"), g.AddNode("10.0.0.2")
+	e := &Edge{From: a.ID, To: b.ID, Action: ActionAccept}
+	e.Flag(AttrPolicy, "policy-violation")
+	g.Edges = append(g.Edges, e)
+
+	var buf bytes.Buffer
+	require.Nil(t, EncodeDOT(&buf, g))
+	assert.Contains(t, buf.String(), `grapherd_policy="policy-violation" color=red penwidth=3`)
+}
@@ -0,0 +1,37 @@
+package graph
+
+import "strings"
+
+// FilterEdges returns a new graph containing only the edges for which keep returns true, along
+// with the nodes they connect. Nodes and edges are shared with the original graph.
+func FilterEdges(g *Graph, keep func(*Edge) bool) *Graph {
+	filtered := New()
+	for _, e := range g.Edges {
+		if !keep(e) {
+			continue
+		}
+		filtered.Edges = append(filtered.Edges, e)
+		for _, id := range []string{e.From, e.To} {
+			if n, ok := g.Nodes[id]; ok {
+				filtered.Nodes[id] = n
+			}
+		}
+	}
+	return filtered
+}
+
+// FilterByCountry returns a new graph containing only the edges which have at least one node
+// located in one of the given countries. Country codes are matched case insensitively.
+func FilterByCountry(g *Graph, countries []string) *Graph {
+	allowed := make(map[string]bool, len(countries))
+	for _, c := range countries {
+		allowed[strings.ToUpper(strings.TrimSpace(c))] = true
+	}
+	inCountry := func(id string) bool {
+		n, ok := g.Nodes[id]
+		return ok && allowed[strings.ToUpper(n.Attrs[AttrCountry])]
+	}
+	return FilterEdges(g, func(e *Edge) bool {
+		return inCountry(e.From) || inCountry(e.To)
+	})
+}
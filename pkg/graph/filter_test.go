@@ -0,0 +1,29 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterByCountry(t *testing.T) {
+	g := New()
+	private := g.AddNode("10.0.0.1")
+	us := g.AddNode("8.8.8.8")
+	us.Attrs[AttrCountry] = "US"
+	de := g.AddNode("85.214.132.117")
+	de.Attrs[AttrCountry] = "DE"
+	other := g.AddNode("10.0.0.2")
+	toUS := &Edge{From: private.ID, To: us.ID}
+	toDE := &Edge{From: private.ID, To: de.ID}
+	internal := &Edge{From: private.ID, To: other.ID}
+	g.Edges = []*Edge{toUS, toDE, internal}
+
+	filtered := FilterByCountry(g, []string{"de", " FR"})
+	assert.Equal(t, []*Edge{toDE}, filtered.Edges)
+	assert.Equal(t, map[string]*Node{private.ID: private, de.ID: de}, filtered.Nodes)
+
+	filtered = FilterByCountry(g, []string{"CN"})
+	assert.Empty(t, filtered.Edges)
+	assert.Empty(t, filtered.Nodes)
+}
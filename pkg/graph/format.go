@@ -0,0 +1,72 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	// FormatDOT identifies the DOT output format
+	FormatDOT = "dot"
+
+	// FormatJSON identifies the JSON output format
+	FormatJSON = "json"
+
+	// FormatGEXF identifies the dynamic GEXF output format of temporal graphs
+	FormatGEXF = "gexf"
+)
+
+// Encoder writes a graph in a specific output format
+type Encoder func(io.Writer, *Graph) error
+
+// Format describes a supported output format
+type Format struct {
+	Encoder     Encoder
+	ContentType string
+}
+
+// Formats contains all of the supported output formats, keyed by name
+var Formats = map[string]Format{
+	FormatDOT:  {Encoder: EncodeDOT, ContentType: "application/octet-stream"},
+	FormatJSON: {Encoder: EncodeJSON, ContentType: "application/json"},
+}
+
+// LookupFormat returns the named output format. An empty name selects the DOT format.
+func LookupFormat(name string) (Format, error) {
+	if name == "" {
+		name = FormatDOT
+	}
+	f, ok := Formats[name]
+	if !ok {
+		return Format{}, fmt.Errorf("unsupported format %q", name)
+	}
+	return f, nil
+}
+
+// TemporalEncoder writes a temporal graph in a specific output format
+type TemporalEncoder func(io.Writer, *Temporal) error
+
+// TemporalFormat describes a supported output format of temporal graphs
+type TemporalFormat struct {
+	Encoder     TemporalEncoder
+	ContentType string
+}
+
+// TemporalFormats contains all of the supported output formats of temporal graphs, keyed by name
+var TemporalFormats = map[string]TemporalFormat{
+	FormatJSON: {Encoder: EncodeTemporalJSON, ContentType: "application/json"},
+	FormatGEXF: {Encoder: EncodeGEXF, ContentType: "application/xml"},
+}
+
+// LookupTemporalFormat returns the named output format of temporal graphs. An empty name selects the
+// JSON format.
+func LookupTemporalFormat(name string) (TemporalFormat, error) {
+	if name == "" {
+		name = FormatJSON
+	}
+	f, ok := TemporalFormats[name]
+	if !ok {
+		return TemporalFormat{}, fmt.Errorf("unsupported format %q", name)
+	}
+	return f, nil
+}
@@ -0,0 +1,195 @@
+package graph
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// ActionAccept identifies flows which were accepted by security groups and network ACLs
+	ActionAccept = "ACCEPT"
+
+	// ActionReject identifies flows which were rejected by security groups or network ACLs
+	ActionReject = "REJECT"
+)
+
+const (
+	// AttrClass is the node attribute which classifies the address of a node as one of the Class values
+	AttrClass = "class"
+
+	// ClassPrivate identifies RFC1918, or IPv6 unique local, addresses
+	ClassPrivate = "private"
+
+	// ClassAWS identifies public addresses owned by AWS
+	ClassAWS = "aws"
+
+	// ClassInternet identifies all other public addresses
+	ClassInternet = "internet"
+
+	// AttrCountry is the node attribute holding the ISO 3166-1 country code of the address
+	AttrCountry = "country"
+
+	// AttrAnomaly is the edge attribute holding the comma separated anomalies detected on the edge
+	AttrAnomaly = "anomaly"
+
+	// AttrPolicy is the edge attribute holding the comma separated policy violations of the edge
+	AttrPolicy = "policy"
+
+	// AttrScan is the node attribute holding the comma separated scanning behaviors detected on the node
+	AttrScan = "scan"
+
+	// AttrVPC is the node attribute holding the ID of the VPC of the address, as set by an Enricher such as
+	// an asset inventory with a vpc column
+	AttrVPC = "vpc"
+)
+
+// Node is a single address observed in the flow logs. Attrs holds any additional
+// annotations, such as enrichment labels, that should be rendered with the node.
+type Node struct {
+	ID    string
+	Addr  string
+	Attrs map[string]string
+}
+
+// Edge is a single digested flow between two nodes, identified by their node IDs.
+// Attrs holds any additional annotations that should be rendered with the edge.
+type Edge struct {
+	From        string
+	To          string
+	AccountID   string
+	InterfaceID string
+	SrcPort     int
+	DstPort     int
+	Protocol    int
+	Packets     int64
+	Bytes       int64
+	Start       time.Time
+	End         time.Time
+	Action      string
+	Attrs       map[string]string
+}
+
+// ephemeralPortStart is the lowest port considered to be a client port when deciding which side
+// of a flow is the service
+const ephemeralPortStart = 1024
+
+// IsResponse reports whether the edge appears to carry the responses of a service to its client,
+// where the source port is a service port and the destination port is a client port. The go-vpcflow
+// digester records the ephemeral port of a client as 0, so an edge from a port to port 0 is a response.
+func (e *Edge) IsResponse() bool {
+	if e.SrcPort > 0 && e.DstPort == 0 {
+		return true
+	}
+	return e.SrcPort > 0 && e.SrcPort < ephemeralPortStart && e.DstPort >= ephemeralPortStart
+}
+
+// Graph is a directed graph of the flows contained in a digest
+type Graph struct {
+	Nodes map[string]*Node
+	Edges []*Edge
+}
+
+// New returns an empty graph
+func New() *Graph {
+	return &Graph{Nodes: make(map[string]*Node)}
+}
+
+// NodeID returns the identifier used for the node representing addr. It matches the
+// identifiers generated by the go-vpcflow DOT converter.
+func NodeID(addr string) string {
+	id := "n" + strings.Replace(addr, ".", "", -1)
+	return strings.Replace(id, ":", "", -1)
+}
+
+// AddNode adds a node for addr to the graph if one does not exist, and returns the node
+func (g *Graph) AddNode(addr string) *Node {
+	id := NodeID(addr)
+	if n, ok := g.Nodes[id]; ok {
+		return n
+	}
+	n := &Node{ID: id, Addr: addr, Attrs: make(map[string]string)}
+	g.Nodes[id] = n
+	return n
+}
+
+// Bounds returns the earliest start and latest end time of all edges incident to each node
+func (g *Graph) Bounds() map[string][2]time.Time {
+	bounds := make(map[string][2]time.Time, len(g.Nodes))
+	extend := func(id string, start, end time.Time) {
+		b, ok := bounds[id]
+		if !ok || start.Before(b[0]) {
+			b[0] = start
+		}
+		if !ok || end.After(b[1]) {
+			b[1] = end
+		}
+		bounds[id] = b
+	}
+	for _, e := range g.Edges {
+		extend(e.From, e.Start, e.End)
+		extend(e.To, e.Start, e.End)
+	}
+	return bounds
+}
+
+// Window returns the earliest start and latest end time of all edges in the graph
+func (g *Graph) Window() (time.Time, time.Time) {
+	var start, end time.Time
+	for _, e := range g.Edges {
+		if start.IsZero() || e.Start.Before(start) {
+			start = e.Start
+		}
+		if end.IsZero() || e.End.After(end) {
+			end = e.End
+		}
+	}
+	return start, end
+}
+
+// Flag adds value to the comma separated list held in the given edge attribute, unless it is already listed
+func (e *Edge) Flag(attr, value string) {
+	if e.Attrs == nil {
+		e.Attrs = make(map[string]string)
+	}
+	flag(e.Attrs, attr, value)
+}
+
+// Flag adds value to the comma separated list held in the given node attribute, unless it is already listed
+func (n *Node) Flag(attr, value string) {
+	if n.Attrs == nil {
+		n.Attrs = make(map[string]string)
+	}
+	flag(n.Attrs, attr, value)
+}
+
+func flag(attrs map[string]string, attr, value string) {
+	current := attrs[attr]
+	if current == "" {
+		attrs[attr] = value
+		return
+	}
+	for _, v := range strings.Split(current, ",") {
+		if v == value {
+			return
+		}
+	}
+	attrs[attr] = current + "," + value
+}
+
+func (n *Node) clone() *Node {
+	c := *n
+	c.Attrs = make(map[string]string, len(n.Attrs))
+	for k, v := range n.Attrs {
+		c.Attrs[k] = v
+	}
+	return &c
+}
+
+func (e *Edge) clone() *Edge {
+	c := *e
+	c.Attrs = make(map[string]string, len(e.Attrs))
+	for k, v := range e.Attrs {
+		c.Attrs[k] = v
+	}
+	return &c
+}
@@ -0,0 +1,76 @@
+package graph
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeID(t *testing.T) {
+	assert.Equal(t, "n10001", NodeID("10.0.0.1"))
+	assert.Equal(t, "n20010db81", NodeID("2001:0db8::1"))
+}
+
+func TestAddNode(t *testing.T) {
+	g := New()
+	n := g.AddNode("10.0.0.1")
+	assert.Equal(t, "10.0.0.1", n.Addr)
+	assert.Equal(t, n, g.AddNode("10.0.0.1"))
+	assert.Len(t, g.Nodes, 1)
+}
+
+func TestBounds(t *testing.T) {
+	g := New()
+	a := g.AddNode("10.0.0.1")
+	b := g.AddNode("10.0.0.2")
+	c := g.AddNode("10.0.0.3")
+	g.Edges = []*Edge{
+		{From: a.ID, To: b.ID, Start: time.Unix(100, 0), End: time.Unix(200, 0)},
+		{From: b.ID, To: c.ID, Start: time.Unix(50, 0), End: time.Unix(150, 0)},
+	}
+	bounds := g.Bounds()
+	assert.Equal(t, [2]time.Time{time.Unix(100, 0), time.Unix(200, 0)}, bounds[a.ID])
+	assert.Equal(t, [2]time.Time{time.Unix(50, 0), time.Unix(200, 0)}, bounds[b.ID])
+	assert.Equal(t, [2]time.Time{time.Unix(50, 0), time.Unix(150, 0)}, bounds[c.ID])
+
+	start, end := g.Window()
+	assert.Equal(t, time.Unix(50, 0), start)
+	assert.Equal(t, time.Unix(200, 0), end)
+}
+
+func TestFlag(t *testing.T) {
+	e := &Edge{}
+	e.Flag(AttrAnomaly, "new-edge")
+	e.Flag(AttrAnomaly, "unusual-port")
+	e.Flag(AttrAnomaly, "new-edge")
+	assert.Equal(t, "new-edge,unusual-port", e.Attrs[AttrAnomaly])
+
+	n := &Node{}
+	n.Flag(AttrScan, "fan-out")
+	n.Flag(AttrScan, "port-scan")
+	assert.Equal(t, "fan-out,port-scan", n.Attrs[AttrScan])
+}
+
+func TestIsResponse(t *testing.T) {
+	tc := []struct {
+		Name     string
+		SrcPort  int
+		DstPort  int
+		Response bool
+	}{
+		{Name: "digest request", SrcPort: 0, DstPort: 443},
+		{Name: "digest response", SrcPort: 443, DstPort: 0, Response: true},
+		{Name: "digest response of a high port", SrcPort: 8080, DstPort: 0, Response: true},
+		{Name: "request", SrcPort: 40000, DstPort: 443},
+		{Name: "response", SrcPort: 443, DstPort: 40000, Response: true},
+		{Name: "between high ports", SrcPort: 8080, DstPort: 40000},
+		{Name: "no ports", SrcPort: 0, DstPort: 0},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			e := &Edge{SrcPort: tt.SrcPort, DstPort: tt.DstPort}
+			assert.Equal(t, tt.Response, e.IsResponse())
+		})
+	}
+}
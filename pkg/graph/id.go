@@ -0,0 +1,18 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var graphNamespace = uuid.NewSHA1(uuid.Nil, []byte("graph"))
+
+// ID generates the identifier of the graph covering start to stop, which is a UUID v5 from a name composed
+// by appending the start and stop time strings in that order. Since the time strings include the location,
+// times should be in UTC to match the identifiers of graphs requested through the API.
+func ID(start, stop time.Time) string {
+	name := start.String() + stop.String()
+	u := uuid.NewSHA1(graphNamespace, []byte(name))
+	return u.String()
+}
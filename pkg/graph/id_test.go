@@ -0,0 +1,16 @@
+package graph
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestID(t *testing.T) {
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(time.Hour)
+	assert.Equal(t, ID(start, stop), ID(start, stop))
+	assert.NotEqual(t, ID(start, stop), ID(start, stop.Add(time.Hour)))
+	assert.NotEqual(t, ID(start, stop), ID(start.In(time.FixedZone("PDT", -7*3600)), stop))
+}
@@ -0,0 +1,71 @@
+package graph
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+type jsonGraph struct {
+	Nodes []jsonNode `json:"nodes"`
+	Edges []jsonEdge `json:"edges"`
+}
+
+type jsonNode struct {
+	ID         string            `json:"id"`
+	Addr       string            `json:"addr"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+type jsonEdge struct {
+	From        string            `json:"from"`
+	To          string            `json:"to"`
+	AccountID   string            `json:"accountID"`
+	InterfaceID string            `json:"eniID"`
+	SrcPort     int               `json:"srcPort"`
+	DstPort     int               `json:"dstPort"`
+	Protocol    int               `json:"protocol"`
+	Packets     int64             `json:"packets"`
+	Bytes       int64             `json:"bytes"`
+	Start       time.Time         `json:"start"`
+	End         time.Time         `json:"end"`
+	Action      string            `json:"action"`
+	Attributes  map[string]string `json:"attributes"`
+}
+
+// EncodeJSON writes the graph as a JSON document containing a list of nodes and a list of edges.
+// Node and edge annotations are written to the attributes of each element.
+func EncodeJSON(w io.Writer, g *Graph) error {
+	doc := jsonGraph{
+		Nodes: make([]jsonNode, 0, len(g.Nodes)),
+		Edges: make([]jsonEdge, 0, len(g.Edges)),
+	}
+	for _, n := range sortedNodes(g) {
+		doc.Nodes = append(doc.Nodes, jsonNode{ID: n.ID, Addr: n.Addr, Attributes: nonNil(n.Attrs)})
+	}
+	for _, e := range g.Edges {
+		doc.Edges = append(doc.Edges, jsonEdge{
+			From:        e.From,
+			To:          e.To,
+			AccountID:   e.AccountID,
+			InterfaceID: e.InterfaceID,
+			SrcPort:     e.SrcPort,
+			DstPort:     e.DstPort,
+			Protocol:    e.Protocol,
+			Packets:     e.Packets,
+			Bytes:       e.Bytes,
+			Start:       e.Start.UTC(),
+			End:         e.End.UTC(),
+			Action:      e.Action,
+			Attributes:  nonNil(e.Attrs),
+		})
+	}
+	return json.NewEncoder(w).Encode(doc)
+}
+
+func nonNil(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
@@ -0,0 +1,66 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeJSON(t *testing.T) {
+	g := New()
+	a := g.AddNode("10.0.0.1")
+	a.Attrs["service"] = "payments"
+	b := g.AddNode("10.0.0.2")
+	g.Edges = []*Edge{{
+		From:        a.ID,
+		To:          b.ID,
+		AccountID:   "123456789010",
+		InterfaceID: "eni-abc123de",
+		DstPort:     443,
+		Protocol:    6,
+		Packets:     10,
+		Bytes:       1000,
+		Start:       time.Unix(1418530010, 0),
+		End:         time.Unix(1418530070, 0),
+		Action:      ActionAccept,
+		Attrs:       map[string]string{AttrDiff: DiffAdded},
+	}}
+
+	var buf bytes.Buffer
+	require.Nil(t, EncodeJSON(&buf, g))
+	var doc map[string]interface{}
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &doc))
+
+	nodes := doc["nodes"].([]interface{})
+	require.Len(t, nodes, 2)
+	assert.Equal(t, map[string]interface{}{"id": "n10001", "addr": "10.0.0.1", "attributes": map[string]interface{}{"service": "payments"}}, nodes[0])
+	assert.Equal(t, map[string]interface{}{}, nodes[1].(map[string]interface{})["attributes"])
+
+	edges := doc["edges"].([]interface{})
+	require.Len(t, edges, 1)
+	edge := edges[0].(map[string]interface{})
+	assert.Equal(t, "n10001", edge["from"])
+	assert.Equal(t, "n10002", edge["to"])
+	assert.Equal(t, float64(443), edge["dstPort"])
+	assert.Equal(t, float64(1000), edge["bytes"])
+	assert.Equal(t, "2014-12-14T04:06:50Z", edge["start"])
+	assert.Equal(t, "ACCEPT", edge["action"])
+	assert.Equal(t, map[string]interface{}{"diff": "added"}, edge["attributes"])
+}
+
+func TestLookupFormat(t *testing.T) {
+	f, err := LookupFormat("")
+	assert.Nil(t, err)
+	assert.Equal(t, "application/octet-stream", f.ContentType)
+
+	f, err = LookupFormat(FormatJSON)
+	assert.Nil(t, err)
+	assert.Equal(t, "application/json", f.ContentType)
+
+	_, err = LookupFormat("svg")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,68 @@
+package graph
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	// MetadataSuffix is appended to the ID of a graph to name the metadata stored alongside it
+	MetadataSuffix = ".metadata.json"
+
+	// IndexPrefix is the prefix of the index entries of the graphs, which are ordered by window
+	IndexPrefix = "graphs/"
+
+	indexLayout = "20060102T150405Z"
+	indexSuffix = ".json"
+)
+
+// Metadata describes a stored graph
+type Metadata struct {
+	ID    string    `json:"id"`
+	Start time.Time `json:"start"`
+	Stop  time.Time `json:"stop"`
+	// Filters are the filters applied to the flows of the graph. Stored graphs cover every flow of their
+	// window, so this is only set for graphs which were filtered before being stored.
+	Filters []string `json:"filters,omitempty"`
+	// Format is the output format the graph is stored in
+	Format string `json:"format"`
+	// Size is the size of the graph before it is compressed by the storage, in bytes
+	Size int `json:"size"`
+	// Hash is the hex encoded SHA-256 of the graph before it is compressed by the storage
+	Hash    string    `json:"hash,omitempty"`
+	Created time.Time `json:"created"`
+	// Version identifies the version of the service which created the graph
+	Version string `json:"version"`
+}
+
+// Overlaps returns true if the window of the graph overlaps start to stop
+func (m Metadata) Overlaps(start, stop time.Time) bool {
+	return m.Start.Before(stop) && m.Stop.After(start)
+}
+
+// IndexKey returns the key of the index entry of a graph. Index entries sort by the start of their window,
+// then by its stop, so that graphs can be listed in chronological order without reading each of them.
+func IndexKey(id string, start, stop time.Time) string {
+	return IndexPrefix + start.UTC().Format(indexLayout) + "_" + stop.UTC().Format(indexLayout) + "_" + id + indexSuffix
+}
+
+// ParseIndexKey returns the ID and window of the graph of an index entry
+func ParseIndexKey(key string) (string, time.Time, time.Time, error) {
+	if !strings.HasPrefix(key, IndexPrefix) || !strings.HasSuffix(key, indexSuffix) {
+		return "", time.Time{}, time.Time{}, errors.New("invalid index key " + key)
+	}
+	parts := strings.SplitN(strings.TrimSuffix(strings.TrimPrefix(key, IndexPrefix), indexSuffix), "_", 3)
+	if len(parts) != 3 || parts[2] == "" {
+		return "", time.Time{}, time.Time{}, errors.New("invalid index key " + key)
+	}
+	start, err := time.Parse(indexLayout, parts[0])
+	if err != nil {
+		return "", time.Time{}, time.Time{}, err
+	}
+	stop, err := time.Parse(indexLayout, parts[1])
+	if err != nil {
+		return "", time.Time{}, time.Time{}, err
+	}
+	return parts[2], start, stop, nil
+}
@@ -0,0 +1,48 @@
+package graph
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexKey(t *testing.T) {
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(time.Hour)
+	key := IndexKey("abc", start.In(time.FixedZone("PDT", -7*3600)), stop)
+	assert.Equal(t, "graphs/20190501T000000Z_20190501T010000Z_abc.json", key)
+
+	id, parsedStart, parsedStop, err := ParseIndexKey(key)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", id)
+	assert.True(t, start.Equal(parsedStart))
+	assert.True(t, stop.Equal(parsedStop))
+
+	// later windows sort after earlier ones
+	assert.True(t, key < IndexKey("aaa", start.Add(time.Minute), stop))
+}
+
+func TestParseIndexKeyInvalid(t *testing.T) {
+	for _, key := range []string{
+		"abc.json",
+		"graphs/20190501T000000Z_20190501T010000Z_abc",
+		"graphs/20190501T000000Z_abc.json",
+		"graphs/20190501T000000Z_20190501T010000Z_.json",
+		"graphs/yesterday_20190501T010000Z_abc.json",
+		"graphs/20190501T000000Z_today_abc.json",
+	} {
+		_, _, _, err := ParseIndexKey(key)
+		assert.Error(t, err, key)
+	}
+}
+
+func TestMetadataOverlaps(t *testing.T) {
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	m := Metadata{Start: start, Stop: start.Add(time.Hour)}
+	assert.True(t, m.Overlaps(start.Add(30*time.Minute), start.Add(2*time.Hour)))
+	assert.True(t, m.Overlaps(start.Add(-time.Hour), start.Add(time.Minute)))
+	assert.False(t, m.Overlaps(start.Add(time.Hour), start.Add(2*time.Hour)))
+	assert.False(t, m.Overlaps(start.Add(-time.Hour), start))
+}
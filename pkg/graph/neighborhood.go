@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ParseNetwork parses either a CIDR block or a single address, which is treated as a host network
+func ParseNetwork(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, network, err := net.ParseCIDR(s)
+		return network, err
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address %q", s)
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// NodesIn returns the IDs of the nodes whose address is contained in the network
+func NodesIn(g *Graph, network *net.IPNet) map[string]bool {
+	ids := make(map[string]bool)
+	for id, n := range g.Nodes {
+		if ip := net.ParseIP(n.Addr); ip != nil && network.Contains(ip) {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// Neighborhood returns a new graph containing the nodes within depth hops of any node in the seed
+// network, regardless of the direction of traffic, along with the edges used to reach them. Nodes and
+// edges are shared with the original graph.
+func Neighborhood(g *Graph, seed *net.IPNet, depth int) *Graph {
+	distance := make(map[string]int)
+	frontier := make([]string, 0)
+	for id := range NodesIn(g, seed) {
+		distance[id] = 0
+		frontier = append(frontier, id)
+	}
+	adjacent := make(map[string][]string, len(g.Nodes))
+	for _, e := range g.Edges {
+		adjacent[e.From] = append(adjacent[e.From], e.To)
+		adjacent[e.To] = append(adjacent[e.To], e.From)
+	}
+	for hop := 1; hop <= depth && len(frontier) > 0; hop++ {
+		var next []string
+		for _, id := range frontier {
+			for _, neighbor := range adjacent[id] {
+				if _, seen := distance[neighbor]; !seen {
+					distance[neighbor] = hop
+					next = append(next, neighbor)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	within := func(id string, max int) bool {
+		d, ok := distance[id]
+		return ok && d <= max
+	}
+	sub := FilterEdges(g, func(e *Edge) bool {
+		return (within(e.From, depth-1) && within(e.To, depth)) || (within(e.To, depth-1) && within(e.From, depth))
+	})
+	// seeds without any edges in range are still part of the neighborhood
+	for id, d := range distance {
+		if d == 0 {
+			sub.Nodes[id] = g.Nodes[id]
+		}
+	}
+	return sub
+}
@@ -0,0 +1,49 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNetwork(t *testing.T) {
+	n, err := ParseNetwork("10.0.0.1")
+	require.Nil(t, err)
+	assert.Equal(t, "10.0.0.1/32", n.String())
+	n, err = ParseNetwork("10.0.0.0/24")
+	require.Nil(t, err)
+	assert.Equal(t, "10.0.0.0/24", n.String())
+	n, err = ParseNetwork("fd00::1")
+	require.Nil(t, err)
+	assert.Equal(t, "fd00::1/128", n.String())
+	_, err = ParseNetwork("not an address")
+	assert.NotNil(t, err)
+}
+
+func TestNeighborhood(t *testing.T) {
+	g := New()
+	a := g.AddNode("10.0.0.1")
+	b := g.AddNode("10.0.0.2")
+	c := g.AddNode("10.0.0.3")
+	d := g.AddNode("10.0.0.4")
+	lonely := g.AddNode("10.0.1.1")
+	ab := &Edge{From: a.ID, To: b.ID}
+	cb := &Edge{From: c.ID, To: b.ID}
+	cd := &Edge{From: c.ID, To: d.ID}
+	g.Edges = []*Edge{ab, cb, cd}
+
+	seed, _ := ParseNetwork("10.0.0.1")
+	oneHop := Neighborhood(g, seed, 1)
+	assert.Equal(t, []*Edge{ab}, oneHop.Edges)
+	assert.Len(t, oneHop.Nodes, 2)
+
+	twoHops := Neighborhood(g, seed, 2)
+	assert.Equal(t, []*Edge{ab, cb}, twoHops.Edges)
+	assert.Len(t, twoHops.Nodes, 3)
+
+	seed, _ = ParseNetwork("10.0.1.0/24")
+	isolated := Neighborhood(g, seed, 2)
+	assert.Empty(t, isolated.Edges)
+	assert.Equal(t, map[string]*Node{lonely.ID: lonely}, isolated.Nodes)
+}
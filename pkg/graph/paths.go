@@ -0,0 +1,170 @@
+package graph
+
+import (
+	"net"
+	"sort"
+)
+
+// pathSteps is the number of hops FindPaths may explore before it gives up, which bounds the work done on
+// densely connected graphs regardless of the number of paths found
+var pathSteps = 100000
+
+// Port is a destination port and protocol
+type Port struct {
+	Port     int `json:"port"`
+	Protocol int `json:"protocol"`
+}
+
+// Hop is the accepted traffic observed from one address to another
+type Hop struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Ports   []Port `json:"ports"`
+	Bytes   int64  `json:"bytes"`
+	Packets int64  `json:"packets"`
+}
+
+// Path is a sequence of hops leading from a source address to a destination address
+type Path struct {
+	Hops []Hop `json:"hops"`
+}
+
+// FindPaths returns the paths of accepted traffic, following the direction of the flows, leading from a
+// node in the source network to a node in the destination network in at most maxHops hops. Paths end at
+// the first destination node reached, and never visit a node twice. Only the nodes from which a destination
+// node can be reached in the remaining hops are explored. At most limit paths are returned, and the second
+// return value reports whether more paths were found, or whether the exploration was abandoned before every
+// path was found.
+func FindPaths(g *Graph, source, destination *net.IPNet, maxHops, limit int) ([]Path, bool) {
+	hops := make(map[[2]string]*Hop)
+	next := make(map[string][]string)
+	for _, e := range g.Edges {
+		if e.Action == ActionReject || e.From == e.To {
+			continue
+		}
+		k := [2]string{e.From, e.To}
+		h, ok := hops[k]
+		if !ok {
+			h = &Hop{From: nodeAddr(g, e.From), To: nodeAddr(g, e.To)}
+			hops[k] = h
+			next[e.From] = append(next[e.From], e.To)
+		}
+		h.Bytes += e.Bytes
+		h.Packets += e.Packets
+		port := Port{Port: e.DstPort, Protocol: e.Protocol}
+		if !containsPort(h.Ports, port) {
+			h.Ports = append(h.Ports, port)
+		}
+	}
+	for _, h := range hops {
+		sort.Slice(h.Ports, func(i, j int) bool {
+			a, b := h.Ports[i], h.Ports[j]
+			return a.Port < b.Port || (a.Port == b.Port && a.Protocol < b.Protocol)
+		})
+	}
+	for _, targets := range next {
+		sort.Strings(targets)
+	}
+
+	destinations := NodesIn(g, destination)
+	distances := distancesTo(next, destinations)
+	sources := make([]string, 0)
+	for id := range NodesIn(g, source) {
+		sources = append(sources, id)
+	}
+	sort.Strings(sources)
+
+	paths := make([]Path, 0)
+	truncated := false
+	steps := 0
+	visited := make(map[string]bool)
+	var route []string
+	var walk func(id string)
+	walk = func(id string) {
+		if truncated {
+			return
+		}
+		if len(route) > 1 && destinations[id] {
+			if len(paths) == limit {
+				truncated = true
+				return
+			}
+			p := Path{Hops: make([]Hop, 0, len(route)-1)}
+			for idx := 1; idx < len(route); idx++ {
+				p.Hops = append(p.Hops, *hops[[2]string{route[idx-1], route[idx]}])
+			}
+			paths = append(paths, p)
+			return
+		}
+		if len(route)-1 >= maxHops {
+			return
+		}
+		for _, to := range next[id] {
+			distance, ok := distances[to]
+			if visited[to] || !ok || len(route)+distance > maxHops {
+				continue
+			}
+			if steps == pathSteps {
+				truncated = true
+				return
+			}
+			steps++
+			visited[to] = true
+			route = append(route, to)
+			walk(to)
+			route = route[:len(route)-1]
+			visited[to] = false
+		}
+	}
+	for _, id := range sources {
+		visited[id] = true
+		route = append(route[:0], id)
+		walk(id)
+		visited[id] = false
+	}
+	return paths, truncated
+}
+
+// distancesTo returns the least number of hops from each node to any of the destinations. Nodes which cannot
+// reach a destination are absent.
+func distancesTo(next map[string][]string, destinations map[string]bool) map[string]int {
+	previous := make(map[string][]string)
+	for from, targets := range next {
+		for _, to := range targets {
+			previous[to] = append(previous[to], from)
+		}
+	}
+	distances := make(map[string]int, len(destinations))
+	queue := make([]string, 0, len(destinations))
+	for id := range destinations {
+		distances[id] = 0
+		queue = append(queue, id)
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, from := range previous[id] {
+			if _, ok := distances[from]; !ok {
+				distances[from] = distances[id] + 1
+				queue = append(queue, from)
+			}
+		}
+	}
+	return distances
+}
+
+func containsPort(ports []Port, port Port) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeAddr(g *Graph, id string) string {
+	if n, ok := g.Nodes[id]; ok {
+		return n.Addr
+	}
+	return id
+}
@@ -0,0 +1,69 @@
+package graph
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindPaths(t *testing.T) {
+	g := New()
+	dmz := g.AddNode("10.0.1.10")
+	app := g.AddNode("10.0.2.10")
+	db := g.AddNode("10.0.3.10")
+	g.Edges = []*Edge{
+		{From: dmz.ID, To: app.ID, DstPort: 8080, Protocol: 6, Bytes: 100, Packets: 1, Action: ActionAccept},
+		{From: dmz.ID, To: app.ID, DstPort: 443, Protocol: 6, Bytes: 50, Packets: 1, Action: ActionAccept},
+		{From: app.ID, To: db.ID, DstPort: 5432, Protocol: 6, Bytes: 10, Packets: 1, Action: ActionAccept},
+		{From: dmz.ID, To: db.ID, DstPort: 5432, Protocol: 6, Bytes: 10, Packets: 1, Action: ActionReject},
+		{From: db.ID, To: dmz.ID, DstPort: 40000, Protocol: 6, Bytes: 10, Packets: 1, Action: ActionAccept},
+	}
+	source, _ := ParseNetwork("10.0.1.0/24")
+	destination, _ := ParseNetwork("10.0.3.0/24")
+
+	paths, truncated := FindPaths(g, source, destination, 3, 10)
+	assert.False(t, truncated)
+	assert.Equal(t, []Path{{Hops: []Hop{
+		{From: "10.0.1.10", To: "10.0.2.10", Ports: []Port{{443, 6}, {8080, 6}}, Bytes: 150, Packets: 2},
+		{From: "10.0.2.10", To: "10.0.3.10", Ports: []Port{{5432, 6}}, Bytes: 10, Packets: 1},
+	}}}, paths)
+
+	paths, _ = FindPaths(g, source, destination, 1, 10)
+	assert.Empty(t, paths)
+	assert.NotNil(t, paths)
+
+	paths, truncated = FindPaths(g, source, destination, 3, 0)
+	assert.Empty(t, paths)
+	assert.True(t, truncated)
+}
+
+func TestFindPathsExplorationBounded(t *testing.T) {
+	// every node of a densely connected graph talks to every other node
+	g := New()
+	ids := make([]string, 0)
+	for i := 0; i < 12; i++ {
+		ids = append(ids, g.AddNode(fmt.Sprintf("10.0.1.%d", i)).ID)
+	}
+	for _, from := range ids {
+		for _, to := range ids {
+			g.Edges = append(g.Edges, &Edge{From: from, To: to, DstPort: 443, Protocol: 6, Action: ActionAccept})
+		}
+	}
+	source, _ := ParseNetwork("10.0.1.0")
+	unreachable, _ := ParseNetwork("10.0.9.0/24")
+	reachable, _ := ParseNetwork("10.0.1.11")
+
+	// no node is explored when the destination cannot be reached
+	defer func(steps int) { pathSteps = steps }(pathSteps)
+	pathSteps = 0
+	paths, truncated := FindPaths(g, source, unreachable, 6, 1000000)
+	assert.Empty(t, paths)
+	assert.False(t, truncated)
+
+	// the exploration is abandoned once its budget is spent, regardless of the limit of paths
+	pathSteps = 100
+	paths, truncated = FindPaths(g, source, reachable, 6, 1000000)
+	assert.NotEmpty(t, paths)
+	assert.True(t, truncated)
+}
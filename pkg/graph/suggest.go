@@ -0,0 +1,231 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// protocolNames are the security group names of the IANA protocol numbers
+var protocolNames = map[int]string{
+	1:  "icmp",
+	6:  "tcp",
+	17: "udp",
+	58: "icmpv6",
+}
+
+// IngressRule is a security group ingress rule. A port range of -1 to -1 covers all ports.
+type IngressRule struct {
+	Protocol       string   `json:"protocol"`
+	FromPort       int      `json:"fromPort"`
+	ToPort         int      `json:"toPort"`
+	CIDRBlocks     []string `json:"cidrBlocks,omitempty"`
+	IPv6CIDRBlocks []string `json:"ipv6CidrBlocks,omitempty"`
+	Flows          int      `json:"flows"`
+	Bytes          int64    `json:"bytes"`
+}
+
+type ingressKey struct {
+	protocol int
+	fromPort int
+	toPort   int
+}
+
+// SuggestIngress derives the minimal set of ingress rules covering every accepted flow into the destination
+// networks. Flows which appear to be responses are ignored, since security groups are stateful, as are flows
+// to port 0. Contiguous
+// ports used by the same source are merged into a single range, and the sources sharing a port range are
+// merged into the fewest CIDR blocks which cover exactly those sources.
+func SuggestIngress(g *Graph, destinations []*net.IPNet) []IngressRule {
+	inDestination := make(map[string]bool)
+	for _, d := range destinations {
+		for id := range NodesIn(g, d) {
+			inDestination[id] = true
+		}
+	}
+
+	type sourceKey struct {
+		addr     string
+		protocol int
+	}
+	ports := make(map[sourceKey]map[int]bool)
+	flows := make(map[sourceKey]map[int]int)
+	volume := make(map[sourceKey]map[int]int64)
+	for _, e := range g.Edges {
+		if e.Action == ActionReject || e.IsResponse() || !inDestination[e.To] {
+			continue
+		}
+		k := sourceKey{nodeAddr(g, e.From), e.Protocol}
+		port := e.DstPort
+		if _, ok := protocolNames[e.Protocol]; !ok || e.Protocol == 1 || e.Protocol == 58 {
+			port = -1
+		}
+		// the digester records the ports of clients as 0, which no rule needs to allow
+		if port == 0 {
+			continue
+		}
+		if _, ok := ports[k]; !ok {
+			ports[k] = make(map[int]bool)
+			flows[k] = make(map[int]int)
+			volume[k] = make(map[int]int64)
+		}
+		ports[k][port] = true
+		flows[k][port]++
+		volume[k][port] += e.Bytes
+	}
+
+	sources := make(map[ingressKey][]string)
+	rules := make(map[ingressKey]*IngressRule)
+	for k, used := range ports {
+		for _, r := range portRanges(used) {
+			ik := ingressKey{k.protocol, r[0], r[1]}
+			rule, ok := rules[ik]
+			if !ok {
+				rule = &IngressRule{Protocol: protocolName(k.protocol), FromPort: r[0], ToPort: r[1]}
+				rules[ik] = rule
+			}
+			for port := range used {
+				if port >= r[0] && port <= r[1] {
+					rule.Flows += flows[k][port]
+					rule.Bytes += volume[k][port]
+				}
+			}
+			sources[ik] = append(sources[ik], k.addr)
+		}
+	}
+
+	suggested := make([]IngressRule, 0, len(rules))
+	for ik, rule := range rules {
+		for _, block := range aggregateAddresses(sources[ik]) {
+			if block.IP.To4() != nil {
+				rule.CIDRBlocks = append(rule.CIDRBlocks, block.String())
+			} else {
+				rule.IPv6CIDRBlocks = append(rule.IPv6CIDRBlocks, block.String())
+			}
+		}
+		suggested = append(suggested, *rule)
+	}
+	sort.Slice(suggested, func(i, j int) bool {
+		a, b := suggested[i], suggested[j]
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.FromPort < b.FromPort || (a.FromPort == b.FromPort && a.ToPort < b.ToPort)
+	})
+	return suggested
+}
+
+// EncodeTerraform writes the ingress rules as the ingress blocks of a Terraform aws_security_group resource
+func EncodeTerraform(w io.Writer, name string, rules []IngressRule) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "resource \"aws_security_group\" %q {\n", name)
+	for idx, rule := range rules {
+		if idx > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("  ingress {\n")
+		fmt.Fprintf(&b, "    description      = %q\n", fmt.Sprintf("%d observed flows, %d bytes", rule.Flows, rule.Bytes))
+		fmt.Fprintf(&b, "    protocol         = %q\n", rule.Protocol)
+		fmt.Fprintf(&b, "    from_port        = %d\n", rule.FromPort)
+		fmt.Fprintf(&b, "    to_port          = %d\n", rule.ToPort)
+		if len(rule.CIDRBlocks) > 0 {
+			fmt.Fprintf(&b, "    cidr_blocks      = [%s]\n", quoteList(rule.CIDRBlocks))
+		}
+		if len(rule.IPv6CIDRBlocks) > 0 {
+			fmt.Fprintf(&b, "    ipv6_cidr_blocks = [%s]\n", quoteList(rule.IPv6CIDRBlocks))
+		}
+		b.WriteString("  }\n")
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// portRanges merges the used ports into contiguous ranges
+func portRanges(used map[int]bool) [][2]int {
+	ports := make([]int, 0, len(used))
+	for port := range used {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	var ranges [][2]int
+	for _, port := range ports {
+		if n := len(ranges); n > 0 && ranges[n-1][1]+1 == port && port > 0 {
+			ranges[n-1][1] = port
+			continue
+		}
+		ranges = append(ranges, [2]int{port, port})
+	}
+	return ranges
+}
+
+// aggregateAddresses returns the fewest CIDR blocks covering exactly the given addresses
+func aggregateAddresses(addrs []string) []*net.IPNet {
+	blocks := make(map[string]*net.IPNet)
+	for _, addr := range addrs {
+		if n, err := ParseNetwork(addr); err == nil {
+			blocks[n.String()] = n
+		}
+	}
+	for merged := true; merged; {
+		merged = false
+		for key, block := range blocks {
+			ones, bits := block.Mask.Size()
+			if ones == 0 {
+				continue
+			}
+			sibling := &net.IPNet{IP: make(net.IP, len(block.IP)), Mask: block.Mask}
+			copy(sibling.IP, block.IP)
+			bit := ones - 1
+			sibling.IP[bit/8] ^= 0x80 >> uint(bit%8)
+			if _, ok := blocks[sibling.String()]; !ok {
+				continue
+			}
+			mask := net.CIDRMask(ones-1, bits)
+			parent := &net.IPNet{IP: block.IP.Mask(mask), Mask: mask}
+			delete(blocks, key)
+			delete(blocks, sibling.String())
+			blocks[parent.String()] = parent
+			merged = true
+			break
+		}
+	}
+	aggregated := make([]*net.IPNet, 0, len(blocks))
+	for _, block := range blocks {
+		aggregated = append(aggregated, block)
+	}
+	sort.Slice(aggregated, func(i, j int) bool {
+		return compareIP(aggregated[i].IP, aggregated[j].IP) < 0
+	})
+	return aggregated
+}
+
+func compareIP(a, b net.IP) int {
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return int(a[idx]) - int(b[idx])
+		}
+	}
+	return 0
+}
+
+func protocolName(protocol int) string {
+	if name, ok := protocolNames[protocol]; ok {
+		return name
+	}
+	return strconv.Itoa(protocol)
+}
+
+func quoteList(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, strconv.Quote(v))
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,73 @@
+package graph
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestIngress(t *testing.T) {
+	g := New()
+	web := g.AddNode("10.0.2.10")
+	db := g.AddNode("10.0.3.10")
+	edge := func(src string, srcPort, dstPort, protocol int, action string) *Edge {
+		return &Edge{From: g.AddNode(src).ID, To: db.ID, SrcPort: srcPort, DstPort: dstPort, Protocol: protocol, Bytes: 10, Action: action}
+	}
+	g.Edges = []*Edge{
+		edge("10.0.1.0", 40000, 5432, 6, ActionAccept),
+		edge("10.0.1.1", 40000, 5432, 6, ActionAccept),
+		edge("10.0.1.2", 40000, 5432, 6, ActionAccept),
+		edge("10.0.1.2", 40000, 5433, 6, ActionAccept),
+		edge("10.0.1.9", 40000, 22, 6, ActionReject),
+		edge("10.0.1.9", 0, 0, 1, ActionAccept),
+		edge("10.0.1.9", 443, 40000, 6, ActionAccept),
+		{From: db.ID, To: web.ID, SrcPort: 40000, DstPort: 80, Protocol: 6, Action: ActionAccept},
+	}
+	destination, _ := ParseNetwork("10.0.3.0/24")
+
+	rules := SuggestIngress(g, []*net.IPNet{destination})
+	assert.Equal(t, []IngressRule{
+		{Protocol: "icmp", FromPort: -1, ToPort: -1, CIDRBlocks: []string{"10.0.1.9/32"}, Flows: 1, Bytes: 10},
+		{Protocol: "tcp", FromPort: 5432, ToPort: 5432, CIDRBlocks: []string{"10.0.1.0/31"}, Flows: 2, Bytes: 20},
+		{Protocol: "tcp", FromPort: 5432, ToPort: 5433, CIDRBlocks: []string{"10.0.1.2/32"}, Flows: 2, Bytes: 20},
+	}, rules)
+
+	var buf bytes.Buffer
+	require.Nil(t, EncodeTerraform(&buf, "suggested", rules[:1]))
+	assert.Equal(t, `resource "aws_security_group" "suggested" {
+  ingress {
+    description      = "1 observed flows, 10 bytes"
+    protocol         = "icmp"
+    from_port        = -1
+    to_port          = -1
+    cidr_blocks      = ["10.0.1.9/32"]
+  }
+}
+`, buf.String())
+}
+
+func TestSuggestIngressDigest(t *testing.T) {
+	// the digester records client ports as 0, so responses and their requests both involve port 0
+	g := convert(t, `2 123456789010 eni-abc123de 172.31.16.139 172.31.16.21 0 443 6 20 1000 1418530010 1418530070 ACCEPT OK
+2 123456789010 eni-abc123de 172.31.16.21 172.31.16.139 443 0 6 20 4000 1418530010 1418530070 ACCEPT OK
+2 123456789010 eni-abc123de 172.31.16.139 172.31.16.21 0 0 6 1 40 1418530010 1418530070 ACCEPT OK
+`)
+	destination, _ := ParseNetwork("172.31.16.0/24")
+
+	rules := SuggestIngress(g, []*net.IPNet{destination})
+	assert.Equal(t, []IngressRule{
+		{Protocol: "tcp", FromPort: 443, ToPort: 443, CIDRBlocks: []string{"172.31.16.139/32"}, Flows: 1, Bytes: 1000},
+	}, rules)
+}
+
+func TestAggregateAddresses(t *testing.T) {
+	blocks := aggregateAddresses([]string{"10.0.0.0", "10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.5", "fd00::1"})
+	var got []string
+	for _, b := range blocks {
+		got = append(got, b.String())
+	}
+	assert.Equal(t, []string{"10.0.0.0/30", "10.0.0.5/32", "fd00::1/128"}, got)
+}
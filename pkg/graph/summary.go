@@ -0,0 +1,271 @@
+package graph
+
+import (
+	"sort"
+
+	"gonum.org/v1/gonum/graph/simple"
+	"gonum.org/v1/gonum/graph/topo"
+)
+
+// betweennessPivots is the number of sources from which shortest paths are explored to estimate the
+// betweenness centrality of the nodes, which bounds its cost on large graphs
+var betweennessPivots = 500
+
+// Talker is the total traffic sent and received by a node
+type Talker struct {
+	Addr    string `json:"addr"`
+	Bytes   int64  `json:"bytes"`
+	Packets int64  `json:"packets"`
+}
+
+// Degree is the number of distinct nodes a node received traffic from, and sent traffic to
+type Degree struct {
+	Addr  string `json:"addr"`
+	In    int    `json:"in"`
+	Out   int    `json:"out"`
+	Total int    `json:"total"`
+}
+
+// Centrality is the betweenness centrality of a node
+type Centrality struct {
+	Addr        string  `json:"addr"`
+	Betweenness float64 `json:"betweenness"`
+}
+
+// PortUsage is the number of edges to a destination port, along with the traffic they carried
+type PortUsage struct {
+	Port     int   `json:"port"`
+	Protocol int   `json:"protocol"`
+	Edges    int   `json:"edges"`
+	Bytes    int64 `json:"bytes"`
+	Packets  int64 `json:"packets"`
+}
+
+// Summary holds the metrics computed over a graph. Each ranking is limited to the top entries.
+type Summary struct {
+	Nodes               int          `json:"nodes"`
+	Edges               int          `json:"edges"`
+	Bytes               int64        `json:"bytes"`
+	Packets             int64        `json:"packets"`
+	TopTalkersByBytes   []Talker     `json:"topTalkersByBytes"`
+	TopTalkersByPackets []Talker     `json:"topTalkersByPackets"`
+	TopDegree           []Degree     `json:"topDegree"`
+	TopBetweenness      []Centrality `json:"topBetweenness"`
+	BetweennessSampled  bool         `json:"betweennessSampled"`
+	Components          int          `json:"components"`
+	ComponentSizes      []int        `json:"componentSizes"`
+	TopPorts            []PortUsage  `json:"topPorts"`
+}
+
+// Summarize computes the metrics of the graph, keeping the top limit entries of each ranking
+func Summarize(g *Graph, limit int) *Summary {
+	s := &Summary{
+		Nodes:             len(g.Nodes),
+		Edges:             len(g.Edges),
+		TopTalkersByBytes: []Talker{},
+		TopDegree:         []Degree{},
+		TopPorts:          []PortUsage{},
+	}
+
+	talkers := make(map[string]*Talker, len(g.Nodes))
+	inbound := make(map[string]map[string]bool, len(g.Nodes))
+	outbound := make(map[string]map[string]bool, len(g.Nodes))
+	for id := range g.Nodes {
+		talkers[id] = &Talker{Addr: g.Nodes[id].Addr}
+		inbound[id] = make(map[string]bool)
+		outbound[id] = make(map[string]bool)
+	}
+	type portKey struct{ port, protocol int }
+	ports := make(map[portKey]*PortUsage)
+	for _, e := range g.Edges {
+		s.Bytes += e.Bytes
+		s.Packets += e.Packets
+		for _, id := range []string{e.From, e.To} {
+			if t, ok := talkers[id]; ok {
+				t.Bytes += e.Bytes
+				t.Packets += e.Packets
+			}
+		}
+		if e.From != e.To {
+			if _, ok := outbound[e.From]; ok {
+				outbound[e.From][e.To] = true
+			}
+			if _, ok := inbound[e.To]; ok {
+				inbound[e.To][e.From] = true
+			}
+		}
+		// the digester records the ports of clients as 0, so the responses of services are not counted
+		if e.DstPort == 0 {
+			continue
+		}
+		k := portKey{e.DstPort, e.Protocol}
+		p, ok := ports[k]
+		if !ok {
+			p = &PortUsage{Port: e.DstPort, Protocol: e.Protocol}
+			ports[k] = p
+		}
+		p.Edges++
+		p.Bytes += e.Bytes
+		p.Packets += e.Packets
+	}
+
+	for _, t := range talkers {
+		s.TopTalkersByBytes = append(s.TopTalkersByBytes, *t)
+	}
+	sort.Slice(s.TopTalkersByBytes, func(i, j int) bool {
+		a, b := s.TopTalkersByBytes[i], s.TopTalkersByBytes[j]
+		return a.Bytes > b.Bytes || (a.Bytes == b.Bytes && a.Addr < b.Addr)
+	})
+	s.TopTalkersByPackets = append([]Talker{}, s.TopTalkersByBytes...)
+	sort.SliceStable(s.TopTalkersByPackets, func(i, j int) bool {
+		return s.TopTalkersByPackets[i].Packets > s.TopTalkersByPackets[j].Packets
+	})
+
+	for id, n := range g.Nodes {
+		d := Degree{Addr: n.Addr, In: len(inbound[id]), Out: len(outbound[id])}
+		d.Total = d.In + d.Out
+		s.TopDegree = append(s.TopDegree, d)
+	}
+	sort.Slice(s.TopDegree, func(i, j int) bool {
+		a, b := s.TopDegree[i], s.TopDegree[j]
+		return a.Total > b.Total || (a.Total == b.Total && a.Addr < b.Addr)
+	})
+
+	s.TopBetweenness, s.BetweennessSampled, s.ComponentSizes = centrality(g, outbound)
+	s.Components = len(s.ComponentSizes)
+
+	for _, p := range ports {
+		s.TopPorts = append(s.TopPorts, *p)
+	}
+	sort.Slice(s.TopPorts, func(i, j int) bool {
+		a, b := s.TopPorts[i], s.TopPorts[j]
+		if a.Edges != b.Edges {
+			return a.Edges > b.Edges
+		}
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return a.Port < b.Port || (a.Port == b.Port && a.Protocol < b.Protocol)
+	})
+
+	if limit > 0 {
+		s.TopTalkersByBytes = truncateTalkers(s.TopTalkersByBytes, limit)
+		s.TopTalkersByPackets = truncateTalkers(s.TopTalkersByPackets, limit)
+		if len(s.TopDegree) > limit {
+			s.TopDegree = s.TopDegree[:limit]
+		}
+		if len(s.TopBetweenness) > limit {
+			s.TopBetweenness = s.TopBetweenness[:limit]
+		}
+		if len(s.TopPorts) > limit {
+			s.TopPorts = s.TopPorts[:limit]
+		}
+	}
+	return s
+}
+
+// centrality returns the nodes ranked by betweenness centrality, and whether it was estimated from a sample
+// of betweennessPivots sources, along with the sizes of the weakly connected components of the graph,
+// largest first
+func centrality(g *Graph, outbound map[string]map[string]bool) ([]Centrality, bool, []int) {
+	nodes := sortedNodes(g)
+	if len(nodes) == 0 {
+		return []Centrality{}, false, []int{}
+	}
+	ids := make(map[string]int, len(nodes))
+	undirected := simple.NewUndirectedGraph()
+	for idx, n := range nodes {
+		ids[n.ID] = idx
+		undirected.AddNode(simple.Node(idx))
+	}
+	adjacency := make([][]int, len(nodes))
+	for from, targets := range outbound {
+		for to := range targets {
+			f, t := ids[from], ids[to]
+			adjacency[f] = append(adjacency[f], t)
+			undirected.SetEdge(simple.Edge{F: simple.Node(f), T: simple.Node(t)})
+		}
+	}
+	for _, targets := range adjacency {
+		sort.Ints(targets)
+	}
+
+	scores, sampled := betweenness(adjacency)
+	ranking := make([]Centrality, 0, len(nodes))
+	for idx, n := range nodes {
+		ranking = append(ranking, Centrality{Addr: n.Addr, Betweenness: scores[idx]})
+	}
+	sort.SliceStable(ranking, func(i, j int) bool { return ranking[i].Betweenness > ranking[j].Betweenness })
+
+	components := topo.ConnectedComponents(undirected)
+	sizes := make([]int, 0, len(components))
+	for _, c := range components {
+		sizes = append(sizes, len(c))
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+	return ranking, sampled, sizes
+}
+
+// betweenness computes the betweenness centrality of the nodes of a directed graph, given as adjacency
+// lists, with Brandes' algorithm. Each source costs a breadth first search, so graphs of more than
+// betweennessPivots nodes only explore the shortest paths from evenly spaced pivot sources, and their
+// scores are scaled up to estimate the centrality over all sources. It reports whether it sampled.
+func betweenness(adjacency [][]int) ([]float64, bool) {
+	n := len(adjacency)
+	scores := make([]float64, n)
+	stride := 1
+	if betweennessPivots > 0 && n > betweennessPivots {
+		stride = (n + betweennessPivots - 1) / betweennessPivots
+	}
+
+	distance := make([]int, n)
+	paths := make([]float64, n)
+	dependency := make([]float64, n)
+	predecessors := make([][]int, n)
+	order := make([]int, 0, n)
+	sources := 0
+	for source := 0; source < n; source += stride {
+		sources++
+		for v := range distance {
+			distance[v], paths[v], dependency[v] = -1, 0, 0
+			predecessors[v] = predecessors[v][:0]
+		}
+		distance[source], paths[source] = 0, 1
+		order = append(order[:0], source)
+		for head := 0; head < len(order); head++ {
+			v := order[head]
+			for _, w := range adjacency[v] {
+				if distance[w] < 0 {
+					distance[w] = distance[v] + 1
+					order = append(order, w)
+				}
+				if distance[w] == distance[v]+1 {
+					paths[w] += paths[v]
+					predecessors[w] = append(predecessors[w], v)
+				}
+			}
+		}
+		for idx := len(order) - 1; idx > 0; idx-- {
+			w := order[idx]
+			for _, v := range predecessors[w] {
+				dependency[v] += paths[v] / paths[w] * (1 + dependency[w])
+			}
+			scores[w] += dependency[w]
+		}
+	}
+	if stride == 1 {
+		return scores, false
+	}
+	scale := float64(n) / float64(sources)
+	for v := range scores {
+		scores[v] *= scale
+	}
+	return scores, true
+}
+
+func truncateTalkers(talkers []Talker, limit int) []Talker {
+	if len(talkers) > limit {
+		return talkers[:limit]
+	}
+	return talkers
+}
@@ -0,0 +1,117 @@
+package graph
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gonum.org/v1/gonum/graph/network"
+	"gonum.org/v1/gonum/graph/simple"
+)
+
+func TestSummarize(t *testing.T) {
+	g := New()
+	a := g.AddNode("10.0.0.1")
+	b := g.AddNode("10.0.0.2")
+	c := g.AddNode("10.0.0.3")
+	d := g.AddNode("10.0.0.4")
+	e := g.AddNode("10.0.0.5")
+	g.Edges = []*Edge{
+		{From: a.ID, To: b.ID, DstPort: 443, Protocol: 6, Bytes: 1000, Packets: 10},
+		{From: b.ID, To: c.ID, DstPort: 5432, Protocol: 6, Bytes: 500, Packets: 50},
+		{From: a.ID, To: b.ID, DstPort: 443, Protocol: 6, Bytes: 100, Packets: 1},
+		{From: d.ID, To: e.ID, DstPort: 53, Protocol: 17, Bytes: 10, Packets: 1},
+	}
+
+	s := Summarize(g, 2)
+	assert.Equal(t, 5, s.Nodes)
+	assert.Equal(t, 4, s.Edges)
+	assert.Equal(t, int64(1610), s.Bytes)
+	assert.Equal(t, int64(62), s.Packets)
+	assert.Equal(t, []Talker{{"10.0.0.2", 1600, 61}, {"10.0.0.1", 1100, 11}}, s.TopTalkersByBytes)
+	assert.Equal(t, []Talker{{"10.0.0.2", 1600, 61}, {"10.0.0.3", 500, 50}}, s.TopTalkersByPackets)
+	assert.Equal(t, []Degree{{"10.0.0.2", 1, 1, 2}, {"10.0.0.1", 0, 1, 1}}, s.TopDegree)
+	assert.Equal(t, "10.0.0.2", s.TopBetweenness[0].Addr)
+	assert.Equal(t, 1.0, s.TopBetweenness[0].Betweenness)
+	assert.Len(t, s.TopBetweenness, 2)
+	assert.Equal(t, 2, s.Components)
+	assert.Equal(t, []int{3, 2}, s.ComponentSizes)
+	assert.Equal(t, []PortUsage{{443, 6, 2, 1100, 11}, {5432, 6, 1, 500, 50}}, s.TopPorts)
+}
+
+func TestSummarizeEmpty(t *testing.T) {
+	s := Summarize(New(), 10)
+	assert.Equal(t, 0, s.Nodes)
+	assert.Equal(t, 0, s.Components)
+}
+
+func TestSummarizeIgnoresPortZero(t *testing.T) {
+	g := New()
+	server := g.AddNode("10.0.0.1")
+	g.Edges = []*Edge{
+		{From: g.AddNode("10.0.0.2").ID, To: server.ID, DstPort: 443, Protocol: 6, Bytes: 10},
+		{From: server.ID, To: g.AddNode("10.0.0.2").ID, SrcPort: 443, Protocol: 6, Bytes: 100},
+		{From: server.ID, To: g.AddNode("10.0.0.3").ID, SrcPort: 443, Protocol: 6, Bytes: 100},
+	}
+	s := Summarize(g, 10)
+	assert.Equal(t, []PortUsage{{443, 6, 1, 10, 0}}, s.TopPorts)
+	assert.Equal(t, int64(210), s.Bytes)
+}
+
+// testAdjacency returns the adjacency lists of a directed graph of n nodes with a few shortcuts, so that
+// some nodes are joined by several shortest paths
+func testAdjacency(n int) [][]int {
+	adjacency := make([][]int, n)
+	for v := 0; v < n; v++ {
+		adjacency[v] = append(adjacency[v], (v+1)%n)
+		if v%3 == 0 {
+			adjacency[v] = append(adjacency[v], (v+7)%n)
+		}
+		if v%5 == 0 {
+			adjacency[v] = append(adjacency[v], (v*v+2)%n)
+		}
+	}
+	return adjacency
+}
+
+func TestBetweennessMatchesGonum(t *testing.T) {
+	adjacency := testAdjacency(60)
+	directed := simple.NewDirectedGraph()
+	for v := range adjacency {
+		directed.AddNode(simple.Node(v))
+	}
+	for v, targets := range adjacency {
+		for _, w := range targets {
+			if v != w && !directed.HasEdgeFromTo(int64(v), int64(w)) {
+				directed.SetEdge(simple.Edge{F: simple.Node(v), T: simple.Node(w)})
+			}
+		}
+	}
+	expected := network.Betweenness(directed)
+
+	scores, sampled := betweenness(adjacency)
+	assert.False(t, sampled)
+	for v, score := range scores {
+		assert.InDelta(t, expected[int64(v)], score, 1e-9, fmt.Sprintf("node %d", v))
+	}
+}
+
+func TestBetweennessSampled(t *testing.T) {
+	defer func(pivots int) { betweennessPivots = pivots }(betweennessPivots)
+	betweennessPivots = 10
+
+	// along a cycle, every node lies on the same number of shortest paths, which sampling estimates exactly
+	adjacency := make([][]int, 100)
+	for v := range adjacency {
+		adjacency[v] = []int{(v + 1) % len(adjacency)}
+	}
+	exact := float64(99*98) / 2
+	scores, sampled := betweenness(adjacency)
+	require.True(t, sampled)
+	total := 0.0
+	for _, score := range scores {
+		total += score
+	}
+	assert.InDelta(t, exact*100, total, 1e-6)
+}
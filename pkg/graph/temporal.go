@@ -0,0 +1,335 @@
+package graph
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Activity is the traffic observed on an edge during a single interval of a temporal graph
+type Activity struct {
+	Start   time.Time `json:"start"`
+	Stop    time.Time `json:"stop"`
+	Bytes   int64     `json:"bytes"`
+	Packets int64     `json:"packets"`
+}
+
+// TemporalEdge is a connection of a temporal graph along with the intervals during which it was active.
+// Edges are matched across intervals by their nodes, ports and protocol.
+type TemporalEdge struct {
+	From     string     `json:"from"`
+	To       string     `json:"to"`
+	SrcPort  int        `json:"srcPort"`
+	DstPort  int        `json:"dstPort"`
+	Protocol int        `json:"protocol"`
+	Actions  []string   `json:"actions"`
+	Bytes    int64      `json:"bytes"`
+	Packets  int64      `json:"packets"`
+	Activity []Activity `json:"activity"`
+}
+
+// Temporal is a graph which keeps the time dimension of a window, by recording the traffic of each
+// edge per interval of the window rather than in aggregate
+type Temporal struct {
+	Start    time.Time
+	Stop     time.Time
+	Interval time.Duration
+	Nodes    map[string]*Node
+	Edges    []*TemporalEdge
+
+	edges map[edgeKey]*TemporalEdge
+}
+
+// NewTemporal creates an empty temporal graph covering start to stop in steps of interval
+func NewTemporal(start, stop time.Time, interval time.Duration) *Temporal {
+	return &Temporal{
+		Start:    start,
+		Stop:     stop,
+		Interval: interval,
+		Nodes:    make(map[string]*Node),
+		edges:    make(map[edgeKey]*TemporalEdge),
+	}
+}
+
+// Intervals splits the window of the temporal graph into its intervals. The last interval is cut
+// short when the window is not a multiple of the interval.
+func (t *Temporal) Intervals() [][2]time.Time {
+	var intervals [][2]time.Time
+	for start := t.Start; start.Before(t.Stop); start = start.Add(t.Interval) {
+		stop := start.Add(t.Interval)
+		if stop.After(t.Stop) {
+			stop = t.Stop
+		}
+		intervals = append(intervals, [2]time.Time{start, stop})
+	}
+	return intervals
+}
+
+// Add records the graph of the interval from start to stop. Intervals should be added in order.
+func (t *Temporal) Add(start, stop time.Time, g *Graph) {
+	for id, n := range g.Nodes {
+		if _, ok := t.Nodes[id]; !ok {
+			t.Nodes[id] = n
+		}
+	}
+	for _, e := range g.Edges {
+		k := keyOf(e)
+		te, ok := t.edges[k]
+		if !ok {
+			te = &TemporalEdge{From: e.From, To: e.To, SrcPort: e.SrcPort, DstPort: e.DstPort, Protocol: e.Protocol}
+			t.edges[k] = te
+			t.Edges = append(t.Edges, te)
+		}
+		if !containsString(te.Actions, e.Action) {
+			te.Actions = append(te.Actions, e.Action)
+		}
+		if n := len(te.Activity); n == 0 || !te.Activity[n-1].Start.Equal(start) {
+			te.Activity = append(te.Activity, Activity{Start: start, Stop: stop})
+		}
+		a := &te.Activity[len(te.Activity)-1]
+		a.Bytes += e.Bytes
+		a.Packets += e.Packets
+		te.Bytes += e.Bytes
+		te.Packets += e.Packets
+	}
+}
+
+// spells merges the contiguous activity of an edge into the periods during which it was active
+func spells(activity []Activity) [][2]time.Time {
+	var merged [][2]time.Time
+	for _, a := range activity {
+		if n := len(merged); n > 0 && merged[n-1][1].Equal(a.Start) {
+			merged[n-1][1] = a.Stop
+			continue
+		}
+		merged = append(merged, [2]time.Time{a.Start, a.Stop})
+	}
+	return merged
+}
+
+// nodeActivity returns the activity of each node, which is the union of the activity of its edges
+func nodeActivity(t *Temporal) map[string][]Activity {
+	active := make(map[string]map[time.Time]Activity)
+	for _, e := range t.Edges {
+		for _, id := range []string{e.From, e.To} {
+			if _, ok := active[id]; !ok {
+				active[id] = make(map[time.Time]Activity)
+			}
+			for _, a := range e.Activity {
+				active[id][a.Start] = Activity{Start: a.Start, Stop: a.Stop}
+			}
+		}
+	}
+	activity := make(map[string][]Activity, len(active))
+	for id, byStart := range active {
+		for _, a := range byStart {
+			activity[id] = append(activity[id], a)
+		}
+		sort.Slice(activity[id], func(i, j int) bool { return activity[id][i].Start.Before(activity[id][j].Start) })
+	}
+	return activity
+}
+
+func (t *Temporal) sortedNodes() []*Node {
+	nodes := make([]*Node, 0, len(t.Nodes))
+	for _, n := range t.Nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+type temporalJSON struct {
+	Start    time.Time       `json:"start"`
+	Stop     time.Time       `json:"stop"`
+	Interval int64           `json:"intervalSeconds"`
+	Nodes    []temporalNode  `json:"nodes"`
+	Edges    []*TemporalEdge `json:"edges"`
+}
+
+type temporalNode struct {
+	jsonNode
+	Activity []Activity `json:"activity"`
+}
+
+// EncodeTemporalJSON writes the temporal graph as a JSON document. Each edge lists the bytes and packets
+// it carried in every interval during which it was active, and each node lists the intervals during which
+// any of its edges were active.
+func EncodeTemporalJSON(w io.Writer, t *Temporal) error {
+	activity := nodeActivity(t)
+	doc := temporalJSON{
+		Start:    t.Start.UTC(),
+		Stop:     t.Stop.UTC(),
+		Interval: int64(t.Interval / time.Second),
+		Nodes:    make([]temporalNode, 0, len(t.Nodes)),
+		Edges:    append([]*TemporalEdge{}, t.Edges...),
+	}
+	for _, n := range t.sortedNodes() {
+		doc.Nodes = append(doc.Nodes, temporalNode{
+			jsonNode: jsonNode{ID: n.ID, Addr: n.Addr, Attributes: nonNil(n.Attrs)},
+			Activity: nonNilActivity(activity[n.ID]),
+		})
+	}
+	return json.NewEncoder(w).Encode(doc)
+}
+
+func nonNilActivity(a []Activity) []Activity {
+	if a == nil {
+		return []Activity{}
+	}
+	return a
+}
+
+type gexfDocument struct {
+	XMLName xml.Name  `xml:"gexf"`
+	XMLNS   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+type gexfGraph struct {
+	Mode               string           `xml:"mode,attr"`
+	DefaultEdgeType    string           `xml:"defaultedgetype,attr"`
+	TimeFormat         string           `xml:"timeformat,attr"`
+	TimeRepresentation string           `xml:"timerepresentation,attr"`
+	Start              string           `xml:"start,attr"`
+	End                string           `xml:"end,attr"`
+	Attributes         []gexfAttributes `xml:"attributes"`
+	Nodes              []gexfElement    `xml:"nodes>node"`
+	Edges              []gexfElement    `xml:"edges>edge"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Mode       string          `xml:"mode,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfElement struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	Source    string         `xml:"source,attr,omitempty"`
+	Target    string         `xml:"target,attr,omitempty"`
+	Weight    int64          `xml:"weight,attr,omitempty"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue,omitempty"`
+	Spells    []gexfSpell    `xml:"spells>spell"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+	Start string `xml:"start,attr,omitempty"`
+	End   string `xml:"end,attr,omitempty"`
+}
+
+type gexfSpell struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr"`
+}
+
+// EncodeGEXF writes the temporal graph as a dynamic GEXF 1.3 document. Nodes and edges carry spells
+// covering the periods during which they were active, and edges carry the bytes and packets of each
+// interval as dynamic attributes. Node annotations are written as static node attributes.
+func EncodeGEXF(w io.Writer, t *Temporal) error {
+	activity := nodeActivity(t)
+	nodeAttrs := make(map[string]bool)
+	for _, n := range t.Nodes {
+		for k := range n.Attrs {
+			nodeAttrs[k] = true
+		}
+	}
+	nodeAttrKeys := make([]string, 0, len(nodeAttrs))
+	for k := range nodeAttrs {
+		nodeAttrKeys = append(nodeAttrKeys, k)
+	}
+	sort.Strings(nodeAttrKeys)
+
+	g := gexfGraph{
+		Mode:               "dynamic",
+		DefaultEdgeType:    "directed",
+		TimeFormat:         "dateTime",
+		TimeRepresentation: "interval",
+		Start:              gexfTime(t.Start),
+		End:                gexfTime(t.Stop),
+		Attributes: []gexfAttributes{
+			{Class: "edge", Mode: "dynamic", Attributes: []gexfAttribute{
+				{ID: "bytes", Title: "bytes", Type: "long"},
+				{ID: "packets", Title: "packets", Type: "long"},
+			}},
+		},
+	}
+	staticAttrs := gexfAttributes{Class: "node", Mode: "static"}
+	for _, k := range nodeAttrKeys {
+		staticAttrs.Attributes = append(staticAttrs.Attributes, gexfAttribute{ID: k, Title: k, Type: "string"})
+	}
+	g.Attributes = append(g.Attributes, staticAttrs)
+
+	for _, n := range t.sortedNodes() {
+		node := gexfElement{ID: n.ID, Label: n.Addr, Spells: gexfSpells(activity[n.ID])}
+		for _, k := range nodeAttrKeys {
+			if v, ok := n.Attrs[k]; ok {
+				node.AttValues = append(node.AttValues, gexfAttValue{For: k, Value: v})
+			}
+		}
+		g.Nodes = append(g.Nodes, node)
+	}
+	for idx, e := range t.Edges {
+		edge := gexfElement{
+			ID:     strconv.Itoa(idx),
+			Label:  fmt.Sprintf("%d/%d", e.DstPort, e.Protocol),
+			Source: e.From,
+			Target: e.To,
+			Weight: e.Bytes,
+			Spells: gexfSpells(e.Activity),
+		}
+		for _, a := range e.Activity {
+			edge.AttValues = append(edge.AttValues,
+				gexfAttValue{For: "bytes", Value: strconv.FormatInt(a.Bytes, 10), Start: gexfTime(a.Start), End: gexfTime(a.Stop)},
+				gexfAttValue{For: "packets", Value: strconv.FormatInt(a.Packets, 10), Start: gexfTime(a.Start), End: gexfTime(a.Stop)},
+			)
+		}
+		g.Edges = append(g.Edges, edge)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(gexfDocument{XMLNS: "http://gexf.net/1.3", Version: "1.3", Graph: g}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func gexfSpells(activity []Activity) []gexfSpell {
+	var result []gexfSpell
+	for _, s := range spells(activity) {
+		result = append(result, gexfSpell{Start: gexfTime(s[0]), End: gexfTime(s[1])})
+	}
+	return result
+}
+
+func gexfTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
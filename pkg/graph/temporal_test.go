@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func temporalFixture() *Temporal {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	tg := NewTemporal(start, start.Add(13*time.Minute), 5*time.Minute)
+	intervals := tg.Intervals()
+	for idx, bytes := range []int64{100, 200, 0} {
+		g := New()
+		a := g.AddNode("10.0.0.1")
+		a.Attrs[AttrClass] = ClassPrivate
+		b := g.AddNode("10.0.0.2")
+		if bytes > 0 {
+			g.Edges = []*Edge{
+				{From: a.ID, To: b.ID, DstPort: 443, Protocol: 6, Bytes: bytes, Packets: 1, Action: ActionAccept},
+				{From: a.ID, To: b.ID, DstPort: 443, Protocol: 6, Bytes: bytes, Packets: 1, Action: ActionReject},
+			}
+		}
+		if idx == 2 {
+			c := g.AddNode("10.0.0.3")
+			g.Edges = []*Edge{{From: c.ID, To: b.ID, DstPort: 22, Protocol: 6, Bytes: 50, Packets: 2, Action: ActionAccept}}
+		}
+		tg.Add(intervals[idx][0], intervals[idx][1], g)
+	}
+	return tg
+}
+
+func TestTemporal(t *testing.T) {
+	tg := temporalFixture()
+	intervals := tg.Intervals()
+	require.Len(t, intervals, 3)
+	assert.Equal(t, tg.Stop, intervals[2][1])
+	assert.Equal(t, 3*time.Minute, intervals[2][1].Sub(intervals[2][0]))
+
+	require.Len(t, tg.Nodes, 3)
+	require.Len(t, tg.Edges, 2)
+	e := tg.Edges[0]
+	assert.Equal(t, []string{ActionAccept, ActionReject}, e.Actions)
+	assert.Equal(t, int64(600), e.Bytes)
+	require.Len(t, e.Activity, 2)
+	assert.Equal(t, Activity{Start: intervals[0][0], Stop: intervals[0][1], Bytes: 200, Packets: 2}, e.Activity[0])
+	assert.Equal(t, int64(400), e.Activity[1].Bytes)
+	assert.Equal(t, [][2]time.Time{{intervals[0][0], intervals[1][1]}}, spells(e.Activity))
+}
+
+func TestEncodeTemporalJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.Nil(t, EncodeTemporalJSON(&buf, temporalFixture()))
+	var doc struct {
+		Interval int64 `json:"intervalSeconds"`
+		Nodes    []struct {
+			ID       string     `json:"id"`
+			Activity []Activity `json:"activity"`
+		} `json:"nodes"`
+		Edges []TemporalEdge `json:"edges"`
+	}
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, int64(300), doc.Interval)
+	require.Len(t, doc.Nodes, 3)
+	assert.Len(t, doc.Nodes[0].Activity, 2)
+	assert.Len(t, doc.Nodes[1].Activity, 3)
+	assert.Len(t, doc.Nodes[2].Activity, 1)
+	require.Len(t, doc.Edges, 2)
+	assert.Equal(t, 22, doc.Edges[1].DstPort)
+}
+
+func TestEncodeGEXF(t *testing.T) {
+	var buf bytes.Buffer
+	require.Nil(t, EncodeGEXF(&buf, temporalFixture()))
+	assert.Contains(t, buf.String(), `<graph mode="dynamic" defaultedgetype="directed" timeformat="dateTime"`)
+	assert.Contains(t, buf.String(), `<spell start="2019-01-01T00:00:00Z" end="2019-01-01T00:10:00Z"></spell>`)
+	assert.Contains(t, buf.String(), `<attvalue for="bytes" value="400" start="2019-01-01T00:05:00Z" end="2019-01-01T00:10:00Z"></attvalue>`)
+	assert.Contains(t, buf.String(), `<attvalue for="class" value="private"></attvalue>`)
+
+	var doc gexfDocument
+	require.Nil(t, xml.Unmarshal(buf.Bytes(), &doc))
+	assert.Len(t, doc.Graph.Nodes, 3)
+	assert.Len(t, doc.Graph.Edges, 2)
+}
+
+func TestLookupTemporalFormat(t *testing.T) {
+	f, err := LookupTemporalFormat("")
+	assert.Nil(t, err)
+	assert.Equal(t, "application/json", f.ContentType)
+
+	f, err = LookupTemporalFormat(FormatGEXF)
+	assert.Nil(t, err)
+	assert.Equal(t, "application/xml", f.ContentType)
+
+	_, err = LookupTemporalFormat(FormatDOT)
+	assert.NotNil(t, err)
+}
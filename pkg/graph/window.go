@@ -0,0 +1,28 @@
+package graph
+
+import (
+	"errors"
+	"time"
+)
+
+// ValidateSteps checks that the start/stop range can be split into windows of step, which must be a whole
+// number of minutes, and a whole number of which must span the range
+func ValidateSteps(start, stop time.Time, step time.Duration) error {
+	if step < time.Minute || step%time.Minute != 0 {
+		return errors.New("step should be a whole number of minutes")
+	}
+	if !stop.After(start) || stop.Sub(start)%step != 0 {
+		return errors.New("the range should be a whole number of steps")
+	}
+	return nil
+}
+
+// Steps splits the start/stop range into consecutive windows of step, in chronological order. The range
+// should be validated with ValidateSteps first.
+func Steps(start, stop time.Time, step time.Duration) [][2]time.Time {
+	var windows [][2]time.Time
+	for s := start; s.Before(stop); s = s.Add(step) {
+		windows = append(windows, [2]time.Time{s, s.Add(step)})
+	}
+	return windows
+}
@@ -0,0 +1,39 @@
+package graph
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSteps(t *testing.T) {
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(3 * time.Hour)
+	require.Nil(t, ValidateSteps(start, stop, time.Hour))
+	windows := Steps(start, stop, time.Hour)
+	require.Len(t, windows, 3)
+	assert.Equal(t, [2]time.Time{start, start.Add(time.Hour)}, windows[0])
+	assert.Equal(t, stop, windows[2][1])
+}
+
+func TestValidateStepsInvalid(t *testing.T) {
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	tc := []struct {
+		Name string
+		Stop time.Time
+		Step time.Duration
+	}{
+		{Name: "zero_step", Stop: start.Add(time.Hour), Step: 0},
+		{Name: "partial_minute", Stop: start.Add(time.Hour), Step: 90 * time.Second},
+		{Name: "partial_step", Stop: start.Add(3 * time.Hour), Step: 2 * time.Hour},
+		{Name: "empty_range", Stop: start, Step: time.Hour},
+		{Name: "reversed_range", Stop: start.Add(-time.Hour), Step: time.Hour},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			assert.NotNil(t, ValidateSteps(start, tt.Stop, tt.Step))
+		})
+	}
+}
@@ -1,26 +1,162 @@
 package grapher
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"io"
+	"io/ioutil"
+	"time"
 
 	"github.com/asecurityteam/go-vpcflow"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/logs"
 	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
 )
 
+// Version identifies the version of the service which created a graph, and is recorded in the graph metadata.
+// It may be set at build time with -ldflags "-X github.com/asecurityteam/vpcflow-grapherd/pkg/grapher.Version=<version>".
+var Version = "1.0.0"
+
 // DOT is a grapher module which converts a VPC flow log digest into a DOT graph using the go-vpc library.
 // If successful, it stores the resulting graph in the backend implemented by the provided types.Storage
+//
+// If any Enrichers are provided, each node of the converted graph is annotated with the attributes
+// returned by the Enrichers before the graph is stored. Any Annotators are then run, in order, against
+// the enriched graph, which is then held in memory while its encoding is streamed to the Storage. The
+// Annotators which implement types.Committer are committed once the graph and its metadata are stored.
+//
+// The metadata of the graph is stored alongside it, and in the index of the graphs which lists them by
+// window, see graph.IndexKey.
+//
+// If a Notifier is provided, the findings of the Annotators are sent to it once the graph is stored, unless
+// the context is flagged with types.WithoutNotify. A failure to notify is logged, but does not fail the graph.
 type DOT struct {
-	Converter vpcflow.Converter
-	Storage   types.Storage
+	Converter   vpcflow.Converter
+	Storage     types.Storage
+	Enrichers   []types.Enricher
+	Annotators  []types.Annotator
+	Notifier    types.Notifier
+	LogProvider types.LogFn
 }
 
 // Graph graphs the given digest in DOT format, and stores the generated DOT contents identified by the supplied id
-func (g *DOT) Graph(ctx context.Context, id string, digest io.ReadCloser) error {
+func (g *DOT) Graph(ctx context.Context, id string, start, stop time.Time, digest io.ReadCloser) error {
 	r, err := g.Converter(digest)
 	if err != nil {
 		return err
 	}
 	defer r.Close()
-	return g.Storage.Store(ctx, id, r)
+	// the converted graph is streamed to the storage as is, unless it has to be enriched or annotated, in which
+	// case it is decoded in memory, and its encoding is streamed to the storage instead
+	var body io.Reader = r
+	var annotated *graph.Graph
+	var findings []types.Finding
+	if len(g.Enrichers) > 0 || len(g.Annotators) > 0 {
+		if annotated, findings, err = g.enrich(ctx, id, r); err != nil {
+			return err
+		}
+		pr, pw := io.Pipe()
+		defer pr.Close()
+		go func() {
+			pw.CloseWithError(graph.EncodeDOT(pw, annotated))
+		}()
+		body = pr
+	}
+	hash := sha256.New()
+	var size byteCounter
+	if err := g.Storage.Store(ctx, id, ioutil.NopCloser(io.TeeReader(body, io.MultiWriter(hash, &size)))); err != nil {
+		return err
+	}
+	metadata := graph.Metadata{
+		ID:      id,
+		Start:   start.UTC(),
+		Stop:    stop.UTC(),
+		Format:  graph.FormatDOT,
+		Size:    int(size),
+		Hash:    hex.EncodeToString(hash.Sum(nil)),
+		Created: time.Now().UTC(),
+		Version: Version,
+	}
+	if err := g.storeMetadata(ctx, metadata); err != nil {
+		return err
+	}
+	for _, a := range g.Annotators {
+		if c, ok := a.(types.Committer); ok {
+			if err := c.Commit(ctx, id, annotated); err != nil {
+				return err
+			}
+		}
+	}
+	if g.Notifier != nil && len(findings) > 0 && types.NotifyFromContext(ctx) {
+		if err := g.Notifier.Notify(ctx, findings); err != nil && g.LogProvider != nil {
+			g.LogProvider(ctx).Error(logs.DependencyFailure{Dependency: logs.DependencyNotifier, Reason: err.Error()})
+		}
+	}
+	return nil
+}
+
+// enrich decodes, enriches and annotates the converted graph, and returns it along with the findings of the
+// Annotators
+func (g *DOT) enrich(ctx context.Context, id string, r io.Reader) (*graph.Graph, []types.Finding, error) {
+	fg, err := graph.FromDOT(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := Enrich(ctx, fg, g.Enrichers); err != nil {
+		return nil, nil, err
+	}
+	var findings []types.Finding
+	for _, a := range g.Annotators {
+		found, err := a.Annotate(ctx, id, fg)
+		if err != nil {
+			return nil, nil, err
+		}
+		findings = append(findings, found...)
+	}
+	return fg, findings, nil
+}
+
+// storeMetadata stores the metadata of a graph alongside it, and as its index entry
+func (g *DOT) storeMetadata(ctx context.Context, metadata graph.Metadata) error {
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	for _, key := range []string{metadata.ID + graph.MetadataSuffix, graph.IndexKey(metadata.ID, metadata.Start, metadata.Stop)} {
+		if err := g.Storage.Store(ctx, key, ioutil.NopCloser(bytes.NewReader(b))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// byteCounter is a writer which counts the bytes written to it
+type byteCounter int64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// Enrich annotates each node of the graph with the attributes returned by the enrichers. Each node is
+// enriched using the time range covered by its incident edges. When multiple enrichers return the same
+// attribute, the value from the latter enricher is kept.
+func Enrich(ctx context.Context, g *graph.Graph, enrichers []types.Enricher) error {
+	bounds := g.Bounds()
+	for id, n := range g.Nodes {
+		b := bounds[id]
+		for _, e := range enrichers {
+			attrs, err := e.Enrich(ctx, n.Addr, b[0], b[1])
+			if err != nil {
+				return err
+			}
+			for k, v := range attrs {
+				n.Attrs[k] = v
+			}
+		}
+	}
+	return nil
 }
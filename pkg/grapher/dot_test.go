@@ -3,17 +3,35 @@ package grapher
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"io/ioutil"
 	"testing"
+	"time"
 
+	"github.com/asecurityteam/go-vpcflow"
+	"github.com/asecurityteam/logevent"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const key = "foo"
 
+var (
+	testStart = time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	testStop  = testStart.Add(time.Hour)
+)
+
+// expectMetadata expects the metadata of the test graph to be stored times times
+func expectMetadata(mockStorage *MockStorage, times int) {
+	mockStorage.EXPECT().Store(gomock.Any(), key+graph.MetadataSuffix, gomock.Any()).Return(nil).Times(times)
+	mockStorage.EXPECT().Store(gomock.Any(), graph.IndexKey(key, testStart, testStop), gomock.Any()).Return(nil).Times(times)
+}
+
 func TestFailedConvert(t *testing.T) {
 	input := []byte(`input data`)
 	d := DOT{
@@ -21,7 +39,7 @@ func TestFailedConvert(t *testing.T) {
 			return nil, errors.New("")
 		},
 	}
-	err := d.Graph(context.Background(), key, ioutil.NopCloser(bytes.NewReader(input)))
+	err := d.Graph(context.Background(), key, testStart, testStop, ioutil.NopCloser(bytes.NewReader(input)))
 	assert.NotNil(t, err)
 }
 
@@ -38,7 +56,7 @@ func TestFailedStore(t *testing.T) {
 			return ioutil.NopCloser(bytes.NewReader([]byte("converted graph"))), nil
 		},
 	}
-	err := d.Graph(context.Background(), key, ioutil.NopCloser(bytes.NewReader(input)))
+	err := d.Graph(context.Background(), key, testStart, testStop, ioutil.NopCloser(bytes.NewReader(input)))
 	assert.NotNil(t, err)
 }
 
@@ -48,6 +66,7 @@ func TestHappyPath(t *testing.T) {
 
 	mockStorage := NewMockStorage(ctrl)
 	mockStorage.EXPECT().Store(gomock.Any(), key, gomock.Any()).Return(nil)
+	expectMetadata(mockStorage, 1)
 	input := []byte(`input data`)
 	d := DOT{
 		Storage: mockStorage,
@@ -55,6 +74,240 @@ func TestHappyPath(t *testing.T) {
 			return ioutil.NopCloser(bytes.NewReader([]byte("converted graph"))), nil
 		},
 	}
-	err := d.Graph(context.Background(), key, ioutil.NopCloser(bytes.NewReader(input)))
+	err := d.Graph(context.Background(), key, testStart, testStop, ioutil.NopCloser(bytes.NewReader(input)))
 	assert.Nil(t, err)
 }
+
+func TestEnrichedHappyPath(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockEnricher := NewMockEnricher(ctrl)
+	mockEnricher.EXPECT().Enrich(gomock.Any(), "172.31.16.139", time.Unix(1418530010, 0), time.Unix(1818530070, 0)).Return(map[string]string{"service": "payments"}, nil)
+	mockEnricher.EXPECT().Enrich(gomock.Any(), "172.31.16.21", time.Unix(1418530010, 0), time.Unix(1818530070, 0)).Return(nil, nil)
+
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Store(gomock.Any(), key, gomock.Any()).DoAndReturn(func(_ context.Context, _ string, r io.ReadCloser) error {
+		data, _ := ioutil.ReadAll(r)
+		assert.Contains(t, string(data), `n1723116139 [label="172.31.16.139\nservice=payments" grapherd_service="payments"]`)
+		assert.Contains(t, string(data), `n172311621 [label="172.31.16.21"]`)
+		return nil
+	})
+	expectMetadata(mockStorage, 1)
+	input := []byte("2 123456789010 eni-abc123de 172.31.16.139 172.31.16.21 0 80 6 20 1000 1418530010 1818530070 ACCEPT OK\n")
+	d := DOT{
+		Storage:   mockStorage,
+		Converter: vpcflow.DOTConverter,
+		Enrichers: []types.Enricher{mockEnricher},
+	}
+	err := d.Graph(context.Background(), key, testStart, testStop, ioutil.NopCloser(bytes.NewReader(input)))
+	assert.Nil(t, err)
+}
+
+func TestEnrichError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockEnricher := NewMockEnricher(ctrl)
+	mockEnricher.EXPECT().Enrich(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("oops"))
+	input := []byte("2 123456789010 eni-abc123de 172.31.16.139 172.31.16.21 0 80 6 20 1000 1418530010 1818530070 ACCEPT OK\n")
+	d := DOT{
+		Converter: vpcflow.DOTConverter,
+		Enrichers: []types.Enricher{mockEnricher},
+	}
+	err := d.Graph(context.Background(), key, testStart, testStop, ioutil.NopCloser(bytes.NewReader(input)))
+	assert.NotNil(t, err)
+}
+
+func TestAnnotatedHappyPath(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAnnotator := NewMockAnnotator(ctrl)
+	mockAnnotator.EXPECT().Annotate(gomock.Any(), key, gomock.Any()).DoAndReturn(func(_ context.Context, _ string, g *graph.Graph) ([]types.Finding, error) {
+		g.Edges[0].Flag(graph.AttrAnomaly, "new-edge")
+		return []types.Finding{{Kind: "new-edge"}}, nil
+	})
+
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Store(gomock.Any(), key, gomock.Any()).DoAndReturn(func(_ context.Context, _ string, r io.ReadCloser) error {
+		data, _ := ioutil.ReadAll(r)
+		assert.Contains(t, string(data), `grapherd_anomaly="new-edge"`)
+		return nil
+	})
+	expectMetadata(mockStorage, 1)
+	input := []byte("2 123456789010 eni-abc123de 172.31.16.139 172.31.16.21 0 80 6 20 1000 1418530010 1818530070 ACCEPT OK\n")
+	d := DOT{
+		Storage:    mockStorage,
+		Converter:  vpcflow.DOTConverter,
+		Annotators: []types.Annotator{mockAnnotator},
+	}
+	err := d.Graph(context.Background(), key, testStart, testStop, ioutil.NopCloser(bytes.NewReader(input)))
+	assert.Nil(t, err)
+}
+
+func TestAnnotatedNotify(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	findings := []types.Finding{{Kind: "new-edge"}}
+	mockAnnotator := NewMockAnnotator(ctrl)
+	mockAnnotator.EXPECT().Annotate(gomock.Any(), key, gomock.Any()).Return(findings, nil).Times(3)
+	emptyAnnotator := NewMockAnnotator(ctrl)
+	emptyAnnotator.EXPECT().Annotate(gomock.Any(), key, gomock.Any()).Return(nil, nil).Times(3)
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Store(gomock.Any(), key, gomock.Any()).Return(nil).Times(3)
+	expectMetadata(mockStorage, 3)
+	mockNotifier := NewMockNotifier(ctrl)
+	mockNotifier.EXPECT().Notify(gomock.Any(), findings).Return(nil)
+	mockNotifier.EXPECT().Notify(gomock.Any(), findings).Return(errors.New("oops"))
+
+	input := []byte("2 123456789010 eni-abc123de 172.31.16.139 172.31.16.21 0 80 6 20 1000 1418530010 1818530070 ACCEPT OK\n")
+	d := DOT{
+		Storage:     mockStorage,
+		Converter:   vpcflow.DOTConverter,
+		Annotators:  []types.Annotator{mockAnnotator, emptyAnnotator},
+		Notifier:    mockNotifier,
+		LogProvider: logevent.FromContext,
+	}
+	ctx := logevent.NewContext(context.Background(), logevent.New(logevent.Config{Output: ioutil.Discard}))
+	assert.Nil(t, d.Graph(ctx, key, testStart, testStop, ioutil.NopCloser(bytes.NewReader(input))))
+	// notification failures do not fail the graph
+	assert.Nil(t, d.Graph(ctx, key, testStart, testStop, ioutil.NopCloser(bytes.NewReader(input))))
+	// findings are not notified when the context says so
+	assert.Nil(t, d.Graph(types.WithoutNotify(ctx), key, testStart, testStop, ioutil.NopCloser(bytes.NewReader(input))))
+}
+
+func TestAnnotateError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAnnotator := NewMockAnnotator(ctrl)
+	mockAnnotator.EXPECT().Annotate(gomock.Any(), key, gomock.Any()).Return(nil, errors.New("oops"))
+	input := []byte("2 123456789010 eni-abc123de 172.31.16.139 172.31.16.21 0 80 6 20 1000 1418530010 1818530070 ACCEPT OK\n")
+	d := DOT{
+		Converter:  vpcflow.DOTConverter,
+		Annotators: []types.Annotator{mockAnnotator},
+	}
+	err := d.Graph(context.Background(), key, testStart, testStop, ioutil.NopCloser(bytes.NewReader(input)))
+	assert.NotNil(t, err)
+}
+
+// committingAnnotator is an Annotator which implements types.Committer
+type committingAnnotator struct {
+	*MockAnnotator
+	*MockCommitter
+}
+
+func TestAnnotatedCommit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAnnotator := NewMockAnnotator(ctrl)
+	mockAnnotator.EXPECT().Annotate(gomock.Any(), key, gomock.Any()).Return(nil, nil).Times(3)
+	mockCommitter := NewMockCommitter(ctrl)
+	mockStorage := NewMockStorage(ctrl)
+	input := "2 123456789010 eni-abc123de 172.31.16.139 172.31.16.21 0 80 6 20 1000 1418530010 1818530070 ACCEPT OK\n"
+	d := DOT{
+		Storage:    mockStorage,
+		Converter:  vpcflow.DOTConverter,
+		Annotators: []types.Annotator{committingAnnotator{mockAnnotator, mockCommitter}},
+	}
+
+	// the annotator is committed once the graph and its metadata are stored
+	gomock.InOrder(
+		mockStorage.EXPECT().Store(gomock.Any(), key, gomock.Any()).Return(nil),
+		mockStorage.EXPECT().Store(gomock.Any(), key+graph.MetadataSuffix, gomock.Any()).Return(nil),
+		mockStorage.EXPECT().Store(gomock.Any(), graph.IndexKey(key, testStart, testStop), gomock.Any()).Return(nil),
+		mockCommitter.EXPECT().Commit(gomock.Any(), key, gomock.Any()).Return(nil),
+	)
+	assert.Nil(t, d.Graph(context.Background(), key, testStart, testStop, ioutil.NopCloser(bytes.NewReader([]byte(input)))))
+
+	// a graph which failed to be stored is not committed
+	mockStorage.EXPECT().Store(gomock.Any(), key, gomock.Any()).Return(errors.New("oops"))
+	assert.NotNil(t, d.Graph(context.Background(), key, testStart, testStop, ioutil.NopCloser(bytes.NewReader([]byte(input)))))
+
+	mockStorage.EXPECT().Store(gomock.Any(), key, gomock.Any()).Return(nil)
+	expectMetadata(mockStorage, 1)
+	mockCommitter.EXPECT().Commit(gomock.Any(), key, gomock.Any()).Return(errors.New("oops"))
+	assert.NotNil(t, d.Graph(context.Background(), key, testStart, testStop, ioutil.NopCloser(bytes.NewReader([]byte(input)))))
+}
+
+func TestAnnotatedStoreFailsWhileReading(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAnnotator := NewMockAnnotator(ctrl)
+	mockAnnotator.EXPECT().Annotate(gomock.Any(), key, gomock.Any()).Return(nil, nil)
+	mockStorage := NewMockStorage(ctrl)
+	// the storage stops reading the streamed graph half way
+	mockStorage.EXPECT().Store(gomock.Any(), key, gomock.Any()).DoAndReturn(func(_ context.Context, _ string, r io.ReadCloser) error {
+		_, _ = r.Read(make([]byte, 1))
+		return errors.New("oops")
+	})
+	input := []byte("2 123456789010 eni-abc123de 172.31.16.139 172.31.16.21 0 80 6 20 1000 1418530010 1818530070 ACCEPT OK\n")
+	d := DOT{
+		Storage:    mockStorage,
+		Converter:  vpcflow.DOTConverter,
+		Annotators: []types.Annotator{mockAnnotator},
+	}
+	assert.NotNil(t, d.Graph(context.Background(), key, testStart, testStop, ioutil.NopCloser(bytes.NewReader(input))))
+}
+
+func TestStoredMetadata(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var stored [][]byte
+	mockStorage := NewMockStorage(ctrl)
+	// the graph is streamed to the storage, and measured as it is read
+	mockStorage.EXPECT().Store(gomock.Any(), key, gomock.Any()).DoAndReturn(func(_ context.Context, _ string, r io.ReadCloser) error {
+		assert.Empty(t, stored)
+		data, _ := ioutil.ReadAll(r)
+		assert.Equal(t, "converted graph", string(data))
+		return nil
+	})
+	for _, metadataKey := range []string{key + graph.MetadataSuffix, graph.IndexKey(key, testStart, testStop)} {
+		mockStorage.EXPECT().Store(gomock.Any(), metadataKey, gomock.Any()).DoAndReturn(func(_ context.Context, _ string, r io.ReadCloser) error {
+			data, _ := ioutil.ReadAll(r)
+			stored = append(stored, data)
+			return nil
+		})
+	}
+	d := DOT{
+		Storage: mockStorage,
+		Converter: func(_ io.ReadCloser) (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader([]byte("converted graph"))), nil
+		},
+	}
+	assert.Nil(t, d.Graph(context.Background(), key, testStart.In(time.FixedZone("PDT", -7*3600)), testStop, ioutil.NopCloser(bytes.NewReader(nil))))
+
+	require.Len(t, stored, 2)
+	assert.Equal(t, stored[0], stored[1])
+	var metadata graph.Metadata
+	require.Nil(t, json.Unmarshal(stored[0], &metadata))
+	assert.Equal(t, key, metadata.ID)
+	assert.Equal(t, testStart, metadata.Start)
+	assert.Equal(t, testStop, metadata.Stop)
+	assert.Equal(t, graph.FormatDOT, metadata.Format)
+	assert.Equal(t, len("converted graph"), metadata.Size)
+	assert.Equal(t, "35809d6a38e3c26d1c0f817bec35d8efb5f53be9337652e6eff882bb3087e6b2", metadata.Hash)
+	assert.Equal(t, Version, metadata.Version)
+	assert.False(t, metadata.Created.IsZero())
+}
+
+func TestFailedStoreMetadata(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Store(gomock.Any(), key, gomock.Any()).Return(nil)
+	mockStorage.EXPECT().Store(gomock.Any(), key+graph.MetadataSuffix, gomock.Any()).Return(errors.New("oops"))
+	d := DOT{
+		Storage: mockStorage,
+		Converter: func(_ io.ReadCloser) (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader([]byte("converted graph"))), nil
+		},
+	}
+	assert.NotNil(t, d.Graph(context.Background(), key, testStart, testStop, ioutil.NopCloser(bytes.NewReader(nil))))
+}
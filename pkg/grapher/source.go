@@ -0,0 +1,54 @@
+package grapher
+
+import (
+	"context"
+	"time"
+
+	"github.com/asecurityteam/go-vpcflow"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+// Source is a GraphSource which loads stored graphs, falling back to digesting and converting the
+// window on demand when the graph has not been stored or is still being created. Graphs built on
+// demand are enriched in the same way as stored graphs, but are not stored.
+type Source struct {
+	Storage   types.Storage
+	Digester  types.Digester
+	Converter vpcflow.Converter
+	Enrichers []types.Enricher
+}
+
+// Load returns the graph identified by id, covering start to stop
+func (s *Source) Load(ctx context.Context, id string, start, stop time.Time) (*graph.Graph, error) {
+	stored, err := s.Storage.Get(ctx, id)
+	switch err.(type) {
+	case nil:
+		defer stored.Close()
+		return graph.FromDOT(stored)
+	case types.ErrNotFound, types.ErrInProgress:
+		if start.IsZero() && stop.IsZero() {
+			return nil, types.ErrNotFound{ID: id}
+		}
+	default:
+		return nil, err
+	}
+	digest, err := s.Digester.Digest(ctx, start, stop)
+	if err != nil {
+		return nil, err
+	}
+	defer digest.Close()
+	r, err := s.Converter(digest)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	g, err := graph.FromDOT(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := Enrich(ctx, g, s.Enrichers); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
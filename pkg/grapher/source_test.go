@@ -0,0 +1,88 @@
+package grapher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/go-vpcflow"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sourceDigest = "2 123456789010 eni-abc123de 172.31.16.139 172.31.16.21 0 80 6 20 1000 1418530010 1818530070 ACCEPT OK\n"
+
+func TestSourceStored(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Get(gomock.Any(), key).Return(ioutil.NopCloser(bytes.NewReader([]byte(`digraph { n10001 -> n10002 }`))), nil)
+
+	s := &Source{Storage: mockStorage}
+	g, err := s.Load(context.Background(), key, time.Now(), time.Now())
+	require.Nil(t, err)
+	assert.Len(t, g.Edges, 1)
+}
+
+func TestSourceDigest(t *testing.T) {
+	for _, storageErr := range []error{types.ErrNotFound{}, types.ErrInProgress{}} {
+		ctrl := gomock.NewController(t)
+
+		start, stop := time.Now().Add(-time.Hour), time.Now()
+		mockStorage := NewMockStorage(ctrl)
+		mockStorage.EXPECT().Get(gomock.Any(), key).Return(nil, storageErr)
+		mockDigester := NewMockDigester(ctrl)
+		mockDigester.EXPECT().Digest(gomock.Any(), start, stop).Return(ioutil.NopCloser(bytes.NewReader([]byte(sourceDigest))), nil)
+		mockEnricher := NewMockEnricher(ctrl)
+		mockEnricher.EXPECT().Enrich(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(map[string]string{"service": "payments"}, nil).Times(2)
+
+		s := &Source{
+			Storage:   mockStorage,
+			Digester:  mockDigester,
+			Converter: vpcflow.DOTConverter,
+			Enrichers: []types.Enricher{mockEnricher},
+		}
+		g, err := s.Load(context.Background(), key, start, stop)
+		require.Nil(t, err)
+		assert.Len(t, g.Edges, 1)
+		assert.Equal(t, "payments", g.Nodes["n1723116139"].Attrs["service"])
+		ctrl.Finish()
+	}
+}
+
+func TestSourceNotFoundWithoutWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Get(gomock.Any(), key).Return(nil, types.ErrNotFound{ID: key})
+
+	s := &Source{Storage: mockStorage}
+	_, err := s.Load(context.Background(), key, time.Time{}, time.Time{})
+	_, ok := err.(types.ErrNotFound)
+	assert.True(t, ok)
+}
+
+func TestSourceErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Get(gomock.Any(), key).Return(nil, errors.New("oops"))
+	s := &Source{Storage: mockStorage}
+	_, err := s.Load(context.Background(), key, time.Now(), time.Now())
+	assert.NotNil(t, err)
+
+	mockStorage.EXPECT().Get(gomock.Any(), key).Return(nil, types.ErrNotFound{ID: key})
+	mockDigester := NewMockDigester(ctrl)
+	mockDigester.EXPECT().Digest(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("oops"))
+	s = &Source{Storage: mockStorage, Digester: mockDigester}
+	_, err = s.Load(context.Background(), key, time.Now(), time.Now())
+	assert.NotNil(t, err)
+}
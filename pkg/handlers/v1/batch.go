@@ -0,0 +1,160 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/logs"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/parallel"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+const (
+	// maxBatchWindows is the maximum number of windows of a single batch, enough for a month of hourly graphs
+	maxBatchWindows = 744
+	// batchConcurrency is the number of windows of a batch which are queued at the same time
+	batchConcurrency = 8
+
+	batchQueued     = "queued"
+	batchExists     = "exists"
+	batchInProgress = "in_progress"
+	batchFailed     = "failed"
+)
+
+type batchWindow struct {
+	Start string `json:"start"`
+	Stop  string `json:"stop"`
+}
+
+// batchPayload lists the windows of a batch, either explicitly or as a range split into steps
+type batchPayload struct {
+	Windows []batchWindow `json:"windows"`
+	Start   string        `json:"start"`
+	Stop    string        `json:"stop"`
+	Step    string        `json:"step"`
+}
+
+type batchResult struct {
+	ID      string    `json:"id"`
+	Start   time.Time `json:"start"`
+	Stop    time.Time `json:"stop"`
+	Status  string    `json:"status"`
+	Message string    `json:"message,omitempty"`
+}
+
+type batchResponse struct {
+	Results []batchResult `json:"results"`
+}
+
+// Batch creates the graphs of multiple windows. The windows are either listed, or described by a start/stop
+// range and a step. Every window is validated before any graph is queued, batchConcurrency windows at a time.
+// The graphs which already exist or are in progress are skipped, and the status of each window is returned in
+// the order of the windows.
+func (h *GrapherHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	logger := h.LogProvider(r.Context())
+	var body batchPayload
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	windows, err := body.windows()
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res := batchResponse{Results: h.queueGraphs(r.Context(), windows)}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// queueGraphs queues the graphs of the windows, batchConcurrency at a time, and returns their results in order
+func (h *GrapherHandler) queueGraphs(ctx context.Context, windows [][2]time.Time) []batchResult {
+	logger := h.LogProvider(ctx)
+	results := make([]batchResult, len(windows))
+	// the windows are queued independently, so a failed window does not abandon the others
+	_ = parallel.ForEach(ctx, len(windows), batchConcurrency, func(ctx context.Context, idx int) error {
+		start, stop := windows[idx][0], windows[idx][1]
+		result := batchResult{ID: graph.ID(start, stop), Start: start, Stop: stop}
+		var err error
+		result.Status, err = queueGraph(ctx, logger, h.Storage, h.Queuer, h.Marker, result.ID, start, stop)
+		if err != nil {
+			logger.Error(logs.DependencyFailure{Dependency: logs.DependencyQueuer, Reason: err.Error()})
+			result.Message = err.Error()
+		}
+		results[idx] = result
+		return nil
+	})
+	return results
+}
+
+// queueGraph queues the graph of a window, unless it already exists or is in progress, and returns its batch status
+func queueGraph(ctx context.Context, logger types.Logger, storage types.Storage, queuer types.Queuer, marker types.Marker, id string, start, stop time.Time) (string, error) {
+	exists, err := storage.Exists(ctx, id)
+	switch err.(type) {
+	case nil:
+	case types.ErrInProgress:
+		return batchInProgress, nil
+	default:
+		return batchFailed, err
+	}
+	if exists {
+		return batchExists, nil
+	}
+	if err := queuer.Queue(ctx, id, start, stop); err != nil {
+		return batchFailed, err
+	}
+	// as with single graphs, a marker failure only means that the graph will not report being in progress
+	if err := marker.Mark(ctx, id); err != nil {
+		logger.Error(logs.DependencyFailure{Dependency: logs.DependencyMarker, Reason: err.Error()})
+	}
+	return batchQueued, nil
+}
+
+// windows validates and returns the windows of the batch
+func (p batchPayload) windows() ([][2]time.Time, error) {
+	var windows [][2]time.Time
+	if len(p.Windows) > 0 {
+		if p.Start != "" || p.Stop != "" || p.Step != "" {
+			return nil, errors.New("windows cannot be combined with start, stop and step")
+		}
+		if len(p.Windows) > maxBatchWindows {
+			return nil, fmt.Errorf("at most %d windows are allowed", maxBatchWindows)
+		}
+		for idx, window := range p.Windows {
+			start, stop, err := parseWindow(window.Start, window.Stop)
+			if err == nil && !stop.After(start) {
+				err = errors.New("start should be before stop")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid window %d: %s", idx+1, err.Error())
+			}
+			windows = append(windows, [2]time.Time{start, stop})
+		}
+		return windows, nil
+	}
+
+	start, stop, err := parseWindow(p.Start, p.Stop)
+	if err != nil {
+		return nil, err
+	}
+	step, err := time.ParseDuration(p.Step)
+	if err != nil {
+		return nil, err
+	}
+	if err := graph.ValidateSteps(start, stop, step); err != nil {
+		return nil, err
+	}
+	if stop.Sub(start)/step > maxBatchWindows {
+		return nil, fmt.Errorf("at most %d windows are allowed", maxBatchWindows)
+	}
+	return graph.Steps(start, stop, step), nil
+}
@@ -0,0 +1,166 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/logevent"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/rs/xstats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBatchRequest(body string) *http.Request {
+	r, _ := http.NewRequest(http.MethodPost, "/batch", bytes.NewReader([]byte(body)))
+	return r.WithContext(logevent.NewContext(context.Background(), logevent.New(logevent.Config{Output: ioutil.Discard})))
+}
+
+func TestBatchBadRequest(t *testing.T) {
+	tc := []struct {
+		Name string
+		Body string
+	}{
+		{Name: "invalid_json", Body: `{`},
+		{Name: "empty", Body: `{}`},
+		{Name: "invalid_window", Body: `{"windows": [{"start": "2019-05-01T00:00:00Z", "stop": "2019-05-02T00:00:00Z"}, {"start": "invalid ts", "stop": "2019-05-02T00:00:00Z"}]}`},
+		{Name: "empty_window", Body: `{"windows": [{"start": "2019-05-01T00:00:00Z", "stop": "2019-05-01T00:00:00Z"}]}`},
+		{Name: "windows_and_range", Body: `{"windows": [{"start": "2019-05-01T00:00:00Z", "stop": "2019-05-02T00:00:00Z"}], "step": "24h"}`},
+		{Name: "missing_step", Body: `{"start": "2019-05-01T00:00:00Z", "stop": "2019-05-31T00:00:00Z"}`},
+		{Name: "short_step", Body: `{"start": "2019-05-01T00:00:00Z", "stop": "2019-05-31T00:00:00Z", "step": "30s"}`},
+		{Name: "partial_step", Body: `{"start": "2019-05-01T00:00:00Z", "stop": "2019-05-31T12:00:00Z", "step": "24h"}`},
+		{Name: "too_many_windows", Body: `{"start": "2019-05-01T00:00:00Z", "stop": "2019-07-01T00:00:00Z", "step": "1h"}`},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h := &GrapherHandler{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext}
+			h.Batch(w, newBatchRequest(tt.Body))
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestBatchRange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	day := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	ids := make([]string, 5)
+	for idx := range ids {
+		ids[idx] = graph.ID(day.Add(time.Duration(idx)*24*time.Hour), day.Add(time.Duration(idx+1)*24*time.Hour))
+	}
+	storageMock := NewMockStorage(ctrl)
+	storageMock.EXPECT().Exists(gomock.Any(), ids[0]).Return(true, nil)
+	storageMock.EXPECT().Exists(gomock.Any(), ids[1]).Return(false, types.ErrInProgress{Key: ids[1]})
+	storageMock.EXPECT().Exists(gomock.Any(), ids[2]).Return(false, errors.New("oops"))
+	storageMock.EXPECT().Exists(gomock.Any(), ids[3]).Return(false, nil)
+	storageMock.EXPECT().Exists(gomock.Any(), ids[4]).Return(false, nil)
+	queuerMock := NewMockQueuer(ctrl)
+	queuerMock.EXPECT().Queue(gomock.Any(), ids[3], day.Add(72*time.Hour), day.Add(96*time.Hour)).Return(nil)
+	queuerMock.EXPECT().Queue(gomock.Any(), ids[4], day.Add(96*time.Hour), day.Add(120*time.Hour)).Return(errors.New("oops"))
+	markerMock := NewMockMarker(ctrl)
+	markerMock.EXPECT().Mark(gomock.Any(), ids[3]).Return(errors.New("oops"))
+
+	w := httptest.NewRecorder()
+	h := &GrapherHandler{
+		LogProvider:  logevent.FromContext,
+		StatProvider: xstats.FromContext,
+		Storage:      storageMock,
+		Queuer:       queuerMock,
+		Marker:       markerMock,
+	}
+	h.Batch(w, newBatchRequest(`{"start": "2019-05-01T00:00:00Z", "stop": "2019-05-06T00:00:00Z", "step": "24h"}`))
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var res batchResponse
+	require.Nil(t, json.NewDecoder(w.Body).Decode(&res))
+	require.Len(t, res.Results, 5)
+	for idx, status := range []string{batchExists, batchInProgress, batchFailed, batchQueued, batchFailed} {
+		assert.Equal(t, ids[idx], res.Results[idx].ID)
+		assert.Equal(t, status, res.Results[idx].Status)
+	}
+	assert.Equal(t, "oops", res.Results[2].Message)
+	assert.Empty(t, res.Results[3].Message)
+}
+
+func TestBatchWindows(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start, _ := time.Parse(time.RFC3339Nano, "2019-05-01T00:00:30Z")
+	storageMock := NewMockStorage(ctrl)
+	storageMock.EXPECT().Exists(gomock.Any(), gomock.Any()).Return(false, nil).Times(2)
+	queuerMock := NewMockQueuer(ctrl)
+	queuerMock.EXPECT().Queue(gomock.Any(), graph.ID(start.Truncate(time.Minute), start.Add(time.Hour).Truncate(time.Minute)), gomock.Any(), gomock.Any()).Return(nil)
+	queuerMock.EXPECT().Queue(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	markerMock := NewMockMarker(ctrl)
+	markerMock.EXPECT().Mark(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+
+	w := httptest.NewRecorder()
+	h := &GrapherHandler{
+		LogProvider:  logevent.FromContext,
+		StatProvider: xstats.FromContext,
+		Storage:      storageMock,
+		Queuer:       queuerMock,
+		Marker:       markerMock,
+	}
+	h.Batch(w, newBatchRequest(`{"windows": [
+		{"start": "2019-05-01T00:00:30Z", "stop": "2019-05-01T01:00:30Z"},
+		{"start": "2019-05-02T00:00:00Z", "stop": "2019-05-03T00:00:00Z"}
+	]}`))
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var res batchResponse
+	require.Nil(t, json.NewDecoder(w.Body).Decode(&res))
+	require.Len(t, res.Results, 2)
+	assert.Equal(t, batchQueued, res.Results[0].Status)
+	assert.Equal(t, batchQueued, res.Results[1].Status)
+}
+
+func TestBatchConcurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var lock sync.Mutex
+	var inFlight, maxInFlight int
+	storageMock := NewMockStorage(ctrl)
+	storageMock.EXPECT().Exists(gomock.Any(), gomock.Any()).DoAndReturn(func(context.Context, string) (bool, error) {
+		lock.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		lock.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+		return true, nil
+	}).Times(3 * batchConcurrency)
+
+	w := httptest.NewRecorder()
+	h := &GrapherHandler{
+		LogProvider:  logevent.FromContext,
+		StatProvider: xstats.FromContext,
+		Storage:      storageMock,
+	}
+	h.Batch(w, newBatchRequest(`{"start": "2019-05-01T00:00:00Z", "stop": "2019-05-02T00:00:00Z", "step": "1h"}`))
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var res batchResponse
+	require.Nil(t, json.NewDecoder(w.Body).Decode(&res))
+	require.Len(t, res.Results, 3*batchConcurrency)
+	for idx, result := range res.Results {
+		assert.Equal(t, time.Date(2019, 5, 1, idx, 0, 0, 0, time.UTC), result.Start)
+		assert.Equal(t, batchExists, result.Status)
+	}
+	assert.Equal(t, batchConcurrency, maxInFlight)
+}
@@ -0,0 +1,98 @@
+package v1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/logs"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage"
+)
+
+// DefaultCacheMaxAge is the default amount of time for which clients may cache a completed graph
+const DefaultCacheMaxAge = 24 * time.Hour
+
+// validators are the HTTP cache validators of a graph
+type validators struct {
+	etag         string
+	lastModified time.Time
+}
+
+// graphValidators returns the validators of a graph, derived from its metadata. The graphs created before
+// their metadata was stored have none, and a graph filtered by country has an ETag of its own.
+func graphValidators(metadata *graph.Metadata, countries string) validators {
+	if metadata == nil {
+		return validators{}
+	}
+	v := validators{lastModified: metadata.Created.UTC().Truncate(time.Second)}
+	if metadata.Hash != "" {
+		tag := metadata.Hash
+		if countries != "" {
+			filter := sha256.Sum256([]byte(countries))
+			tag += "-" + hex.EncodeToString(filter[:8])
+		}
+		// the ETag is weak, since the graph is returned compressed or not depending on the client
+		v.etag = `W/"` + tag + `"`
+	}
+	return v
+}
+
+// loadValidators loads the validators of a graph from its metadata, which costs a request to the Storage.
+// The validators are optional, so the graph is still returned without them if its metadata cannot be loaded.
+func (h *GrapherHandler) loadValidators(ctx context.Context, id string, countries string) validators {
+	metadata, err := storage.LoadMetadata(ctx, h.Storage, id)
+	if err != nil {
+		h.LogProvider(ctx).Error(logs.DependencyFailure{Dependency: logs.DependencyStorage, Reason: err.Error()})
+	}
+	return graphValidators(metadata, countries)
+}
+
+// conditional returns whether the request has conditional headers, which are evaluated by notModified
+func conditional(r *http.Request) bool {
+	return r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != ""
+}
+
+// notModified evaluates the conditional headers of the request against the validators. As described in
+// RFC 7232, If-None-Match is evaluated with the weak comparison, and takes precedence over If-Modified-Since.
+func (v validators) notModified(r *http.Request) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if v.etag == "" {
+			return false
+		}
+		for _, tag := range strings.Split(ifNoneMatch, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(v.etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" && !v.lastModified.IsZero() {
+		t, err := http.ParseTime(ifModifiedSince)
+		return err == nil && !v.lastModified.After(t)
+	}
+	return false
+}
+
+// writeCacheHeaders sets the validators of a graph, and allows the client to cache it for maxAge. Completed
+// graphs do not change until they are regenerated, so they are cached for long. The responses which are
+// derived from a graph, such as filtered graphs, are passed a zero maxAge and are revalidated every time.
+// Graphs describe the network of the account, so they are not stored in shared caches.
+func writeCacheHeaders(w http.ResponseWriter, v validators, maxAge time.Duration) {
+	if v.etag != "" {
+		w.Header().Set("ETag", v.etag)
+	}
+	if !v.lastModified.IsZero() {
+		w.Header().Set("Last-Modified", v.lastModified.Format(http.TimeFormat))
+	}
+	if maxAge <= 0 {
+		w.Header().Set("Cache-Control", "private, no-cache")
+		return
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d, must-revalidate", int64(maxAge/time.Second)))
+}
@@ -0,0 +1,219 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/logevent"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/rs/xstats"
+	"github.com/stretchr/testify/assert"
+)
+
+const testHash = "35809d6a38e3c26d1c0f817bec35d8efb5f53be9337652e6eff882bb3087e6b2"
+
+var testCreated = time.Date(2019, 5, 1, 1, 5, 30, 0, time.UTC)
+
+// metadataKey matches the keys of the metadata stored alongside graphs
+type metadataKey struct{}
+
+func (metadataKey) Matches(x interface{}) bool {
+	key, ok := x.(string)
+	return ok && strings.HasSuffix(key, graph.MetadataSuffix)
+}
+
+func (metadataKey) String() string {
+	return "is a metadata key"
+}
+
+// expectMetadata expects the metadata of the graph to be loaded, and returns the given metadata, or
+// types.ErrNotFound if nil. It must be called before any other expectation of Get on the storage.
+func expectMetadata(storageMock *MockStorage, metadata *graph.Metadata) {
+	if metadata == nil {
+		storageMock.EXPECT().Get(gomock.Any(), metadataKey{}).Return(nil, types.ErrNotFound{})
+		return
+	}
+	b, _ := json.Marshal(metadata)
+	storageMock.EXPECT().Get(gomock.Any(), metadataKey{}).Return(ioutil.NopCloser(bytes.NewReader(b)), nil)
+}
+
+func newCacheHandler(storageMock *MockStorage) *GrapherHandler {
+	return &GrapherHandler{
+		LogProvider:  logevent.FromContext,
+		StatProvider: xstats.FromContext,
+		Storage:      storageMock,
+		CacheMaxAge:  7 * 24 * time.Hour,
+	}
+}
+
+func TestGetCacheHeaders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storageMock := NewMockStorage(ctrl)
+	expectMetadata(storageMock, &graph.Metadata{Hash: testHash, Created: testCreated})
+	storageMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(bytes.NewReader([]byte("digraph {}"))), nil)
+
+	w := httptest.NewRecorder()
+	newCacheHandler(storageMock).Get(w, newGraphRequest(""))
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, `W/"`+testHash+`"`, w.Result().Header.Get("ETag"))
+	assert.Equal(t, "Wed, 01 May 2019 01:05:30 GMT", w.Result().Header.Get("Last-Modified"))
+	assert.Equal(t, "private, max-age=604800, must-revalidate", w.Result().Header.Get("Cache-Control"))
+}
+
+func TestGetCacheHeadersWithoutMetadata(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the graph is returned without validators when its metadata is missing or cannot be loaded
+	storageMock := NewMockStorage(ctrl)
+	storageMock.EXPECT().Get(gomock.Any(), metadataKey{}).Return(nil, errors.New("oops"))
+	storageMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(bytes.NewReader([]byte("digraph {}"))), nil)
+
+	w := httptest.NewRecorder()
+	h := newCacheHandler(storageMock)
+	h.CacheMaxAge = 0
+	h.Get(w, newGraphRequest(""))
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Empty(t, w.Result().Header.Get("ETag"))
+	assert.Empty(t, w.Result().Header.Get("Last-Modified"))
+	assert.Equal(t, "private, max-age=86400, must-revalidate", w.Result().Header.Get("Cache-Control"))
+}
+
+func TestGetNotModified(t *testing.T) {
+	tc := []struct {
+		Name   string
+		Header string
+		Value  string
+	}{
+		{Name: "etag", Header: "If-None-Match", Value: `W/"` + testHash + `"`},
+		{Name: "strong etag", Header: "If-None-Match", Value: `"other", "` + testHash + `"`},
+		{Name: "any etag", Header: "If-None-Match", Value: "*"},
+		{Name: "modified since", Header: "If-Modified-Since", Value: testCreated.Format(http.TimeFormat)},
+		{Name: "modified since later", Header: "If-Modified-Since", Value: testCreated.Add(time.Hour).Format(http.TimeFormat)},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// the graph is not loaded when the client has it already
+			storageMock := NewMockStorage(ctrl)
+			expectMetadata(storageMock, &graph.Metadata{Hash: testHash, Created: testCreated.Add(300 * time.Millisecond)})
+
+			r := newGraphRequest("")
+			r.Header.Set(tt.Header, tt.Value)
+			w := httptest.NewRecorder()
+			newCacheHandler(storageMock).Get(w, r)
+			assert.Equal(t, http.StatusNotModified, w.Result().StatusCode)
+			assert.Equal(t, `W/"`+testHash+`"`, w.Result().Header.Get("ETag"))
+			body, _ := ioutil.ReadAll(w.Result().Body)
+			assert.Empty(t, body)
+		})
+	}
+}
+
+func TestGetModified(t *testing.T) {
+	tc := []struct {
+		Name    string
+		Headers map[string]string
+	}{
+		{Name: "etag", Headers: map[string]string{"If-None-Match": `W/"other"`}},
+		{Name: "modified since", Headers: map[string]string{"If-Modified-Since": testCreated.Add(-time.Second).Format(http.TimeFormat)}},
+		{Name: "invalid date", Headers: map[string]string{"If-Modified-Since": "yesterday"}},
+		// If-None-Match takes precedence
+		{Name: "etag and modified since", Headers: map[string]string{
+			"If-None-Match":     `W/"other"`,
+			"If-Modified-Since": testCreated.Format(http.TimeFormat),
+		}},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			storageMock := NewMockStorage(ctrl)
+			expectMetadata(storageMock, &graph.Metadata{Hash: testHash, Created: testCreated})
+			storageMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(bytes.NewReader([]byte("digraph {}"))), nil)
+
+			r := newGraphRequest("")
+			for k, v := range tt.Headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			newCacheHandler(storageMock).Get(w, r)
+			assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestGetFilteredETag(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the ETag of the unfiltered graph does not match the filtered graph
+	storageMock := NewMockStorage(ctrl)
+	expectMetadata(storageMock, &graph.Metadata{Hash: testHash, Created: testCreated})
+	storageMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(bytes.NewReader([]byte("digraph {}"))), nil)
+
+	r := newAnalysisRequest("/", map[string]string{
+		"start":   time.Now().Format(time.RFC3339Nano),
+		"stop":    time.Now().Format(time.RFC3339Nano),
+		"country": "DE",
+	})
+	r.Header.Set("If-None-Match", `W/"`+testHash+`"`)
+	w := httptest.NewRecorder()
+	newCacheHandler(storageMock).Get(w, r)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.True(t, strings.HasPrefix(w.Result().Header.Get("ETag"), `W/"`+testHash+`-`))
+	// the filtered graph is revalidated every time
+	assert.Equal(t, "private, no-cache", w.Result().Header.Get("Cache-Control"))
+}
+
+func TestHead(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storageMock := NewMockStorage(ctrl)
+	expectMetadata(storageMock, &graph.Metadata{Hash: testHash, Created: testCreated})
+	storageMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(bytes.NewReader([]byte("not a graph"))), nil)
+
+	// the graph is not decoded, so the filter does not fail on an invalid graph
+	r := newAnalysisRequest("/", map[string]string{
+		"start":   time.Now().Format(time.RFC3339Nano),
+		"stop":    time.Now().Format(time.RFC3339Nano),
+		"country": "DE",
+	})
+	r.Method = http.MethodHead
+	w := httptest.NewRecorder()
+	newCacheHandler(storageMock).Get(w, r)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.NotEmpty(t, w.Result().Header.Get("ETag"))
+	body, _ := ioutil.ReadAll(w.Result().Body)
+	assert.Empty(t, body)
+}
+
+func TestHeadInProgress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storageMock := NewMockStorage(ctrl)
+	storageMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, types.ErrInProgress{})
+
+	r := newGraphRequest("")
+	r.Method = http.MethodHead
+	w := httptest.NewRecorder()
+	newCacheHandler(storageMock).Get(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+	assert.Equal(t, "no-store", w.Result().Header.Get("Cache-Control"))
+}
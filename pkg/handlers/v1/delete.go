@@ -0,0 +1,47 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/logs"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage"
+	"github.com/go-chi/chi"
+)
+
+// Delete removes the graph of the start/stop window, along with its sidecar outputs and progress marker
+func (h *GrapherHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	logger := h.LogProvider(r.Context())
+	start, stop, err := extractInput(r)
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.deleteGraph(w, r, graph.ID(start, stop))
+}
+
+// DeleteByID removes the graph identified by the id URL parameter, along with its sidecar outputs and
+// progress marker. The id must be a graph ID, so that no other object of the storage can be deleted.
+func (h *GrapherHandler) DeleteByID(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		h.LogProvider(r.Context()).Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.deleteGraph(w, r, id)
+}
+
+// deleteGraph removes a graph and responds with 204 No Content. Deleting a graph which does not exist succeeds,
+// so that a failed deletion can be retried.
+func (h *GrapherHandler) deleteGraph(w http.ResponseWriter, r *http.Request, id string) {
+	logger := h.LogProvider(r.Context())
+	if err := storage.DeleteGraph(r.Context(), h.Storage, id, h.Sidecars); err != nil {
+		logger.Error(logs.DependencyFailure{Dependency: logs.DependencyStorage, Reason: err.Error()})
+		writeJSONResponse(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	logger.Info(logs.DeletedGraph{ID: id})
+	w.WriteHeader(http.StatusNoContent)
+}
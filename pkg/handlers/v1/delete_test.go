@@ -0,0 +1,128 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/logevent"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/rs/xstats"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDeleteByIDRequest(id string) *http.Request {
+	r, _ := http.NewRequest(http.MethodDelete, "/graphs/"+id, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	ctx := logevent.NewContext(context.Background(), logevent.New(logevent.Config{Output: ioutil.Discard}))
+	return r.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+}
+
+func TestDeleteBadRequest(t *testing.T) {
+	h := &GrapherHandler{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext}
+
+	w := httptest.NewRecorder()
+	h.Delete(w, newAnalysisRequest("/", map[string]string{"start": "invalid ts"}))
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+
+	w = httptest.NewRecorder()
+	h.DeleteByID(w, newDeleteByIDRequest(""))
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestDeleteByWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(time.Hour)
+	id := graph.ID(start, stop)
+	metadata, _ := json.Marshal(graph.Metadata{ID: id, Start: start, Stop: stop})
+	storage := NewMockStorage(ctrl)
+	gomock.InOrder(
+		storage.EXPECT().Get(gomock.Any(), id+graph.MetadataSuffix).Return(ioutil.NopCloser(bytes.NewReader(metadata)), nil),
+		storage.EXPECT().Delete(gomock.Any(), id+".stats.json").Return(nil),
+		storage.EXPECT().Delete(gomock.Any(), id+RegenerationSuffix).Return(nil),
+		storage.EXPECT().Delete(gomock.Any(), id).Return(nil),
+		storage.EXPECT().Delete(gomock.Any(), graph.IndexKey(id, start, stop)).Return(nil),
+		storage.EXPECT().Delete(gomock.Any(), id+graph.MetadataSuffix).Return(nil),
+	)
+
+	w := httptest.NewRecorder()
+	h := &GrapherHandler{
+		LogProvider:  logevent.FromContext,
+		StatProvider: xstats.FromContext,
+		Storage:      storage,
+		Sidecars:     []string{".stats.json", RegenerationSuffix},
+	}
+	h.Delete(w, newAnalysisRequest("/", map[string]string{"start": start.Format(time.RFC3339Nano), "stop": stop.Format(time.RFC3339Nano)}))
+	assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+}
+
+func TestDeleteByID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// graphs created before their metadata was stored have no index entry
+	storage := NewMockStorage(ctrl)
+	storage.EXPECT().Get(gomock.Any(), testID+graph.MetadataSuffix).Return(nil, types.ErrNotFound{ID: testID})
+	storage.EXPECT().Delete(gomock.Any(), testID+".stats.json").Return(nil)
+	storage.EXPECT().Delete(gomock.Any(), testID).Return(nil)
+
+	w := httptest.NewRecorder()
+	h := &GrapherHandler{
+		LogProvider:  logevent.FromContext,
+		StatProvider: xstats.FromContext,
+		Storage:      storage,
+		Sidecars:     []string{".stats.json"},
+	}
+	h.DeleteByID(w, newDeleteByIDRequest(testID))
+	assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+}
+
+func TestDeleteByIDBadRequest(t *testing.T) {
+	for _, id := range []string{"", "abc", "baseline/123456789012.json", testID + ".stats.json"} {
+		t.Run(id, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			w := httptest.NewRecorder()
+			h := &GrapherHandler{
+				LogProvider:  logevent.FromContext,
+				StatProvider: xstats.FromContext,
+				Storage:      NewMockStorage(ctrl),
+			}
+			h.DeleteByID(w, newDeleteByIDRequest(id))
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestDeleteStorageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := NewMockStorage(ctrl)
+	storage.EXPECT().Get(gomock.Any(), testID+graph.MetadataSuffix).Return(nil, types.ErrNotFound{ID: testID})
+	storage.EXPECT().Delete(gomock.Any(), testID+".stats.json").Return(errors.New("oops"))
+
+	w := httptest.NewRecorder()
+	h := &GrapherHandler{
+		LogProvider:  logevent.FromContext,
+		StatProvider: xstats.FromContext,
+		Storage:      storage,
+		Sidecars:     []string{".stats.json"},
+	}
+	h.DeleteByID(w, newDeleteByIDRequest(testID))
+	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+}
@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/logs"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+// Diff is a handler which compares the graphs of two windows of time
+type Diff struct {
+	LogProvider  types.LogFn
+	StatProvider types.StatFn
+	Source       types.GraphSource
+}
+
+// ServeHTTP handles incoming HTTP requests, and returns a graph highlighting the edges which were added,
+// removed or changed since a base graph. The graphs are identified either by the start/stop and
+// baseStart/baseStop windows, or by the id and base graph IDs.
+func (h *Diff) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := h.LogProvider(r.Context())
+	format, err := graph.LookupFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var id, baseID string
+	var start, stop, baseStart, baseStop time.Time
+	if r.URL.Query().Get("id") != "" || r.URL.Query().Get("base") != "" {
+		id, baseID = r.URL.Query().Get("id"), r.URL.Query().Get("base")
+		if id == "" || baseID == "" {
+			err = errors.New("both id and base are required")
+		}
+		if err == nil {
+			id, err = parseID(id)
+		}
+		if err == nil {
+			baseID, err = parseID(baseID)
+		}
+	} else {
+		start, stop, err = extractInput(r)
+		if err == nil {
+			baseStart, baseStop, err = extractWindow(r, "baseStart", "baseStop")
+		}
+		id, baseID = graph.ID(start, stop), graph.ID(baseStart, baseStop)
+	}
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	base, err := h.Source.Load(r.Context(), baseID, baseStart, baseStop)
+	if err != nil {
+		writeSourceError(w, logger, err)
+		return
+	}
+	current, err := h.Source.Load(r.Context(), id, start, stop)
+	if err != nil {
+		writeSourceError(w, logger, err)
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType)
+	w.WriteHeader(http.StatusOK)
+	_ = format.Encoder(w, graph.Diff(base, current))
+}
+
+// writeSourceError writes the http response for an error returned by a GraphSource
+func writeSourceError(w http.ResponseWriter, logger types.Logger, err error) {
+	switch err.(type) {
+	case types.ErrNotFound:
+		logger.Info(logs.NotFound{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusNotFound, err.Error())
+	default:
+		logger.Error(logs.DependencyFailure{Dependency: logs.DependencySource, Reason: err.Error()})
+		writeJSONResponse(w, http.StatusInternalServerError, "Internal Server Error")
+	}
+}
@@ -0,0 +1,168 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/logevent"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/rs/xstats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAnalysisRequest(path string, params map[string]string) *http.Request {
+	r, _ := http.NewRequest(http.MethodGet, path, nil)
+	q := url.Values{}
+	for k, v := range params {
+		q.Set(k, v)
+	}
+	r.URL.RawQuery = q.Encode()
+	return r.WithContext(logevent.NewContext(context.Background(), logevent.New(logevent.Config{Output: ioutil.Discard})))
+}
+
+func newTestGraph(dstPorts ...int) *graph.Graph {
+	g := graph.New()
+	a := g.AddNode("10.0.0.1")
+	b := g.AddNode("10.0.0.2")
+	for _, port := range dstPorts {
+		g.Edges = append(g.Edges, &graph.Edge{From: a.ID, To: b.ID, DstPort: port, Protocol: 6, Bytes: 100, Action: graph.ActionAccept})
+	}
+	return g
+}
+
+func TestDiffBadRequest(t *testing.T) {
+	now := time.Now()
+	tc := []struct {
+		Name   string
+		Params map[string]string
+	}{
+		{
+			Name:   "bad_format",
+			Params: map[string]string{"id": testID, "base": testBaseID, "format": "svg"},
+		},
+		{
+			Name:   "missing_base_id",
+			Params: map[string]string{"id": testID},
+		},
+		{
+			Name:   "bad_id",
+			Params: map[string]string{"id": "baseline/123456789012.json", "base": testBaseID},
+		},
+		{
+			Name:   "bad_base_id",
+			Params: map[string]string{"id": testID, "base": "../baseline"},
+		},
+		{
+			Name:   "missing_base_window",
+			Params: map[string]string{"start": now.Format(time.RFC3339Nano), "stop": now.Format(time.RFC3339Nano)},
+		},
+		{
+			Name:   "bad_window",
+			Params: map[string]string{"start": "invalid ts", "stop": now.Format(time.RFC3339Nano)},
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h := &Diff{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext}
+			h.ServeHTTP(w, newAnalysisRequest("/diff", tt.Params))
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestDiffByWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	baseStart := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	start := baseStart.Add(24 * time.Hour)
+	params := map[string]string{
+		"start":     start.Format(time.RFC3339Nano),
+		"stop":      start.Add(time.Hour).Format(time.RFC3339Nano),
+		"baseStart": baseStart.Format(time.RFC3339Nano),
+		"baseStop":  baseStart.Add(time.Hour).Format(time.RFC3339Nano),
+		"format":    "json",
+	}
+	mockSource := NewMockGraphSource(ctrl)
+	mockSource.EXPECT().Load(gomock.Any(), graph.ID(baseStart, baseStart.Add(time.Hour)), &timeMatcher{baseStart}, gomock.Any()).Return(newTestGraph(443), nil)
+	mockSource.EXPECT().Load(gomock.Any(), graph.ID(start, start.Add(time.Hour)), &timeMatcher{start}, gomock.Any()).Return(newTestGraph(443, 22), nil)
+
+	w := httptest.NewRecorder()
+	h := &Diff{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Source: mockSource}
+	h.ServeHTTP(w, newAnalysisRequest("/diff", params))
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, "application/json", w.Result().Header.Get("Content-Type"))
+
+	var doc struct {
+		Edges []struct {
+			DstPort    int               `json:"dstPort"`
+			Attributes map[string]string `json:"attributes"`
+		} `json:"edges"`
+	}
+	require.Nil(t, json.NewDecoder(w.Result().Body).Decode(&doc))
+	require.Len(t, doc.Edges, 2)
+	assert.Equal(t, "", doc.Edges[0].Attributes["diff"])
+	assert.Equal(t, 22, doc.Edges[1].DstPort)
+	assert.Equal(t, "added", doc.Edges[1].Attributes["diff"])
+}
+
+func TestDiffByID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSource := NewMockGraphSource(ctrl)
+	mockSource.EXPECT().Load(gomock.Any(), testBaseID, time.Time{}, time.Time{}).Return(newTestGraph(443), nil)
+	mockSource.EXPECT().Load(gomock.Any(), testID, time.Time{}, time.Time{}).Return(newTestGraph(), nil)
+
+	w := httptest.NewRecorder()
+	h := &Diff{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Source: mockSource}
+	h.ServeHTTP(w, newAnalysisRequest("/diff", map[string]string{"id": testID, "base": testBaseID}))
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	body, _ := ioutil.ReadAll(w.Result().Body)
+	assert.Contains(t, string(body), `grapherd_diff="removed" color=gray style=dashed`)
+}
+
+func TestDiffSourceErrors(t *testing.T) {
+	tc := []struct {
+		Name               string
+		Error              error
+		ExpectedStatusCode int
+	}{
+		{
+			Name:               "not_found",
+			Error:              types.ErrNotFound{},
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "unknown",
+			Error:              errors.New("oops"),
+			ExpectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockSource := NewMockGraphSource(ctrl)
+			mockSource.EXPECT().Load(gomock.Any(), testBaseID, gomock.Any(), gomock.Any()).Return(newTestGraph(), nil)
+			mockSource.EXPECT().Load(gomock.Any(), testID, gomock.Any(), gomock.Any()).Return(nil, tt.Error)
+
+			w := httptest.NewRecorder()
+			h := &Diff{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Source: mockSource}
+			h.ServeHTTP(w, newAnalysisRequest("/diff", map[string]string{"id": testID, "base": testBaseID}))
+			assert.Equal(t, tt.ExpectedStatusCode, w.Result().StatusCode)
+		})
+	}
+}
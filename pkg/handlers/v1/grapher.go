@@ -1,30 +1,49 @@
 package v1
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
 	"github.com/asecurityteam/vpcflow-grapherd/pkg/logs"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage"
 	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
 	"github.com/google/uuid"
 )
 
-var graphNamespace = uuid.NewSHA1(uuid.Nil, []byte("graph"))
-
-// GrapherHandler handles incoming HTTP requests for creating and retrieving new graphs
+// GrapherHandler handles incoming HTTP requests for creating, retrieving and deleting graphs
 type GrapherHandler struct {
 	LogProvider  types.LogFn
 	StatProvider types.StatFn
 	Storage      types.Storage
 	Marker       types.Marker
 	Queuer       types.Queuer
+	// Sidecars are the suffixes of the outputs stored alongside each graph, which are deleted with the graph,
+	// in addition to its metadata
+	Sidecars []string
+	// RedirectExpiration enables redirecting the downloads of graphs to presigned URLs, which expire after
+	// this duration. Graphs are streamed through the service if it is zero, or if the Storage is not a
+	// types.Presigner.
+	RedirectExpiration time.Duration
+	// RedirectMinSize is the stored size, in bytes, from which the downloads of graphs are redirected
+	RedirectMinSize int64
+	// CacheMaxAge is the amount of time for which clients may cache a completed graph. Defaults to
+	// DefaultCacheMaxAge.
+	CacheMaxAge time.Duration
+	// Lock serializes the updates of the regeneration records. Defaults to a storage.Lock of Storage.
+	Lock *storage.Lock
 }
 
-// Post creates a new graph
+// Post creates a new graph. If the force query parameter is true, an existing graph is regenerated rather than
+// rejected with a conflict. The previous version of the graph remains available until the new one is stored.
 func (h *GrapherHandler) Post(w http.ResponseWriter, r *http.Request) {
 	logger := h.LogProvider(r.Context())
 	start, stop, err := extractInput(r)
@@ -33,7 +52,13 @@ func (h *GrapherHandler) Post(w http.ResponseWriter, r *http.Request) {
 		writeJSONResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	id := computeID(start, stop)
+	force, err := extractForce(r)
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id := graph.ID(start, stop)
 	exists, err := h.Storage.Exists(r.Context(), id)
 	switch err.(type) {
 	case nil:
@@ -46,31 +71,67 @@ func (h *GrapherHandler) Post(w http.ResponseWriter, r *http.Request) {
 		writeJSONResponse(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
-	// if data is returned, a graph already exists. return 409 and exit
-	if exists {
+	// if data is returned, a graph already exists. return 409 and exit, unless the graph is regenerated
+	if exists && !force {
 		startStr := start.Format(time.RFC3339)
-		stopStr := start.Format(time.RFC3339)
+		stopStr := stop.Format(time.RFC3339)
 		msg := fmt.Sprintf("graph for the time %s to %s already exists", startStr, stopStr)
 		logger.Info(logs.Conflict{Reason: msg})
 		writeJSONResponse(w, http.StatusConflict, msg)
 		return
 	}
 
-	if err = h.Queuer.Queue(r.Context(), id, start, stop); err != nil {
+	ctx := r.Context()
+	if exists {
+		ctx = types.WithForce(ctx)
+	}
+	if err = h.Queuer.Queue(ctx, id, start, stop); err != nil {
 		logger.Error(logs.DependencyFailure{Dependency: logs.DependencyQueuer, Reason: err.Error()})
 		writeJSONResponse(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 
-	err = h.Marker.Mark(r.Context(), id)
+	err = h.Marker.Mark(ctx, id)
 	if err != nil {
 		logger.Error(logs.DependencyFailure{Dependency: logs.DependencyMarker, Reason: err.Error()})
 	}
 
+	// the regeneration count is informational, so failing to record it does not fail the request
+	if exists {
+		if err := recordRegeneration(ctx, h.lock(), h.Storage, id, time.Now()); err != nil {
+			logger.Error(logs.DependencyFailure{Dependency: logs.DependencyStorage, Reason: err.Error()})
+		}
+	}
+
 	w.WriteHeader(http.StatusAccepted)
 }
 
-// Get retrieves a graph
+func (h *GrapherHandler) cacheMaxAge() time.Duration {
+	if h.CacheMaxAge <= 0 {
+		return DefaultCacheMaxAge
+	}
+	return h.CacheMaxAge
+}
+
+func (h *GrapherHandler) lock() *storage.Lock {
+	if h.Lock == nil {
+		return &storage.Lock{Storage: h.Storage}
+	}
+	return h.Lock
+}
+
+// Get retrieves a graph. If the country query parameter is provided, only the edges connected to a
+// node located in one of the comma separated countries are returned. Otherwise, a graph stored compressed
+// with a content encoding accepted by the client is returned as stored, with a Content-Encoding header, and
+// the download of a large graph may be redirected to a presigned URL of the Storage.
+//
+// Graphs are returned with an ETag, derived from the hash of their content, and a Last-Modified header set to
+// their creation time, which answer conditional requests with 304 Not Modified. Completed graphs may be cached
+// by the client for CacheMaxAge, while filtered graphs are revalidated every time, and graphs in progress are
+// not cached. Get also serves HEAD requests.
+// The validators are read from the metadata of the graph, which costs a request to the Storage in addition
+// to the graph itself. It is only made for conditional requests and the graphs which are returned, so that
+// the redirects of unconditional downloads cost a single presigning.
 func (h *GrapherHandler) Get(w http.ResponseWriter, r *http.Request) {
 	logger := h.LogProvider(r.Context())
 	start, stop, err := extractInput(r)
@@ -79,16 +140,42 @@ func (h *GrapherHandler) Get(w http.ResponseWriter, r *http.Request) {
 		writeJSONResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	id := computeID(start, stop)
-	body, err := h.Storage.Get(r.Context(), id)
+	id := graph.ID(start, stop)
+	countries := r.URL.Query().Get("country")
+	maxAge := h.cacheMaxAge()
+	if countries != "" {
+		maxAge = 0
+	}
+	var v validators
+	if conditional(r) {
+		v = h.loadValidators(r.Context(), id, countries)
+		if v.notModified(r) {
+			writeCacheHeaders(w, v, maxAge)
+			w.Header().Set("Vary", "Accept-Encoding")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	ctx := r.Context()
+	// a filtered graph is decoded, so only the unfiltered graph may be returned as stored
+	if countries == "" {
+		if h.redirect(w, r, id) {
+			return
+		}
+		ctx = types.WithAcceptEncoding(ctx, acceptedEncodings(r)...)
+	}
+	body, err := h.Storage.Get(ctx, id)
 	switch err.(type) {
 	case nil:
 		defer body.Close()
 	case types.ErrInProgress:
+		// the graph will exist once completed, so neither response may be cached
+		w.Header().Set("Cache-Control", "no-store")
 		w.WriteHeader(http.StatusNoContent)
 		return
 	case types.ErrNotFound:
 		logger.Info(logs.NotFound{Reason: err.Error()})
+		w.Header().Set("Cache-Control", "no-store")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	default:
@@ -97,9 +184,105 @@ func (h *GrapherHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	head := r.Method == http.MethodHead
+	if countries != "" && !head {
+		g, err := graph.FromDOT(body)
+		if err != nil {
+			logger.Error(logs.UnknownFailure{Reason: err.Error()})
+			writeJSONResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		var buf bytes.Buffer
+		_ = graph.EncodeDOT(&buf, graph.FilterByCountry(g, strings.Split(countries, ",")))
+		body = ioutil.NopCloser(&buf)
+	}
+	if !conditional(r) {
+		v = h.loadValidators(r.Context(), id, countries)
+	}
 	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Vary", "Accept-Encoding")
+	if encoding := types.ContentEncoding(body); encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	writeCacheHeaders(w, v, maxAge)
 	w.WriteHeader(http.StatusOK)
-	_, _ = io.Copy(w, body)
+	if !head {
+		_, _ = io.Copy(w, body)
+	}
+}
+
+// redirect responds with a temporary redirect to a presigned URL of the graph, and returns true if it did. The
+// graphs which cannot be redirected, including those which do not exist, are left to be returned by Get, as
+// are HEAD requests.
+func (h *GrapherHandler) redirect(w http.ResponseWriter, r *http.Request, id string) bool {
+	// presigned URLs are signed for GET, so HEAD requests are answered by the service
+	presigner, ok := h.Storage.(types.Presigner)
+	if !ok || h.RedirectExpiration <= 0 || r.Method != http.MethodGet {
+		return false
+	}
+	presigned, err := presigner.Presign(r.Context(), id, h.RedirectExpiration)
+	switch err.(type) {
+	case nil:
+	case types.ErrNotFound, types.ErrInProgress, types.ErrNotPresignable:
+		return false
+	default:
+		h.LogProvider(r.Context()).Error(logs.DependencyFailure{Dependency: logs.DependencyStorage, Reason: err.Error()})
+		return false
+	}
+	if presigned.Size < h.RedirectMinSize {
+		return false
+	}
+	// the presigned download is returned as stored, so it must be in an encoding the client accepts
+	if presigned.Encoding != "" && !contains(acceptedEncodings(r), presigned.Encoding) {
+		return false
+	}
+	// the redirect must not be cached for longer than the presigned URL is valid
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, presigned.URL, http.StatusTemporaryRedirect)
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptedEncodings returns the content encodings accepted by the client in the Accept-Encoding header,
+// excluding those with a quality of zero
+func acceptedEncodings(r *http.Request) []string {
+	var encodings []string
+	for _, header := range r.Header["Accept-Encoding"] {
+		for _, value := range strings.Split(header, ",") {
+			params := strings.Split(value, ";")
+			encoding := strings.ToLower(strings.TrimSpace(params[0]))
+			if encoding == "" {
+				continue
+			}
+			rejected := false
+			for _, param := range params[1:] {
+				if q, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(param), "q="), 64); err == nil && q == 0 {
+					rejected = true
+				}
+			}
+			if !rejected {
+				encodings = append(encodings, encoding)
+			}
+		}
+	}
+	return encodings
+}
+
+// extractForce returns the value of the optional force query parameter
+func extractForce(r *http.Request) (bool, error) {
+	force := r.URL.Query().Get("force")
+	if force == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(force)
 }
 
 // extractInput attempts to extract the start/stop query parameters required by GET and POST.
@@ -108,8 +291,16 @@ func (h *GrapherHandler) Get(w http.ResponseWriter, r *http.Request) {
 // truncates the time values to the nearest minute since anything with more precision doesn't
 // really fit the vpc flow filter use case
 func extractInput(r *http.Request) (time.Time, time.Time, error) {
-	startString := r.URL.Query().Get("start")
-	stopString := r.URL.Query().Get("stop")
+	return extractWindow(r, "start", "stop")
+}
+
+// extractWindow applies the same parsing and validation as extractInput to the given pair of query parameters
+func extractWindow(r *http.Request, startParam, stopParam string) (time.Time, time.Time, error) {
+	return parseWindow(r.URL.Query().Get(startParam), r.URL.Query().Get(stopParam))
+}
+
+// parseWindow parses and validates a pair of RFC3339Nano start and stop times, truncated to the minute
+func parseWindow(startString, stopString string) (time.Time, time.Time, error) {
 	start, err := time.Parse(time.RFC3339Nano, startString)
 	if err != nil {
 		return time.Time{}, time.Time{}, err
@@ -124,12 +315,28 @@ func extractInput(r *http.Request) (time.Time, time.Time, error) {
 	return start.Truncate(time.Minute), stop.Truncate(time.Minute), nil
 }
 
-// computeID generates a UUID v5 from a name composed by appending start and stop time strings
-// in that order
-func computeID(start, stop time.Time) string {
-	name := start.String() + stop.String()
-	u := uuid.NewSHA1(graphNamespace, []byte(name))
-	return u.String()
+// extractGraph identifies the graph addressed by a request, either by the id query parameter or, if absent,
+// by the start/stop window. The window is only returned when the graph is addressed by it.
+func extractGraph(r *http.Request) (string, time.Time, time.Time, error) {
+	if id := r.URL.Query().Get("id"); id != "" {
+		id, err := parseID(id)
+		return id, time.Time{}, time.Time{}, err
+	}
+	start, stop, err := extractInput(r)
+	if err != nil {
+		return "", time.Time{}, time.Time{}, err
+	}
+	return graph.ID(start, stop), start, stop, nil
+}
+
+// parseID validates the ID of a graph, which is a UUID, and returns its canonical form. IDs address objects of
+// the storage, so anything else is rejected rather than risking to reach objects which are not graphs.
+func parseID(id string) (string, error) {
+	u, err := uuid.Parse(id)
+	if err != nil {
+		return "", fmt.Errorf("invalid graph ID %q", id)
+	}
+	return u.String(), nil
 }
 
 // write the http response with the given status code and message
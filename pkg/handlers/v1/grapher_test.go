@@ -3,18 +3,31 @@ package v1
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/asecurityteam/logevent"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage/storagetest"
 	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
 	"github.com/golang/mock/gomock"
 	"github.com/rs/xstats"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testID and testBaseID are graph IDs for requests which identify graphs by ID
+const (
+	testID     = "d9a2f4b6-5c1e-5f3a-9b7d-2e8c4a6f1b03"
+	testBaseID = "4f7c1e2a-8b3d-5a6e-a1c9-7d0b3e5f2a84"
 )
 
 type timeMatcher struct {
@@ -184,6 +197,7 @@ func TestGetHappyPath(t *testing.T) {
 	readCloser := ioutil.NopCloser(bytes.NewReader([]byte(data)))
 
 	storageMock := NewMockStorage(ctrl)
+	expectMetadata(storageMock, nil)
 	storageMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(readCloser, nil)
 
 	h := GrapherHandler{
@@ -202,6 +216,145 @@ func TestGetHappyPath(t *testing.T) {
 	assert.Equal(t, data, string(result))
 }
 
+func TestGetCountryFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Now().Format(time.RFC3339Nano)
+	stop := time.Now().Format(time.RFC3339Nano)
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	q := r.URL.Query()
+	q.Set("start", start)
+	q.Set("stop", stop)
+	q.Set("country", "de,fr")
+	r.URL.RawQuery = q.Encode()
+	r = r.WithContext(logevent.NewContext(context.Background(), logevent.New(logevent.Config{Output: ioutil.Discard})))
+
+	data := `digraph {
+	n10001 -> n8888 [govpc_dstPort="53"]
+	n10001 -> n85214132117 [govpc_dstPort="443"]
+	n10001 [label="10.0.0.1"]
+	n8888 [label="8.8.8.8" grapherd_country="US"]
+	n85214132117 [label="85.214.132.117" grapherd_country="DE"]
+}`
+	readCloser := ioutil.NopCloser(bytes.NewReader([]byte(data)))
+	storageMock := NewMockStorage(ctrl)
+	expectMetadata(storageMock, nil)
+	storageMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(readCloser, nil)
+	h := GrapherHandler{
+		LogProvider:  logevent.FromContext,
+		StatProvider: xstats.FromContext,
+		Storage:      storageMock,
+	}
+	h.Get(w, r)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	result, _ := ioutil.ReadAll(w.Result().Body)
+	assert.Contains(t, string(result), "n85214132117")
+	assert.NotContains(t, string(result), "n8888")
+}
+
+func TestGetEncoded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Now().Format(time.RFC3339Nano)
+	stop := time.Now().Format(time.RFC3339Nano)
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	q := r.URL.Query()
+	q.Set("start", start)
+	q.Set("stop", stop)
+	r.URL.RawQuery = q.Encode()
+	r.Header.Set("Accept-Encoding", "br;q=0, gzip;q=0.8")
+	r = r.WithContext(logevent.NewContext(context.Background(), logevent.New(logevent.Config{Output: ioutil.Discard})))
+
+	data := "compressed graph"
+	readCloser := types.EncodedReadCloser{ReadCloser: ioutil.NopCloser(bytes.NewReader([]byte(data))), Encoding: "gzip"}
+	storageMock := NewMockStorage(ctrl)
+	expectMetadata(storageMock, nil)
+	storageMock.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, _ string) (io.ReadCloser, error) {
+		assert.True(t, types.AcceptsEncoding(ctx, "gzip"))
+		assert.False(t, types.AcceptsEncoding(ctx, "br"))
+		return readCloser, nil
+	})
+	h := GrapherHandler{
+		LogProvider:  logevent.FromContext,
+		StatProvider: xstats.FromContext,
+		Storage:      storageMock,
+	}
+	h.Get(w, r)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, "gzip", w.Result().Header.Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Result().Header.Get("Vary"))
+	result, _ := ioutil.ReadAll(w.Result().Body)
+	assert.Equal(t, data, string(result))
+}
+
+func TestGetCountryFilterNotEncoded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	r := newAnalysisRequest("/", map[string]string{
+		"start":   time.Now().Format(time.RFC3339Nano),
+		"stop":    time.Now().Format(time.RFC3339Nano),
+		"country": "DE",
+	})
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+
+	storageMock := NewMockStorage(ctrl)
+	expectMetadata(storageMock, nil)
+	storageMock.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, _ string) (io.ReadCloser, error) {
+		assert.False(t, types.AcceptsEncoding(ctx, "gzip"))
+		return ioutil.NopCloser(bytes.NewReader([]byte("digraph {}"))), nil
+	})
+	h := GrapherHandler{
+		LogProvider:  logevent.FromContext,
+		StatProvider: xstats.FromContext,
+		Storage:      storageMock,
+	}
+	h.Get(w, r)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Empty(t, w.Result().Header.Get("Content-Encoding"))
+}
+
+func TestGetCountryFilterInvalidGraph(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Now().Format(time.RFC3339Nano)
+	stop := time.Now().Format(time.RFC3339Nano)
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	q := r.URL.Query()
+	q.Set("start", start)
+	q.Set("stop", stop)
+	q.Set("country", "DE")
+	r.URL.RawQuery = q.Encode()
+	r = r.WithContext(logevent.NewContext(context.Background(), logevent.New(logevent.Config{Output: ioutil.Discard})))
+
+	readCloser := ioutil.NopCloser(bytes.NewReader([]byte("not a graph")))
+	storageMock := NewMockStorage(ctrl)
+	storageMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(readCloser, nil)
+	h := GrapherHandler{
+		LogProvider:  logevent.FromContext,
+		StatProvider: xstats.FromContext,
+		Storage:      storageMock,
+	}
+	h.Get(w, r)
+	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+}
+
+func TestParseID(t *testing.T) {
+	id, err := parseID(strings.ToUpper(testID))
+	assert.Nil(t, err)
+	assert.Equal(t, testID, id)
+
+	_, err = parseID("baseline/123456789012.json")
+	assert.NotNil(t, err)
+}
+
 func TestPostConflictInProgress(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -234,14 +387,14 @@ func TestPostConflictGraphCreated(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	start := time.Now().Format(time.RFC3339Nano)
-	stop := time.Now().Format(time.RFC3339Nano)
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(time.Hour)
 	r, _ := http.NewRequest(http.MethodPost, "/", nil)
 	w := httptest.NewRecorder()
 
 	q := r.URL.Query()
-	q.Set("start", start)
-	q.Set("stop", stop)
+	q.Set("start", start.Format(time.RFC3339Nano))
+	q.Set("stop", stop.Format(time.RFC3339Nano))
 	r.URL.RawQuery = q.Encode()
 	r = r.WithContext(logevent.NewContext(context.Background(), logevent.New(logevent.Config{Output: ioutil.Discard})))
 
@@ -256,6 +409,8 @@ func TestPostConflictGraphCreated(t *testing.T) {
 	h.Post(w, r)
 
 	assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+	body, _ := ioutil.ReadAll(w.Result().Body)
+	assert.Contains(t, string(body), "2019-05-01T00:00:00Z to 2019-05-01T01:00:00Z")
 }
 
 func TestPostStorageError(t *testing.T) {
@@ -394,3 +549,146 @@ func TestPostUnsuccessfulMark(t *testing.T) {
 	// Shouldn't blow up
 	assert.Equal(t, http.StatusAccepted, w.Result().StatusCode)
 }
+
+func newForceRequest(start, stop time.Time, force string) *http.Request {
+	r, _ := http.NewRequest(http.MethodPost, "/", nil)
+	q := r.URL.Query()
+	q.Set("start", start.Format(time.RFC3339Nano))
+	q.Set("stop", stop.Format(time.RFC3339Nano))
+	q.Set("force", force)
+	r.URL.RawQuery = q.Encode()
+	return r.WithContext(logevent.NewContext(context.Background(), logevent.New(logevent.Config{Output: ioutil.Discard})))
+}
+
+// newLock returns a lock which is stored apart from the mocked storage
+func newLock() *storage.Lock {
+	return &storage.Lock{Storage: storagetest.NewMemory(), Wait: time.Millisecond, Settle: time.Millisecond}
+}
+
+func TestRecordRegenerationConcurrent(t *testing.T) {
+	memory := storagetest.NewMemory()
+	lock := &storage.Lock{Storage: memory, Wait: time.Millisecond, Settle: 5 * time.Millisecond}
+	id := graph.ID(time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2019, 5, 1, 1, 0, 0, 0, time.UTC))
+
+	const regenerations = 5
+	errs := make(chan error, regenerations)
+	for i := 0; i < regenerations; i++ {
+		go func() {
+			errs <- recordRegeneration(context.Background(), lock, memory, id, time.Now())
+		}()
+	}
+	for i := 0; i < regenerations; i++ {
+		require.Nil(t, <-errs)
+	}
+
+	stored, err := memory.Get(context.Background(), id+RegenerationSuffix)
+	require.Nil(t, err)
+	var record regeneration
+	require.Nil(t, json.NewDecoder(stored).Decode(&record))
+	assert.Equal(t, regenerations, record.Count)
+}
+
+func TestPostForceInvalid(t *testing.T) {
+	w := httptest.NewRecorder()
+	h := GrapherHandler{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext}
+	h.Post(w, newForceRequest(time.Now().Add(-time.Hour), time.Now(), "maybe"))
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestPostForceRegenerates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(time.Hour)
+	id := graph.ID(start, stop)
+
+	storageMock := NewMockStorage(ctrl)
+	storageMock.EXPECT().Exists(gomock.Any(), id).Return(true, nil)
+	storageMock.EXPECT().Get(gomock.Any(), id+RegenerationSuffix).Return(ioutil.NopCloser(bytes.NewReader([]byte(`{"graphID":"`+id+`","count":1}`))), nil)
+	storageMock.EXPECT().Store(gomock.Any(), id+RegenerationSuffix, gomock.Any()).DoAndReturn(func(_ context.Context, _ string, r io.ReadCloser) error {
+		var record regeneration
+		require.Nil(t, json.NewDecoder(r).Decode(&record))
+		assert.Equal(t, id, record.GraphID)
+		assert.Equal(t, 2, record.Count)
+		assert.False(t, record.Requested.IsZero())
+		return nil
+	})
+	queuerMock := NewMockQueuer(ctrl)
+	queuerMock.EXPECT().Queue(gomock.Any(), id, start, stop).DoAndReturn(func(ctx context.Context, _ string, _, _ time.Time) error {
+		assert.True(t, types.ForceFromContext(ctx))
+		return nil
+	})
+	markerMock := NewMockMarker(ctrl)
+	markerMock.EXPECT().Mark(gomock.Any(), id).Return(nil)
+
+	w := httptest.NewRecorder()
+	h := GrapherHandler{
+		LogProvider:  logevent.FromContext,
+		StatProvider: xstats.FromContext,
+		Storage:      storageMock,
+		Queuer:       queuerMock,
+		Marker:       markerMock,
+		Lock:         newLock(),
+	}
+	h.Post(w, newForceRequest(start, stop, "true"))
+	assert.Equal(t, http.StatusAccepted, w.Result().StatusCode)
+}
+
+func TestPostForceRecordError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(time.Hour)
+
+	storageMock := NewMockStorage(ctrl)
+	storageMock.EXPECT().Exists(gomock.Any(), gomock.Any()).Return(true, nil)
+	storageMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, errors.New("oops"))
+	queuerMock := NewMockQueuer(ctrl)
+	queuerMock.EXPECT().Queue(gomock.Any(), gomock.Any(), start, stop).Return(nil)
+	markerMock := NewMockMarker(ctrl)
+	markerMock.EXPECT().Mark(gomock.Any(), gomock.Any()).Return(nil)
+
+	w := httptest.NewRecorder()
+	h := GrapherHandler{
+		LogProvider:  logevent.FromContext,
+		StatProvider: xstats.FromContext,
+		Storage:      storageMock,
+		Queuer:       queuerMock,
+		Marker:       markerMock,
+		Lock:         newLock(),
+	}
+	h.Post(w, newForceRequest(start, stop, "true"))
+	assert.Equal(t, http.StatusAccepted, w.Result().StatusCode)
+}
+
+func TestPostForceNewGraph(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(time.Hour)
+
+	// a graph which does not exist yet is created as usual, without a regeneration record
+	storageMock := NewMockStorage(ctrl)
+	storageMock.EXPECT().Exists(gomock.Any(), gomock.Any()).Return(false, nil)
+	queuerMock := NewMockQueuer(ctrl)
+	queuerMock.EXPECT().Queue(gomock.Any(), gomock.Any(), start, stop).DoAndReturn(func(ctx context.Context, _ string, _, _ time.Time) error {
+		assert.False(t, types.ForceFromContext(ctx))
+		return nil
+	})
+	markerMock := NewMockMarker(ctrl)
+	markerMock.EXPECT().Mark(gomock.Any(), gomock.Any()).Return(nil)
+
+	w := httptest.NewRecorder()
+	h := GrapherHandler{
+		LogProvider:  logevent.FromContext,
+		StatProvider: xstats.FromContext,
+		Storage:      storageMock,
+		Queuer:       queuerMock,
+		Marker:       markerMock,
+	}
+	h.Post(w, newForceRequest(start, stop, "true"))
+	assert.Equal(t, http.StatusAccepted, w.Result().StatusCode)
+}
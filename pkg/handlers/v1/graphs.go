@@ -0,0 +1,153 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/logs"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+const (
+	defaultGraphsLimit = 100
+	maxGraphsLimit     = 1000
+
+	// listPageSize is the number of index entries requested from Storage at once
+	listPageSize = 1000
+)
+
+type graphsResponse struct {
+	Graphs []graph.Metadata `json:"graphs"`
+	// Next is the value of the after query parameter which returns the next page, if any
+	Next string `json:"next,omitempty"`
+}
+
+// Graphs is a handler which lists the stored graphs in chronological order of their window, from the index
+// of the graphs. If the start and stop query parameters are provided, only the graphs whose window overlaps
+// start to stop are listed.
+type Graphs struct {
+	LogProvider  types.LogFn
+	StatProvider types.StatFn
+	Storage      types.Storage
+}
+
+// ServeHTTP handles incoming HTTP requests, and returns a page of graph metadata
+func (h *Graphs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := h.LogProvider(r.Context())
+	filter, err := extractGraphsFilter(r)
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := extractLimit(r)
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := h.list(r.Context(), filter, r.URL.Query().Get("after"), limit)
+	if err != nil {
+		logger.Error(logs.DependencyFailure{Dependency: logs.DependencyStorage, Reason: err.Error()})
+		writeJSONResponse(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// graphsFilter is the window the listed graphs overlap. A nil filter lists every graph.
+type graphsFilter struct {
+	Start time.Time
+	Stop  time.Time
+}
+
+// list returns up to limit graphs after the index key after. Since index entries are ordered by the start of
+// their window, listing stops at the first entry which starts after the filter.
+func (h *Graphs) list(ctx context.Context, filter *graphsFilter, after string, limit int) (graphsResponse, error) {
+	res := graphsResponse{Graphs: make([]graph.Metadata, 0, limit)}
+	for {
+		keys, err := h.Storage.List(ctx, graph.IndexPrefix, after, listPageSize)
+		if err != nil {
+			return graphsResponse{}, err
+		}
+		for _, key := range keys {
+			if len(res.Graphs) == limit {
+				res.Next = after
+				return res, nil
+			}
+			_, start, stop, err := graph.ParseIndexKey(key)
+			if err != nil {
+				after = key
+				continue
+			}
+			if filter != nil && !start.Before(filter.Stop) {
+				return res, nil
+			}
+			after = key
+			if filter != nil && !stop.After(filter.Start) {
+				continue
+			}
+			metadata, err := h.load(ctx, key)
+			switch err.(type) {
+			case nil:
+				res.Graphs = append(res.Graphs, metadata)
+			case types.ErrNotFound:
+				// the graph was deleted since it was listed
+			default:
+				return graphsResponse{}, err
+			}
+		}
+		if len(keys) < listPageSize {
+			return res, nil
+		}
+	}
+}
+
+func (h *Graphs) load(ctx context.Context, key string) (graph.Metadata, error) {
+	var metadata graph.Metadata
+	stored, err := h.Storage.Get(ctx, key)
+	if err != nil {
+		return metadata, err
+	}
+	defer stored.Close()
+	err = json.NewDecoder(stored).Decode(&metadata)
+	return metadata, err
+}
+
+// extractGraphsFilter returns the window of the optional start and stop query parameters, which must be
+// provided together
+func extractGraphsFilter(r *http.Request) (*graphsFilter, error) {
+	query := r.URL.Query()
+	if query.Get("start") == "" && query.Get("stop") == "" {
+		return nil, nil
+	}
+	start, stop, err := extractInput(r)
+	if err != nil {
+		return nil, err
+	}
+	return &graphsFilter{Start: start, Stop: stop}, nil
+}
+
+// extractLimit returns the value of the optional limit query parameter
+func extractLimit(r *http.Request) (int, error) {
+	limitString := r.URL.Query().Get("limit")
+	if limitString == "" {
+		return defaultGraphsLimit, nil
+	}
+	limit, err := strconv.Atoi(limitString)
+	if err != nil {
+		return 0, err
+	}
+	if limit < 1 || limit > maxGraphsLimit {
+		return 0, errors.New("limit should be between 1 and " + strconv.Itoa(maxGraphsLimit))
+	}
+	return limit, nil
+}
@@ -0,0 +1,131 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/logevent"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/rs/xstats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectIndexedGraph expects the index entry of the graph of an hour to be loaded, and returns its key
+func expectIndexedGraph(storage *MockStorage, start time.Time) string {
+	stop := start.Add(time.Hour)
+	id := graph.ID(start, stop)
+	key := graph.IndexKey(id, start, stop)
+	b, _ := json.Marshal(graph.Metadata{ID: id, Start: start, Stop: stop, Format: graph.FormatDOT})
+	storage.EXPECT().Get(gomock.Any(), key).Return(ioutil.NopCloser(bytes.NewReader(b)), nil)
+	return key
+}
+
+func decodeGraphs(t *testing.T, w *httptest.ResponseRecorder) graphsResponse {
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, "application/json", w.Result().Header.Get("Content-Type"))
+	var res graphsResponse
+	require.Nil(t, json.NewDecoder(w.Body).Decode(&res))
+	return res
+}
+
+func TestGraphsBadRequest(t *testing.T) {
+	tc := []struct {
+		Name   string
+		Params map[string]string
+	}{
+		{"missing_stop", map[string]string{"start": "2019-05-01T00:00:00Z"}},
+		{"bad_start", map[string]string{"start": "invalid ts", "stop": "2019-05-01T00:00:00Z"}},
+		{"bad_limit", map[string]string{"limit": "all"}},
+		{"limit_too_large", map[string]string{"limit": "1001"}},
+		{"limit_too_small", map[string]string{"limit": "0"}},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h := &Graphs{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext}
+			h.ServeHTTP(w, newAnalysisRequest("/graphs", tt.Params))
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestGraphsPaginated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	storage := NewMockStorage(ctrl)
+	first := expectIndexedGraph(storage, start)
+	second := expectIndexedGraph(storage, start.Add(time.Hour))
+	third := graph.IndexKey("c", start.Add(2*time.Hour), start.Add(3*time.Hour))
+	storage.EXPECT().List(gomock.Any(), graph.IndexPrefix, "", listPageSize).Return([]string{first, "graphs/invalid.json", second, third}, nil)
+
+	w := httptest.NewRecorder()
+	h := &Graphs{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Storage: storage}
+	h.ServeHTTP(w, newAnalysisRequest("/graphs", map[string]string{"limit": "2"}))
+	res := decodeGraphs(t, w)
+	require.Len(t, res.Graphs, 2)
+	assert.Equal(t, graph.ID(start, start.Add(time.Hour)), res.Graphs[0].ID)
+	assert.Equal(t, second, res.Next)
+
+	// the next page starts after the last graph of the previous page
+	storage.EXPECT().List(gomock.Any(), graph.IndexPrefix, second, listPageSize).Return(nil, nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, newAnalysisRequest("/graphs", map[string]string{"limit": "2", "after": second}))
+	res = decodeGraphs(t, w)
+	assert.Empty(t, res.Graphs)
+	assert.Empty(t, res.Next)
+}
+
+func TestGraphsOverlap(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	storage := NewMockStorage(ctrl)
+	before := graph.IndexKey("a", start, start.Add(time.Hour))
+	overlapping := expectIndexedGraph(storage, start.Add(time.Hour))
+	deleted := graph.IndexKey("b", start.Add(time.Hour), start.Add(3*time.Hour))
+	storage.EXPECT().Get(gomock.Any(), deleted).Return(nil, types.ErrNotFound{ID: deleted})
+	after := graph.IndexKey("c", start.Add(2*time.Hour), start.Add(3*time.Hour))
+	storage.EXPECT().List(gomock.Any(), graph.IndexPrefix, "", listPageSize).Return([]string{before, overlapping, deleted, after}, nil)
+
+	w := httptest.NewRecorder()
+	h := &Graphs{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Storage: storage}
+	h.ServeHTTP(w, newAnalysisRequest("/graphs", map[string]string{
+		"start": start.Add(90 * time.Minute).Format(time.RFC3339Nano),
+		"stop":  start.Add(2 * time.Hour).Format(time.RFC3339Nano),
+	}))
+	res := decodeGraphs(t, w)
+	require.Len(t, res.Graphs, 1)
+	assert.Equal(t, graph.ID(start.Add(time.Hour), start.Add(2*time.Hour)), res.Graphs[0].ID)
+	assert.Empty(t, res.Next)
+}
+
+func TestGraphsStorageErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := NewMockStorage(ctrl)
+	storage.EXPECT().List(gomock.Any(), graph.IndexPrefix, "", listPageSize).Return(nil, errors.New("oops"))
+	w := httptest.NewRecorder()
+	h := &Graphs{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Storage: storage}
+	h.ServeHTTP(w, newAnalysisRequest("/graphs", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+
+	key := graph.IndexKey("a", time.Now().Add(-time.Hour), time.Now())
+	storage.EXPECT().List(gomock.Any(), graph.IndexPrefix, "", listPageSize).Return([]string{key}, nil)
+	storage.EXPECT().Get(gomock.Any(), key).Return(nil, errors.New("oops"))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, newAnalysisRequest("/graphs", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+}
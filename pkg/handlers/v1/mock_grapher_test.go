@@ -7,6 +7,7 @@ import (
 	context "context"
 	gomock "github.com/golang/mock/gomock"
 	io "io"
+	time "time"
 )
 
 // Mock of Grapher interface
@@ -30,12 +31,12 @@ func (_m *MockGrapher) EXPECT() *_MockGrapherRecorder {
 	return _m.recorder
 }
 
-func (_m *MockGrapher) Graph(_param0 context.Context, _param1 string, _param2 io.ReadCloser) error {
-	ret := _m.ctrl.Call(_m, "Graph", _param0, _param1, _param2)
+func (_m *MockGrapher) Graph(ctx context.Context, id string, start time.Time, stop time.Time, digest io.ReadCloser) error {
+	ret := _m.ctrl.Call(_m, "Graph", ctx, id, start, stop, digest)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-func (_mr *_MockGrapherRecorder) Graph(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "Graph", arg0, arg1, arg2)
+func (_mr *_MockGrapherRecorder) Graph(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Graph", arg0, arg1, arg2, arg3, arg4)
 }
@@ -62,6 +62,27 @@ func (_mr *_MockStorageRecorder) Store(arg0, arg1, arg2 interface{}) *gomock.Cal
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Store", arg0, arg1, arg2)
 }
 
+func (_m *MockStorage) Delete(ctx context.Context, key string) error {
+	ret := _m.ctrl.Call(_m, "Delete", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockStorageRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Delete", arg0, arg1)
+}
+
+func (_m *MockStorage) List(ctx context.Context, prefix string, after string, limit int) ([]string, error) {
+	ret := _m.ctrl.Call(_m, "List", ctx, prefix, after, limit)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockStorageRecorder) List(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "List", arg0, arg1, arg2, arg3)
+}
+
 // Mock of Marker interface
 type MockMarker struct {
 	ctrl     *gomock.Controller
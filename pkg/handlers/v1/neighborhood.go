@@ -0,0 +1,79 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/logs"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+const (
+	defaultNeighborhoodDepth = 1
+	maxNeighborhoodDepth     = 5
+)
+
+// Neighborhood is a handler which extracts the part of a graph surrounding a single address or CIDR block
+type Neighborhood struct {
+	LogProvider  types.LogFn
+	StatProvider types.StatFn
+	Source       types.GraphSource
+}
+
+// ServeHTTP handles incoming HTTP requests, and returns every node within depth hops of the addresses
+// matching the ip query parameter, along with the edges connecting them. The graph is identified either
+// by the start/stop window or by the id of a stored graph.
+func (h *Neighborhood) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := h.LogProvider(r.Context())
+	format, err := graph.LookupFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	seed, err := graph.ParseNetwork(r.URL.Query().Get("ip"))
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	depth, err := extractDepth(r)
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id, start, stop, err := extractGraph(r)
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	g, err := h.Source.Load(r.Context(), id, start, stop)
+	if err != nil {
+		writeSourceError(w, logger, err)
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType)
+	w.WriteHeader(http.StatusOK)
+	_ = format.Encoder(w, graph.Neighborhood(g, seed, depth))
+}
+
+// extractDepth returns the depth query parameter, which must be between 1 and maxNeighborhoodDepth
+func extractDepth(r *http.Request) (int, error) {
+	depthStr := r.URL.Query().Get("depth")
+	if depthStr == "" {
+		return defaultNeighborhoodDepth, nil
+	}
+	depth, err := strconv.Atoi(depthStr)
+	if err != nil {
+		return 0, err
+	}
+	if depth < 1 || depth > maxNeighborhoodDepth {
+		return 0, fmt.Errorf("depth should be between 1 and %d", maxNeighborhoodDepth)
+	}
+	return depth, nil
+}
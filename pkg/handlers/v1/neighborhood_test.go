@@ -0,0 +1,105 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/logevent"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/golang/mock/gomock"
+	"github.com/rs/xstats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNeighborhoodBadRequest(t *testing.T) {
+	tc := []struct {
+		Name   string
+		Params map[string]string
+	}{
+		{
+			Name:   "bad_format",
+			Params: map[string]string{"id": testID, "ip": "10.0.0.1", "format": "svg"},
+		},
+		{
+			Name:   "bad_id",
+			Params: map[string]string{"id": "baseline/123456789012.json", "ip": "10.0.0.1"},
+		},
+		{
+			Name:   "missing_ip",
+			Params: map[string]string{"id": testID},
+		},
+		{
+			Name:   "bad_cidr",
+			Params: map[string]string{"id": testID, "ip": "10.0.0.0/33"},
+		},
+		{
+			Name:   "bad_depth",
+			Params: map[string]string{"id": testID, "ip": "10.0.0.1", "depth": "two"},
+		},
+		{
+			Name:   "depth_too_large",
+			Params: map[string]string{"id": testID, "ip": "10.0.0.1", "depth": "6"},
+		},
+		{
+			Name:   "bad_window",
+			Params: map[string]string{"ip": "10.0.0.1", "start": "invalid ts"},
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h := &Neighborhood{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext}
+			h.ServeHTTP(w, newAnalysisRequest("/neighborhood", tt.Params))
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestNeighborhoodByWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(time.Hour)
+	g := newTestGraph(443)
+	c := g.AddNode("10.0.0.3")
+	g.Edges = append(g.Edges, &graph.Edge{From: graph.NodeID("10.0.0.2"), To: c.ID, DstPort: 5432, Protocol: 6})
+	mockSource := NewMockGraphSource(ctrl)
+	mockSource.EXPECT().Load(gomock.Any(), graph.ID(start, stop), start, stop).Return(g, nil)
+
+	w := httptest.NewRecorder()
+	h := &Neighborhood{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Source: mockSource}
+	h.ServeHTTP(w, newAnalysisRequest("/neighborhood", map[string]string{
+		"start":  start.Format(time.RFC3339Nano),
+		"stop":   stop.Format(time.RFC3339Nano),
+		"ip":     "10.0.0.1",
+		"format": "json",
+	}))
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, "application/json", w.Result().Header.Get("Content-Type"))
+	var body struct {
+		Nodes []interface{} `json:"nodes"`
+		Edges []interface{} `json:"edges"`
+	}
+	require.Nil(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Len(t, body.Nodes, 2)
+	assert.Len(t, body.Edges, 1)
+}
+
+func TestNeighborhoodSourceError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSource := NewMockGraphSource(ctrl)
+	mockSource.EXPECT().Load(gomock.Any(), testID, time.Time{}, time.Time{}).Return(nil, errors.New("oops"))
+
+	w := httptest.NewRecorder()
+	h := &Neighborhood{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Source: mockSource}
+	h.ServeHTTP(w, newAnalysisRequest("/neighborhood", map[string]string{"id": testID, "ip": "10.0.0.1", "depth": "2"}))
+	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+}
@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/logs"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+const (
+	defaultMaxHops = 3
+	maxMaxHops     = 6
+	maxPaths       = 100
+)
+
+// pathsResponse is the body returned by the Paths handler
+type pathsResponse struct {
+	GraphID     string       `json:"graphID"`
+	Source      string       `json:"source"`
+	Destination string       `json:"destination"`
+	MaxHops     int          `json:"maxHops"`
+	Truncated   bool         `json:"truncated"`
+	Paths       []graph.Path `json:"paths"`
+}
+
+// Paths is a handler which finds the observed paths of accepted traffic between two networks
+type Paths struct {
+	LogProvider  types.LogFn
+	StatProvider types.StatFn
+	Source       types.GraphSource
+}
+
+// ServeHTTP handles incoming HTTP requests, and returns the paths leading from the source to the
+// destination network, including the ports and volume of each hop. When no path exists, an empty
+// list of paths is returned. The graph is identified either by the start/stop window or by the id
+// of a stored graph.
+func (h *Paths) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := h.LogProvider(r.Context())
+	source, err := graph.ParseNetwork(r.URL.Query().Get("source"))
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	destination, err := graph.ParseNetwork(r.URL.Query().Get("destination"))
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	maxHops, err := extractMaxHops(r)
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id, start, stop, err := extractGraph(r)
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	g, err := h.Source.Load(r.Context(), id, start, stop)
+	if err != nil {
+		writeSourceError(w, logger, err)
+		return
+	}
+	paths, truncated := graph.FindPaths(g, source, destination, maxHops, maxPaths)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(pathsResponse{
+		GraphID:     id,
+		Source:      source.String(),
+		Destination: destination.String(),
+		MaxHops:     maxHops,
+		Truncated:   truncated,
+		Paths:       paths,
+	})
+}
+
+// extractMaxHops returns the maxHops query parameter, which must be between 1 and maxMaxHops
+func extractMaxHops(r *http.Request) (int, error) {
+	hopsStr := r.URL.Query().Get("maxHops")
+	if hopsStr == "" {
+		return defaultMaxHops, nil
+	}
+	hops, err := strconv.Atoi(hopsStr)
+	if err != nil {
+		return 0, err
+	}
+	if hops < 1 || hops > maxMaxHops {
+		return 0, fmt.Errorf("maxHops should be between 1 and %d", maxMaxHops)
+	}
+	return hops, nil
+}
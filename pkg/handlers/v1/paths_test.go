@@ -0,0 +1,102 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/logevent"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/rs/xstats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathsBadRequest(t *testing.T) {
+	tc := []struct {
+		Name   string
+		Params map[string]string
+	}{
+		{
+			Name:   "missing_source",
+			Params: map[string]string{"id": testID, "destination": "10.0.0.2"},
+		},
+		{
+			Name:   "missing_destination",
+			Params: map[string]string{"id": testID, "source": "10.0.0.1"},
+		},
+		{
+			Name:   "bad_max_hops",
+			Params: map[string]string{"id": testID, "source": "10.0.0.1", "destination": "10.0.0.2", "maxHops": "0"},
+		},
+		{
+			Name:   "bad_window",
+			Params: map[string]string{"source": "10.0.0.1", "destination": "10.0.0.2", "start": "invalid ts"},
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h := &Paths{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext}
+			h.ServeHTTP(w, newAnalysisRequest("/paths", tt.Params))
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestPathsFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(time.Hour)
+	mockSource := NewMockGraphSource(ctrl)
+	mockSource.EXPECT().Load(gomock.Any(), graph.ID(start, stop), start, stop).Return(newTestGraph(443), nil)
+
+	w := httptest.NewRecorder()
+	h := &Paths{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Source: mockSource}
+	h.ServeHTTP(w, newAnalysisRequest("/paths", map[string]string{
+		"start":       start.Format(time.RFC3339Nano),
+		"stop":        stop.Format(time.RFC3339Nano),
+		"source":      "10.0.0.0/31",
+		"destination": "10.0.0.2",
+	}))
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Contains(t, w.Body.String(), `"paths":[{"hops":[{"from":"10.0.0.1","to":"10.0.0.2","ports":[{"port":443,"protocol":6}],"bytes":100,"packets":0}]}]`)
+}
+
+func TestPathsNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSource := NewMockGraphSource(ctrl)
+	mockSource.EXPECT().Load(gomock.Any(), testID, time.Time{}, time.Time{}).Return(newTestGraph(443), nil)
+
+	w := httptest.NewRecorder()
+	h := &Paths{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Source: mockSource}
+	h.ServeHTTP(w, newAnalysisRequest("/paths", map[string]string{"id": testID, "source": "10.0.0.2", "destination": "10.0.0.1"}))
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Contains(t, w.Body.String(), `"paths":[]`)
+}
+
+func TestPathsSourceError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSource := NewMockGraphSource(ctrl)
+	mockSource.EXPECT().Load(gomock.Any(), testID, gomock.Any(), gomock.Any()).Return(nil, types.ErrNotFound{ID: testID})
+
+	w := httptest.NewRecorder()
+	h := &Paths{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Source: mockSource}
+	h.ServeHTTP(w, newAnalysisRequest("/paths", map[string]string{"id": testID, "source": "10.0.0.1", "destination": "10.0.0.2"}))
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+
+	mockSource.EXPECT().Load(gomock.Any(), testID, gomock.Any(), gomock.Any()).Return(nil, errors.New("oops"))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, newAnalysisRequest("/paths", map[string]string{"id": testID, "source": "10.0.0.1", "destination": "10.0.0.2"}))
+	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+}
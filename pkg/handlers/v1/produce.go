@@ -13,6 +13,7 @@ type payload struct {
 	ID    string `json:"id"`
 	Start string `json:"start"`
 	Stop  string `json:"stop"`
+	Force bool   `json:"force"`
 }
 
 // Produce is a handler which performs the digest job, and stores the digest
@@ -62,7 +63,12 @@ func (h *Produce) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	digest, err := h.Digester.Digest(r.Context(), start, stop)
+	// forced jobs regenerate an existing graph, and must not reuse any result cached when it was first created
+	ctx := r.Context()
+	if body.Force {
+		ctx = types.WithForce(ctx)
+	}
+	digest, err := h.Digester.Digest(ctx, start, stop)
 	if err != nil {
 		logger.Error(logs.DependencyFailure{Dependency: logs.DependencyDigester, Reason: err.Error()})
 		writeTextResponse(w, http.StatusInternalServerError, err.Error())
@@ -70,7 +76,7 @@ func (h *Produce) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	defer digest.Close()
 
-	if err := h.Grapher.Graph(r.Context(), body.ID, digest); err != nil {
+	if err := h.Grapher.Graph(ctx, body.ID, start, stop, digest); err != nil {
 		logger.Error(logs.DependencyFailure{Dependency: logs.DependencyGrapher, Reason: err.Error()})
 		writeTextResponse(w, http.StatusInternalServerError, err.Error())
 		return
@@ -80,7 +86,7 @@ func (h *Produce) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// fetching the digest will result in a perpetual "in progress" state. To mitigate this, we
 	// report a failure to the caller signifying that the operation should be retried. This will
 	// hopefully mitigate the amount of invalid state occurrence we may incur
-	if err := h.Marker.Unmark(r.Context(), body.ID); err != nil {
+	if err := h.Marker.Unmark(ctx, body.ID); err != nil {
 		logger.Error(logs.DependencyFailure{Dependency: logs.DependencyMarker, Reason: err.Error()})
 		writeTextResponse(w, http.StatusInternalServerError, err.Error())
 		return
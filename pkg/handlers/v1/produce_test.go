@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/asecurityteam/logevent"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
 	"github.com/golang/mock/gomock"
 	"github.com/rs/xstats"
 	"github.com/stretchr/testify/assert"
@@ -94,7 +96,7 @@ func TestGrapherError(t *testing.T) {
 	digesterMock.EXPECT().Digest(gomock.Any(), gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(bytes.NewReader([]byte(""))), nil)
 
 	grapherMock := NewMockGrapher(ctrl)
-	grapherMock.EXPECT().Graph(gomock.Any(), key, gomock.Any()).Return(errors.New("oops"))
+	grapherMock.EXPECT().Graph(gomock.Any(), key, gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("oops"))
 
 	start := time.Now().Add(-1 * time.Minute)
 	stop := time.Now()
@@ -120,7 +122,7 @@ func TestMarkerError(t *testing.T) {
 	digesterMock.EXPECT().Digest(gomock.Any(), gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(bytes.NewReader([]byte(""))), nil)
 
 	grapherMock := NewMockGrapher(ctrl)
-	grapherMock.EXPECT().Graph(gomock.Any(), key, gomock.Any()).Return(nil)
+	grapherMock.EXPECT().Graph(gomock.Any(), key, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 
 	markerMock := NewMockMarker(ctrl)
 	markerMock.EXPECT().Unmark(gomock.Any(), key).Return(errors.New("oops"))
@@ -150,7 +152,7 @@ func TestHappyPath(t *testing.T) {
 	digesterMock.EXPECT().Digest(gomock.Any(), gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(bytes.NewReader([]byte(""))), nil)
 
 	grapherMock := NewMockGrapher(ctrl)
-	grapherMock.EXPECT().Graph(gomock.Any(), key, gomock.Any()).Return(nil)
+	grapherMock.EXPECT().Graph(gomock.Any(), key, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 
 	markerMock := NewMockMarker(ctrl)
 	markerMock.EXPECT().Unmark(gomock.Any(), key).Return(nil)
@@ -171,3 +173,34 @@ func TestHappyPath(t *testing.T) {
 	handler.ServeHTTP(w, r)
 	assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
 }
+
+func TestProduceForce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	digesterMock := NewMockDigester(ctrl)
+	digesterMock.EXPECT().Digest(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, _, _ time.Time) (io.ReadCloser, error) {
+		assert.True(t, types.ForceFromContext(ctx))
+		return ioutil.NopCloser(bytes.NewReader([]byte(""))), nil
+	})
+	grapherMock := NewMockGrapher(ctrl)
+	grapherMock.EXPECT().Graph(gomock.Any(), key, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	markerMock := NewMockMarker(ctrl)
+	markerMock.EXPECT().Unmark(gomock.Any(), key).Return(nil)
+
+	start := time.Now().Add(-1 * time.Minute)
+	stop := time.Now()
+	payload := []byte(fmt.Sprintf(`{"id":"%s","start":"%s","stop":"%s","force":true}`, key, start.Format(time.RFC3339Nano), stop.Format(time.RFC3339Nano)))
+	r, _ := http.NewRequest(http.MethodPost, "/", ioutil.NopCloser(bytes.NewReader(payload)))
+	r = r.WithContext(logevent.NewContext(context.Background(), logevent.New(logevent.Config{Output: ioutil.Discard})))
+	w := httptest.NewRecorder()
+	handler := &Produce{
+		LogProvider:  logevent.FromContext,
+		StatProvider: xstats.FromContext,
+		Grapher:      grapherMock,
+		Marker:       markerMock,
+		Digester:     digesterMock,
+	}
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+}
@@ -0,0 +1,195 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/logevent"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/rs/xstats"
+	"github.com/stretchr/testify/assert"
+)
+
+// presignerStorage is a Storage with the Presigner capability, which returns a fixed result
+type presignerStorage struct {
+	*MockStorage
+	presigned types.Presigned
+	err       error
+}
+
+func (s *presignerStorage) Presign(_ context.Context, _ string, _ time.Duration) (types.Presigned, error) {
+	return s.presigned, s.err
+}
+
+func newGraphRequest(acceptEncoding string) *http.Request {
+	r := newAnalysisRequest("/", map[string]string{
+		"start": time.Now().Format(time.RFC3339Nano),
+		"stop":  time.Now().Format(time.RFC3339Nano),
+	})
+	if acceptEncoding != "" {
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	return r
+}
+
+func TestGetRedirect(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := &presignerStorage{
+		MockStorage: NewMockStorage(ctrl),
+		presigned:   types.Presigned{URL: "https://bucket.s3.amazonaws.com/graph.dot?X-Amz-Signature=abc", Size: 2048, Encoding: "gzip"},
+	}
+	h := GrapherHandler{
+		LogProvider:        logevent.FromContext,
+		StatProvider:       xstats.FromContext,
+		Storage:            storage,
+		RedirectExpiration: time.Minute,
+		RedirectMinSize:    1024,
+	}
+	// the metadata of the graph is not loaded for an unconditional redirect
+	w := httptest.NewRecorder()
+	h.Get(w, newGraphRequest("gzip"))
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Result().StatusCode)
+	assert.Equal(t, storage.presigned.URL, w.Result().Header.Get("Location"))
+	assert.Equal(t, "no-store", w.Result().Header.Get("Cache-Control"))
+}
+
+func TestHeadNotRedirected(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the presigned URL is only valid for GET, so the headers are returned by the service
+	storage := &presignerStorage{
+		MockStorage: NewMockStorage(ctrl),
+		presigned:   types.Presigned{URL: "https://bucket.s3.amazonaws.com/graph.dot?X-Amz-Signature=abc", Size: 2048},
+	}
+	expectMetadata(storage.MockStorage, &graph.Metadata{Hash: testHash, Created: testCreated})
+	storage.MockStorage.EXPECT().Get(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(bytes.NewReader([]byte("digraph {}"))), nil)
+	h := GrapherHandler{
+		LogProvider:        logevent.FromContext,
+		StatProvider:       xstats.FromContext,
+		Storage:            storage,
+		RedirectExpiration: time.Minute,
+		RedirectMinSize:    1024,
+	}
+	r := newGraphRequest("")
+	r.Method = http.MethodHead
+	w := httptest.NewRecorder()
+	h.Get(w, r)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Empty(t, w.Result().Header.Get("Location"))
+	assert.Equal(t, `W/"`+testHash+`"`, w.Result().Header.Get("ETag"))
+	body, _ := ioutil.ReadAll(w.Result().Body)
+	assert.Empty(t, body)
+}
+
+func TestGetRedirectConditional(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := &presignerStorage{
+		MockStorage: NewMockStorage(ctrl),
+		presigned:   types.Presigned{URL: "https://bucket.s3.amazonaws.com/graph.dot?X-Amz-Signature=abc", Size: 2048},
+	}
+	h := GrapherHandler{
+		LogProvider:        logevent.FromContext,
+		StatProvider:       xstats.FromContext,
+		Storage:            storage,
+		RedirectExpiration: time.Minute,
+		RedirectMinSize:    1024,
+	}
+	// the validators are loaded to evaluate the conditional request, and a modified graph is redirected
+	expectMetadata(storage.MockStorage, &graph.Metadata{Hash: testHash, Created: testCreated})
+	r := newGraphRequest("")
+	r.Header.Set("If-None-Match", `W/"other"`)
+	w := httptest.NewRecorder()
+	h.Get(w, r)
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Result().StatusCode)
+	assert.Equal(t, storage.presigned.URL, w.Result().Header.Get("Location"))
+}
+
+func TestGetRedirectFallback(t *testing.T) {
+	tc := []struct {
+		Name           string
+		Presigned      types.Presigned
+		Err            error
+		AcceptEncoding string
+		Expiration     time.Duration
+	}{
+		{
+			Name:       "disabled",
+			Presigned:  types.Presigned{URL: "https://example.com", Size: 2048},
+			Expiration: 0,
+		},
+		{
+			Name:       "small graph",
+			Presigned:  types.Presigned{URL: "https://example.com", Size: 512},
+			Expiration: time.Minute,
+		},
+		{
+			Name:           "encoding not accepted",
+			Presigned:      types.Presigned{URL: "https://example.com", Size: 2048, Encoding: "gzip"},
+			AcceptEncoding: "br",
+			Expiration:     time.Minute,
+		},
+		{
+			Name:       "not presignable",
+			Err:        types.ErrNotPresignable{Key: "abc"},
+			Expiration: time.Minute,
+		},
+		{
+			Name:       "presign error",
+			Err:        errors.New("oops"),
+			Expiration: time.Minute,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			storage := &presignerStorage{MockStorage: NewMockStorage(ctrl), presigned: tt.Presigned, err: tt.Err}
+			expectMetadata(storage.MockStorage, nil)
+			storage.MockStorage.EXPECT().Get(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(bytes.NewReader([]byte("digraph {}"))), nil)
+			h := GrapherHandler{
+				LogProvider:        logevent.FromContext,
+				StatProvider:       xstats.FromContext,
+				Storage:            storage,
+				RedirectExpiration: tt.Expiration,
+				RedirectMinSize:    1024,
+			}
+			w := httptest.NewRecorder()
+			h.Get(w, newGraphRequest(tt.AcceptEncoding))
+			assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+			result, _ := ioutil.ReadAll(w.Result().Body)
+			assert.Equal(t, "digraph {}", string(result))
+		})
+	}
+}
+
+func TestGetRedirectInProgress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := &presignerStorage{MockStorage: NewMockStorage(ctrl), err: types.ErrInProgress{Key: "abc"}}
+	storage.MockStorage.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, types.ErrInProgress{Key: "abc"})
+	h := GrapherHandler{
+		LogProvider:        logevent.FromContext,
+		StatProvider:       xstats.FromContext,
+		Storage:            storage,
+		RedirectExpiration: time.Minute,
+	}
+	w := httptest.NewRecorder()
+	h.Get(w, newGraphRequest(""))
+	assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+}
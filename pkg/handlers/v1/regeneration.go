@@ -0,0 +1,49 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+// RegenerationSuffix is appended to the ID of a graph to name the record of its forced regenerations
+const RegenerationSuffix = ".regeneration.json"
+
+// regeneration records how many times a graph was regenerated, and when the latest regeneration was requested
+type regeneration struct {
+	GraphID   string    `json:"graphID"`
+	Count     int       `json:"count"`
+	Requested time.Time `json:"requested"`
+}
+
+// recordRegeneration increments the regeneration count of a graph. The record is updated while holding its
+// lock, so that concurrent regenerations are all counted.
+func recordRegeneration(ctx context.Context, lock *storage.Lock, store types.Storage, id string, now time.Time) error {
+	key := id + RegenerationSuffix
+	return lock.Do(ctx, key, func() error {
+		record := regeneration{GraphID: id}
+		stored, err := store.Get(ctx, key)
+		switch err.(type) {
+		case nil:
+			defer stored.Close()
+			if err := json.NewDecoder(stored).Decode(&record); err != nil {
+				return err
+			}
+		case types.ErrNotFound:
+		default:
+			return err
+		}
+		record.Count++
+		record.Requested = now.UTC()
+		b, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		return store.Store(ctx, key, ioutil.NopCloser(bytes.NewReader(b)))
+	})
+}
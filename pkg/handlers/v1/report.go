@@ -0,0 +1,49 @@
+package v1
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/logs"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+// Report is a handler which returns a JSON document stored alongside a graph, such as the summary
+// written by an annotator. The document is stored under the graph ID followed by Suffix.
+type Report struct {
+	LogProvider  types.LogFn
+	StatProvider types.StatFn
+	Storage      types.Storage
+	Suffix       string
+}
+
+// ServeHTTP handles incoming HTTP requests, and returns the report of the graph identified either by
+// the id query parameter, or by the start/stop window
+func (h *Report) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := h.LogProvider(r.Context())
+	id, _, _, err := extractGraph(r)
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	body, err := h.Storage.Get(r.Context(), id+h.Suffix)
+	switch err.(type) {
+	case nil:
+		defer body.Close()
+	case types.ErrInProgress:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case types.ErrNotFound:
+		logger.Info(logs.NotFound{Reason: err.Error()})
+		w.WriteHeader(http.StatusNotFound)
+		return
+	default:
+		logger.Error(logs.DependencyFailure{Dependency: logs.DependencyStorage, Reason: err.Error()})
+		writeJSONResponse(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, body)
+}
@@ -0,0 +1,66 @@
+package v1
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/logevent"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/rs/xstats"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportBadRequest(t *testing.T) {
+	w := httptest.NewRecorder()
+	h := &Report{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Suffix: ".baseline.json"}
+	h.ServeHTTP(w, newAnalysisRequest("/baseline", map[string]string{"start": "invalid ts"}))
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestReportStorageErrors(t *testing.T) {
+	tc := []struct {
+		Name     string
+		Err      error
+		Expected int
+	}{
+		{"in_progress", types.ErrInProgress{}, http.StatusNoContent},
+		{"not_found", types.ErrNotFound{}, http.StatusNotFound},
+		{"unknown", errors.New("oops"), http.StatusInternalServerError},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			storage := NewMockStorage(ctrl)
+			storage.EXPECT().Get(gomock.Any(), testID+".baseline.json").Return(nil, tt.Err)
+			w := httptest.NewRecorder()
+			h := &Report{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Storage: storage, Suffix: ".baseline.json"}
+			h.ServeHTTP(w, newAnalysisRequest("/baseline", map[string]string{"id": testID}))
+			assert.Equal(t, tt.Expected, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestReportByWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(time.Hour)
+	storage := NewMockStorage(ctrl)
+	storage.EXPECT().Get(gomock.Any(), graph.ID(start, stop)+".baseline.json").Return(ioutil.NopCloser(bytes.NewReader([]byte(`{"graphID":"abc"}`))), nil)
+	w := httptest.NewRecorder()
+	h := &Report{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Storage: storage, Suffix: ".baseline.json"}
+	h.ServeHTTP(w, newAnalysisRequest("/baseline", map[string]string{"start": start.Format(time.RFC3339Nano), "stop": stop.Format(time.RFC3339Nano)}))
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, "application/json", w.Result().Header.Get("Content-Type"))
+	assert.Equal(t, `{"graphID":"abc"}`, w.Body.String())
+}
@@ -0,0 +1,105 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/logs"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+const (
+	suggestionsFormatJSON      = "json"
+	suggestionsFormatTerraform = "terraform"
+	defaultSecurityGroupName   = "suggested"
+)
+
+// suggestionsResponse is the JSON body returned by the Suggestions handler
+type suggestionsResponse struct {
+	GraphID      string              `json:"graphID"`
+	Destinations []string            `json:"destinations"`
+	Rules        []graph.IngressRule `json:"rules"`
+}
+
+// Suggestions is a handler which derives security group ingress rules from the accepted traffic observed
+// into a set of destination networks
+type Suggestions struct {
+	LogProvider  types.LogFn
+	StatProvider types.StatFn
+	Source       types.GraphSource
+}
+
+// ServeHTTP handles incoming HTTP requests, and returns the minimal ingress rules covering every accepted
+// flow into the comma separated destination networks, either as JSON or as a Terraform snippet. The graph
+// is identified either by the start/stop window or by the id of a stored graph.
+func (h *Suggestions) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := h.LogProvider(r.Context())
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = suggestionsFormatJSON
+	}
+	if format != suggestionsFormatJSON && format != suggestionsFormatTerraform {
+		err := fmt.Errorf("unsupported format %q", format)
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	destinations, err := extractNetworks(r, "destination")
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id, start, stop, err := extractGraph(r)
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	g, err := h.Source.Load(r.Context(), id, start, stop)
+	if err != nil {
+		writeSourceError(w, logger, err)
+		return
+	}
+	rules := graph.SuggestIngress(g, destinations)
+	if format == suggestionsFormatTerraform {
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			name = defaultSecurityGroupName
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		_ = graph.EncodeTerraform(w, name, rules)
+		return
+	}
+	response := suggestionsResponse{GraphID: id, Rules: rules}
+	for _, d := range destinations {
+		response.Destinations = append(response.Destinations, d.String())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// extractNetworks parses the comma separated addresses and CIDR blocks of the given query parameter
+func extractNetworks(r *http.Request, param string) ([]*net.IPNet, error) {
+	value := r.URL.Query().Get(param)
+	if value == "" {
+		return nil, errors.New(param + " is required")
+	}
+	var networks []*net.IPNet
+	for _, s := range strings.Split(value, ",") {
+		network, err := graph.ParseNetwork(strings.TrimSpace(s))
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
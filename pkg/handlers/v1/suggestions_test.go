@@ -0,0 +1,97 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/logevent"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/golang/mock/gomock"
+	"github.com/rs/xstats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestionsBadRequest(t *testing.T) {
+	tc := []struct {
+		Name   string
+		Params map[string]string
+	}{
+		{
+			Name:   "bad_format",
+			Params: map[string]string{"id": testID, "destination": "10.0.0.2", "format": "cloudformation"},
+		},
+		{
+			Name:   "missing_destination",
+			Params: map[string]string{"id": testID},
+		},
+		{
+			Name:   "bad_destination",
+			Params: map[string]string{"id": testID, "destination": "10.0.0.0/24,nope"},
+		},
+		{
+			Name:   "bad_window",
+			Params: map[string]string{"destination": "10.0.0.2", "start": "invalid ts"},
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h := &Suggestions{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext}
+			h.ServeHTTP(w, newAnalysisRequest("/suggestions", tt.Params))
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestSuggestionsJSON(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(time.Hour)
+	mockSource := NewMockGraphSource(ctrl)
+	mockSource.EXPECT().Load(gomock.Any(), graph.ID(start, stop), start, stop).Return(newTestGraph(443, 444), nil)
+
+	w := httptest.NewRecorder()
+	h := &Suggestions{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Source: mockSource}
+	h.ServeHTTP(w, newAnalysisRequest("/suggestions", map[string]string{
+		"start":       start.Format(time.RFC3339Nano),
+		"stop":        stop.Format(time.RFC3339Nano),
+		"destination": "10.0.0.2/32",
+	}))
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, "application/json", w.Result().Header.Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `"rules":[{"protocol":"tcp","fromPort":443,"toPort":444,"cidrBlocks":["10.0.0.1/32"],"flows":2,"bytes":200}]`)
+}
+
+func TestSuggestionsTerraform(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSource := NewMockGraphSource(ctrl)
+	mockSource.EXPECT().Load(gomock.Any(), testID, time.Time{}, time.Time{}).Return(newTestGraph(443), nil)
+
+	w := httptest.NewRecorder()
+	h := &Suggestions{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Source: mockSource}
+	h.ServeHTTP(w, newAnalysisRequest("/suggestions", map[string]string{"id": testID, "destination": "10.0.0.2", "format": "terraform", "name": "db"}))
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Contains(t, w.Body.String(), `resource "aws_security_group" "db" {`)
+	assert.Contains(t, w.Body.String(), `cidr_blocks      = ["10.0.0.1/32"]`)
+}
+
+func TestSuggestionsSourceError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSource := NewMockGraphSource(ctrl)
+	mockSource.EXPECT().Load(gomock.Any(), testID, gomock.Any(), gomock.Any()).Return(nil, errors.New("oops"))
+
+	w := httptest.NewRecorder()
+	h := &Suggestions{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Source: mockSource}
+	h.ServeHTTP(w, newAnalysisRequest("/suggestions", map[string]string{"id": testID, "destination": "10.0.0.2"}))
+	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+}
@@ -0,0 +1,153 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/logs"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/parallel"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+const (
+	defaultTemporalInterval = 5 * time.Minute
+	maxTemporalIntervals    = 48
+	temporalConcurrency     = 4
+)
+
+// Temporal is a handler which builds a graph of a window of time which keeps track of when each edge was active
+type Temporal struct {
+	LogProvider  types.LogFn
+	StatProvider types.StatFn
+	Source       types.GraphSource
+	// Storage, Queuer and Marker queue the graphs of the intervals which have not been created yet
+	Storage types.Storage
+	Queuer  types.Queuer
+	Marker  types.Marker
+}
+
+// ServeHTTP handles incoming HTTP requests, and returns a temporal graph of the start/stop window. The window
+// is split into intervals of the given number of minutes, and the stored graphs of the intervals are loaded
+// from the Source, temporalConcurrency at a time, so that every edge lists the bytes and packets it carried in
+// each interval during which it was active. Intervals are never digested on demand: when the graph of any of
+// them has not been created yet, the missing graphs are queued instead, and their batch status is returned
+// with a 202, so that the request may be retried once they are created.
+func (h *Temporal) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := h.LogProvider(r.Context())
+	format, err := graph.LookupTemporalFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	start, stop, err := extractInput(r)
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	interval, err := extractInterval(r, start, stop)
+	if err != nil {
+		logger.Info(logs.InvalidInput{Reason: err.Error()})
+		writeJSONResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	t := graph.NewTemporal(start, stop, interval)
+	intervals := t.Intervals()
+	graphs, missing, err := h.load(r.Context(), intervals)
+	if err != nil {
+		writeSourceError(w, logger, err)
+		return
+	}
+	if len(missing) > 0 {
+		res := batchResponse{Results: h.queueMissing(r.Context(), intervals, missing)}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(res)
+		return
+	}
+	for idx, i := range intervals {
+		t.Add(i[0], i[1], graphs[idx])
+	}
+	w.Header().Set("Content-Type", format.ContentType)
+	w.WriteHeader(http.StatusOK)
+	_ = format.Encoder(w, t)
+}
+
+// load returns the stored graph of each of the intervals, in the same order, along with the indexes of the
+// intervals whose graph does not exist. The remaining intervals are abandoned as soon as one of them fails.
+func (h *Temporal) load(ctx context.Context, intervals [][2]time.Time) ([]*graph.Graph, []int, error) {
+	graphs := make([]*graph.Graph, len(intervals))
+	found := make([]bool, len(intervals))
+	err := parallel.ForEach(ctx, len(intervals), temporalConcurrency, func(ctx context.Context, idx int) error {
+		start, stop := intervals[idx][0], intervals[idx][1]
+		// zero times only load the stored graph, rather than digesting the interval
+		g, err := h.Source.Load(ctx, graph.ID(start, stop), time.Time{}, time.Time{})
+		switch err.(type) {
+		case nil:
+			graphs[idx], found[idx] = g, true
+		case types.ErrNotFound:
+		default:
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	var missing []int
+	for idx := range intervals {
+		if !found[idx] {
+			missing = append(missing, idx)
+		}
+	}
+	return graphs, missing, nil
+}
+
+// queueMissing queues the graphs of the missing intervals, unless they are in progress, and returns their
+// batch results in order
+func (h *Temporal) queueMissing(ctx context.Context, intervals [][2]time.Time, missing []int) []batchResult {
+	logger := h.LogProvider(ctx)
+	results := make([]batchResult, 0, len(missing))
+	for _, idx := range missing {
+		start, stop := intervals[idx][0], intervals[idx][1]
+		result := batchResult{ID: graph.ID(start, stop), Start: start, Stop: stop}
+		var err error
+		result.Status, err = queueGraph(ctx, logger, h.Storage, h.Queuer, h.Marker, result.ID, start, stop)
+		if err != nil {
+			logger.Error(logs.DependencyFailure{Dependency: logs.DependencyQueuer, Reason: err.Error()})
+			result.Message = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// extractInterval returns the interval query parameter, in minutes, which must split the window into at
+// most maxTemporalIntervals intervals
+func extractInterval(r *http.Request, start, stop time.Time) (time.Duration, error) {
+	interval := defaultTemporalInterval
+	if intervalStr := r.URL.Query().Get("interval"); intervalStr != "" {
+		minutes, err := strconv.Atoi(intervalStr)
+		if err != nil {
+			return 0, err
+		}
+		if minutes < 1 {
+			return 0, fmt.Errorf("interval should be at least 1 minute")
+		}
+		interval = time.Duration(minutes) * time.Minute
+	}
+	if !stop.After(start) {
+		return 0, fmt.Errorf("start should be before stop")
+	}
+	if intervals := (stop.Sub(start) + interval - 1) / interval; intervals > maxTemporalIntervals {
+		return 0, fmt.Errorf("the window spans %d intervals, at most %d are allowed", intervals, maxTemporalIntervals)
+	}
+	return interval, nil
+}
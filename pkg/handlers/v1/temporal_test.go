@@ -0,0 +1,167 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/logevent"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/rs/xstats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemporalBadRequest(t *testing.T) {
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	tc := []struct {
+		Name   string
+		Params map[string]string
+	}{
+		{
+			Name:   "bad_format",
+			Params: map[string]string{"start": start.Format(time.RFC3339Nano), "stop": start.Add(time.Hour).Format(time.RFC3339Nano), "format": "dot"},
+		},
+		{
+			Name:   "missing_window",
+			Params: map[string]string{"id": testID},
+		},
+		{
+			Name:   "empty_window",
+			Params: map[string]string{"start": start.Format(time.RFC3339Nano), "stop": start.Format(time.RFC3339Nano)},
+		},
+		{
+			Name:   "bad_interval",
+			Params: map[string]string{"start": start.Format(time.RFC3339Nano), "stop": start.Add(time.Hour).Format(time.RFC3339Nano), "interval": "five"},
+		},
+		{
+			Name:   "zero_interval",
+			Params: map[string]string{"start": start.Format(time.RFC3339Nano), "stop": start.Add(time.Hour).Format(time.RFC3339Nano), "interval": "0"},
+		},
+		{
+			Name:   "too_many_intervals",
+			Params: map[string]string{"start": start.Format(time.RFC3339Nano), "stop": start.Add(48 * time.Hour).Format(time.RFC3339Nano)},
+		},
+		{
+			Name:   "too_many_default_intervals",
+			Params: map[string]string{"start": start.Format(time.RFC3339Nano), "stop": start.Add(5 * time.Hour).Format(time.RFC3339Nano)},
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h := &Temporal{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext}
+			h.ServeHTTP(w, newAnalysisRequest("/temporal", tt.Params))
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestTemporalJSON(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	middle := start.Add(10 * time.Minute)
+	stop := start.Add(15 * time.Minute)
+	mockSource := NewMockGraphSource(ctrl)
+	mockSource.EXPECT().Load(gomock.Any(), graph.ID(start, middle), time.Time{}, time.Time{}).Return(newTestGraph(443), nil)
+	mockSource.EXPECT().Load(gomock.Any(), graph.ID(middle, stop), time.Time{}, time.Time{}).Return(newTestGraph(443, 22), nil)
+
+	w := httptest.NewRecorder()
+	h := &Temporal{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Source: mockSource}
+	h.ServeHTTP(w, newAnalysisRequest("/temporal", map[string]string{
+		"start":    start.Format(time.RFC3339Nano),
+		"stop":     stop.Format(time.RFC3339Nano),
+		"interval": "10",
+	}))
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, "application/json", w.Result().Header.Get("Content-Type"))
+	var doc struct {
+		Edges []graph.TemporalEdge `json:"edges"`
+	}
+	require.Nil(t, json.NewDecoder(w.Body).Decode(&doc))
+	require.Len(t, doc.Edges, 2)
+	assert.Len(t, doc.Edges[0].Activity, 2)
+	assert.Len(t, doc.Edges[1].Activity, 1)
+	assert.Equal(t, middle, doc.Edges[1].Activity[0].Start)
+}
+
+func TestTemporalGEXF(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	mockSource := NewMockGraphSource(ctrl)
+	mockSource.EXPECT().Load(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(newTestGraph(443), nil).Times(12)
+
+	w := httptest.NewRecorder()
+	h := &Temporal{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Source: mockSource}
+	h.ServeHTTP(w, newAnalysisRequest("/temporal", map[string]string{
+		"start":  start.Format(time.RFC3339Nano),
+		"stop":   start.Add(time.Hour).Format(time.RFC3339Nano),
+		"format": "gexf",
+	}))
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, "application/xml", w.Result().Header.Get("Content-Type"))
+	assert.True(t, strings.Contains(w.Body.String(), `<spell start="2019-05-01T00:00:00Z" end="2019-05-01T01:00:00Z"></spell>`))
+}
+
+func TestTemporalSourceError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	mockSource := NewMockGraphSource(ctrl)
+	// the intervals loaded at the same time as the failed one may complete
+	mockSource.EXPECT().Load(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("oops")).MinTimes(1).MaxTimes(temporalConcurrency)
+
+	w := httptest.NewRecorder()
+	h := &Temporal{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Source: mockSource}
+	h.ServeHTTP(w, newAnalysisRequest("/temporal", map[string]string{
+		"start": start.Format(time.RFC3339Nano),
+		"stop":  start.Add(time.Hour).Format(time.RFC3339Nano),
+	}))
+	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+}
+
+func TestTemporalQueuesMissing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	first, second, stop := start.Add(10*time.Minute), start.Add(20*time.Minute), start.Add(30*time.Minute)
+	mockSource := NewMockGraphSource(ctrl)
+	mockSource.EXPECT().Load(gomock.Any(), graph.ID(start, first), time.Time{}, time.Time{}).Return(newTestGraph(443), nil)
+	mockSource.EXPECT().Load(gomock.Any(), graph.ID(first, second), time.Time{}, time.Time{}).Return(nil, types.ErrNotFound{})
+	mockSource.EXPECT().Load(gomock.Any(), graph.ID(second, stop), time.Time{}, time.Time{}).Return(nil, types.ErrNotFound{})
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Exists(gomock.Any(), graph.ID(first, second)).Return(false, nil)
+	mockStorage.EXPECT().Exists(gomock.Any(), graph.ID(second, stop)).Return(false, types.ErrInProgress{})
+	// only the graph which is neither stored nor in progress is queued, and no interval is digested on demand
+	mockQueuer := NewMockQueuer(ctrl)
+	mockQueuer.EXPECT().Queue(gomock.Any(), graph.ID(first, second), first, second).Return(nil)
+	mockMarker := NewMockMarker(ctrl)
+	mockMarker.EXPECT().Mark(gomock.Any(), graph.ID(first, second)).Return(nil)
+
+	w := httptest.NewRecorder()
+	h := &Temporal{LogProvider: logevent.FromContext, StatProvider: xstats.FromContext, Source: mockSource, Storage: mockStorage, Queuer: mockQueuer, Marker: mockMarker}
+	h.ServeHTTP(w, newAnalysisRequest("/temporal", map[string]string{
+		"start":    start.Format(time.RFC3339Nano),
+		"stop":     stop.Format(time.RFC3339Nano),
+		"interval": "10",
+	}))
+	require.Equal(t, http.StatusAccepted, w.Result().StatusCode)
+	var res batchResponse
+	require.Nil(t, json.NewDecoder(w.Body).Decode(&res))
+	require.Len(t, res.Results, 2)
+	assert.Equal(t, batchQueued, res.Results[0].Status)
+	assert.Equal(t, batchInProgress, res.Results[1].Status)
+	assert.Equal(t, second, res.Results[1].Start)
+}
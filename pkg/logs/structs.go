@@ -15,6 +15,15 @@ const (
 
 	// DependencyGrapher identifies a grapher failure
 	DependencyGrapher = "grapher"
+
+	// DependencySource identifies a graph source failure
+	DependencySource = "source"
+
+	// DependencyNotifier identifies a notifier failure
+	DependencyNotifier = "notifier"
+
+	// DependencyScheduler identifies a scheduler failure
+	DependencyScheduler = "scheduler"
 )
 
 // DependencyFailure is logged when a downstream dependency fails
@@ -47,3 +56,36 @@ type Conflict struct {
 	Reason  string `logevent:"reason"`
 	Message string `logevent:"message,default=conflict"`
 }
+
+// ScheduledGraph is logged when the scheduler queues a graph
+type ScheduledGraph struct {
+	ID       string `logevent:"id"`
+	Schedule string `logevent:"schedule"`
+	Start    string `logevent:"start"`
+	Stop     string `logevent:"stop"`
+	Message  string `logevent:"message,default=scheduled-graph"`
+}
+
+// DeletedGraph is logged when a graph is deleted through the API
+type DeletedGraph struct {
+	ID      string `logevent:"id"`
+	Message string `logevent:"message,default=deleted-graph"`
+}
+
+// ExpiredGraph is logged when the retention job deletes a graph, or would delete it in dry run mode
+type ExpiredGraph struct {
+	ID      string `logevent:"id"`
+	Start   string `logevent:"start"`
+	Stop    string `logevent:"stop"`
+	DryRun  bool   `logevent:"dry_run"`
+	Message string `logevent:"message,default=expired-graph"`
+}
+
+// RetentionRun is logged after each run of the retention job
+type RetentionRun struct {
+	Expired       int    `logevent:"expired"`
+	Failed        int    `logevent:"failed"`
+	ExpiredStates int    `logevent:"expired_states"`
+	DryRun        bool   `logevent:"dry_run"`
+	Message       string `logevent:"message,default=retention-run"`
+}
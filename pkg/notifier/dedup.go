@@ -0,0 +1,123 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+const (
+	// DefaultDedupWindow is the default period during which a finding is only sent once
+	DefaultDedupWindow = 24 * time.Hour
+
+	// NotifiedPrefix is the prefix of the keys recording when each finding was last sent in Storage
+	NotifiedPrefix = "notified/"
+
+	// dedupLockKey is the key of the lock held while findings are checked, sent and recorded. It is kept out of
+	// NotifiedPrefix, whose objects are expired by the retention job.
+	dedupLockKey = "notified"
+)
+
+// Dedup is a Notifier which decorates another Notifier, and drops findings whose key was already
+// sent within Window. The time each key was last sent is recorded in Storage, so repeated findings
+// made in overlapping windows are only sent once. Findings are checked, sent and recorded while holding
+// Lock, so that replicas sharing Storage do not send the same finding concurrently.
+//
+// Findings are recorded as sent once they were delivered to any endpoint, so that a single failing
+// endpoint of a Webhook does not cause them to be sent again to the others.
+type Dedup struct {
+	Storage types.Storage
+	Window  time.Duration
+	// Lock serializes the notifications across replicas. Defaults to a storage.Lock of Storage.
+	Lock *storage.Lock
+	types.Notifier
+}
+
+// Notify sends the findings which were not sent recently, then records them as sent
+func (n *Dedup) Notify(ctx context.Context, findings []types.Finding) error {
+	return n.lock().Do(ctx, dedupLockKey, func() error {
+		return n.notify(ctx, findings)
+	})
+}
+
+func (n *Dedup) notify(ctx context.Context, findings []types.Finding) error {
+	window := n.Window
+	if window <= 0 {
+		window = DefaultDedupWindow
+	}
+	now := time.Now()
+	var pending []types.Finding
+	seen := make(map[string]bool)
+	for _, f := range findings {
+		if seen[f.Key] {
+			continue
+		}
+		seen[f.Key] = true
+		sent, err := n.lastSent(ctx, f.Key)
+		if err != nil {
+			return err
+		}
+		if !sent.IsZero() && now.Sub(sent) < window {
+			continue
+		}
+		pending = append(pending, f)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	notifyErr := n.Notifier.Notify(ctx, pending)
+	if delivery, ok := notifyErr.(ErrDelivery); notifyErr != nil && (!ok || delivery.Delivered == 0) {
+		return notifyErr
+	}
+	for _, f := range pending {
+		ts := []byte(now.Format(time.RFC3339Nano))
+		if err := n.Storage.Store(ctx, notifiedKey(f.Key), ioutil.NopCloser(bytes.NewReader(ts))); err != nil {
+			return err
+		}
+	}
+	return notifyErr
+}
+
+func (n *Dedup) lock() *storage.Lock {
+	if n.Lock == nil {
+		return &storage.Lock{Storage: n.Storage}
+	}
+	return n.Lock
+}
+
+// lastSent returns the time the finding key was last sent, or the zero time if it never was
+func (n *Dedup) lastSent(ctx context.Context, key string) (time.Time, error) {
+	ts, err := NotifiedTime(ctx, n.Storage, notifiedKey(key))
+	if _, ok := err.(types.ErrNotFound); ok {
+		return time.Time{}, nil
+	}
+	return ts, err
+}
+
+// NotifiedTime returns the time recorded under a key of NotifiedPrefix, at which a finding was last sent.
+// An unreadable time is returned as the zero time.
+func NotifiedTime(ctx context.Context, storage types.Storage, key string) (time.Time, error) {
+	r, err := storage.Get(ctx, key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return time.Time{}, err
+	}
+	ts, _ := time.Parse(time.RFC3339Nano, string(b))
+	return ts, nil
+}
+
+// notifiedKey hashes the finding key, which may contain characters unsuitable for storage keys
+func notifiedKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return NotifiedPrefix + hex.EncodeToString(sum[:]) + ".txt"
+}
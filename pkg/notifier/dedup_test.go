@@ -0,0 +1,126 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage/storagetest"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// testLock returns a lock kept apart from the mocked storage
+func testLock() *storage.Lock {
+	return &storage.Lock{Storage: storagetest.NewMemory(), Wait: time.Millisecond, Settle: time.Millisecond}
+}
+
+func TestDedupSendsNewFindings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	findings := append(testFindings, testFindings[0])
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Get(gomock.Any(), notifiedKey(testFindings[0].Key)).Return(nil, types.ErrNotFound{})
+	mockStorage.EXPECT().Store(gomock.Any(), notifiedKey(testFindings[0].Key), gomock.Any()).Return(nil)
+	mockNotifier := NewMockNotifier(ctrl)
+	mockNotifier.EXPECT().Notify(gomock.Any(), testFindings).Return(nil)
+
+	n := &Dedup{Storage: mockStorage, Notifier: mockNotifier, Lock: testLock()}
+	assert.Nil(t, n.Notify(context.Background(), findings))
+}
+
+func TestDedupDropsRecentFindings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sent := []byte(time.Now().Add(-time.Hour).Format(time.RFC3339Nano))
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Get(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(bytes.NewReader(sent)), nil)
+	mockNotifier := NewMockNotifier(ctrl)
+
+	n := &Dedup{Storage: mockStorage, Notifier: mockNotifier, Lock: testLock()}
+	assert.Nil(t, n.Notify(context.Background(), testFindings))
+}
+
+func TestDedupResendsExpiredFindings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sent := []byte(time.Now().Add(-2 * time.Hour).Format(time.RFC3339Nano))
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Get(gomock.Any(), gomock.Any()).Return(ioutil.NopCloser(bytes.NewReader(sent)), nil)
+	mockStorage.EXPECT().Store(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	mockNotifier := NewMockNotifier(ctrl)
+	mockNotifier.EXPECT().Notify(gomock.Any(), testFindings).Return(nil)
+
+	n := &Dedup{Storage: mockStorage, Notifier: mockNotifier, Window: time.Hour, Lock: testLock()}
+	assert.Nil(t, n.Notify(context.Background(), testFindings))
+}
+
+func TestDedupErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := NewMockStorage(ctrl)
+	mockNotifier := NewMockNotifier(ctrl)
+	n := &Dedup{Storage: mockStorage, Notifier: mockNotifier, Lock: testLock()}
+
+	mockStorage.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, errors.New("oops"))
+	assert.NotNil(t, n.Notify(context.Background(), testFindings))
+
+	mockStorage.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, types.ErrNotFound{})
+	mockNotifier.EXPECT().Notify(gomock.Any(), gomock.Any()).Return(errors.New("oops"))
+	assert.NotNil(t, n.Notify(context.Background(), testFindings))
+}
+
+func TestDedupPartialDelivery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := NewMockStorage(ctrl)
+	mockNotifier := NewMockNotifier(ctrl)
+	n := &Dedup{Storage: mockStorage, Notifier: mockNotifier, Lock: testLock()}
+
+	// findings delivered to some of the endpoints are recorded as sent
+	mockStorage.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, types.ErrNotFound{})
+	mockNotifier.EXPECT().Notify(gomock.Any(), testFindings).Return(ErrDelivery{Failures: []string{"oops"}, Delivered: 1})
+	mockStorage.EXPECT().Store(gomock.Any(), notifiedKey(testFindings[0].Key), gomock.Any()).Return(nil)
+	assert.NotNil(t, n.Notify(context.Background(), testFindings))
+
+	// findings delivered to none of the endpoints are not
+	mockStorage.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, types.ErrNotFound{})
+	mockNotifier.EXPECT().Notify(gomock.Any(), testFindings).Return(ErrDelivery{Failures: []string{"oops"}})
+	assert.NotNil(t, n.Notify(context.Background(), testFindings))
+}
+
+func TestDedupReplicas(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	memory := storagetest.NewMemory()
+	// the finding is sent by a single replica
+	mockNotifier := NewMockNotifier(ctrl)
+	mockNotifier.EXPECT().Notify(gomock.Any(), testFindings).Return(nil)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		n := &Dedup{Storage: memory, Notifier: mockNotifier, Lock: &storage.Lock{Storage: memory, Wait: time.Millisecond, Settle: time.Millisecond}}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = n.Notify(context.Background(), testFindings)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, []error{nil, nil}, errs)
+	_, sent := memory.Objects[notifiedKey(testFindings[0].Key)]
+	assert.True(t, sent)
+}
@@ -0,0 +1,4 @@
+// Package notifier contains the built in types.Notifier implementations which push the findings
+// of each graph to external systems.
+//
+package notifier
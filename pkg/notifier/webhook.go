@@ -0,0 +1,91 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+// SignatureHeader is the request header holding the HMAC-SHA256 signature of the webhook body
+const SignatureHeader = "X-Grapherd-Signature"
+
+// ErrDelivery is returned by Webhook when the findings could not be sent to some of the endpoints
+type ErrDelivery struct {
+	Failures  []string
+	Delivered int
+}
+
+func (e ErrDelivery) Error() string {
+	return fmt.Sprintf("unable to notify webhooks: %s", strings.Join(e.Failures, "; "))
+}
+
+type payload struct {
+	Findings []types.Finding `json:"findings"`
+}
+
+// Webhook is a Notifier which POSTs findings as JSON to each of the configured endpoints. If a Secret
+// is provided, the body is signed using HMAC-SHA256 and the hex encoded signature is sent in the
+// SignatureHeader as sha256=<signature>. Retries are left to the transport of the Client.
+type Webhook struct {
+	Endpoints []*url.URL
+	Client    *http.Client
+	Secret    []byte
+}
+
+// Notify sends the findings to every endpoint. All endpoints are attempted even if one of them fails, in which
+// case an error of type ErrDelivery is returned.
+func (n *Webhook) Notify(ctx context.Context, findings []types.Finding) error {
+	if len(findings) == 0 {
+		return nil
+	}
+	rawBody, err := json.Marshal(payload{Findings: findings})
+	if err != nil {
+		return err
+	}
+	var failures []string
+	for _, endpoint := range n.Endpoints {
+		if err := n.post(ctx, endpoint, rawBody); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return ErrDelivery{Failures: failures, Delivered: len(n.Endpoints) - len(failures)}
+	}
+	return nil
+}
+
+func (n *Webhook) post(ctx context.Context, endpoint *url.URL, rawBody []byte) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint.String(), bytes.NewReader(rawBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.Secret) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(n.Secret, rawBody))
+	}
+	res, err := n.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected response from %s: %d", endpoint.Host, res.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body using secret
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
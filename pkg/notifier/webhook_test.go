@@ -0,0 +1,60 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+var testFindings = []types.Finding{{Key: "new-edge|123|a|b|443|6", Kind: "new-edge", GraphID: "abc"}}
+
+func TestWebhookSigned(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRT := NewMockRoundTripper(ctrl)
+	mockRT.EXPECT().RoundTrip(gomock.Any()).DoAndReturn(func(r *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "sha256="+Sign([]byte("secret"), body), r.Header.Get(SignatureHeader))
+		assert.Contains(t, string(body), `"kind":"new-edge"`)
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(nil)}, nil
+	})
+	endpoint, _ := url.Parse("http://some.host/hook")
+	n := &Webhook{Endpoints: []*url.URL{endpoint}, Client: &http.Client{Transport: mockRT}, Secret: []byte("secret")}
+	assert.Nil(t, n.Notify(context.Background(), testFindings))
+}
+
+func TestWebhookNoFindings(t *testing.T) {
+	n := &Webhook{}
+	assert.Nil(t, n.Notify(context.Background(), nil))
+}
+
+func TestWebhookFailures(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRT := NewMockRoundTripper(ctrl)
+	mockRT.EXPECT().RoundTrip(gomock.Any()).Return(&http.Response{StatusCode: http.StatusInternalServerError, Body: ioutil.NopCloser(nil)}, nil)
+	mockRT.EXPECT().RoundTrip(gomock.Any()).Return(nil, errors.New("oops"))
+	mockRT.EXPECT().RoundTrip(gomock.Any()).DoAndReturn(func(r *http.Request) (*http.Response, error) {
+		assert.Empty(t, r.Header.Get(SignatureHeader))
+		return &http.Response{StatusCode: http.StatusAccepted, Body: ioutil.NopCloser(nil)}, nil
+	})
+	first, _ := url.Parse("http://first.host/hook")
+	second, _ := url.Parse("http://second.host/hook")
+	third, _ := url.Parse("http://third.host/hook")
+	n := &Webhook{Endpoints: []*url.URL{first, second, third}, Client: &http.Client{Transport: mockRT}}
+	err := n.Notify(context.Background(), testFindings)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "first.host: 500")
+	assert.Len(t, err.(ErrDelivery).Failures, 2)
+	assert.Equal(t, 1, err.(ErrDelivery).Delivered)
+}
@@ -0,0 +1,3 @@
+// Package parallel contains the bounded worker pool shared by the handlers, digesters and commands which
+// process the windows of a range concurrently.
+package parallel
@@ -0,0 +1,49 @@
+package parallel
+
+import (
+	"context"
+	"sync"
+)
+
+// ForEach calls fn with each index from 0 to n, concurrency indexes at a time. The remaining indexes are
+// abandoned as soon as fn fails or the context is done, and the error of the first failed index is returned,
+// or else the error of the context. fn is called with a context which is cancelled once any index fails.
+func ForEach(ctx context.Context, n, concurrency int, fn func(ctx context.Context, idx int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	pending := make(chan int)
+	for i := 0; i < concurrency && i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range pending {
+				if ctx.Err() != nil {
+					continue
+				}
+				if errs[idx] = fn(ctx, idx); errs[idx] != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+feed:
+	for idx := 0; idx < n; idx++ {
+		select {
+		case pending <- idx:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(pending)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
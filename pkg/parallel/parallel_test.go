@@ -0,0 +1,60 @@
+package parallel
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForEach(t *testing.T) {
+	var running, maxRunning int32
+	done := make([]bool, 10)
+	err := ForEach(context.Background(), len(done), 3, func(_ context.Context, idx int) error {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			seen := atomic.LoadInt32(&maxRunning)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxRunning, seen, current) {
+				break
+			}
+		}
+		done[idx] = true
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{true, true, true, true, true, true, true, true, true, true}, done)
+	assert.True(t, maxRunning <= 3)
+}
+
+func TestForEachFailure(t *testing.T) {
+	var calls int32
+	err := ForEach(context.Background(), 100, 2, func(ctx context.Context, idx int) error {
+		atomic.AddInt32(&calls, 1)
+		if idx == 1 {
+			return errors.New("oops")
+		}
+		<-ctx.Done()
+		return nil
+	})
+	assert.EqualError(t, err, "oops")
+	// the indexes fed after the failure are abandoned
+	assert.True(t, atomic.LoadInt32(&calls) <= 3)
+}
+
+func TestForEachCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := ForEach(ctx, 10, 2, func(context.Context, int) error {
+		return nil
+	})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestForEachEmpty(t *testing.T) {
+	assert.Nil(t, ForEach(context.Background(), 0, 2, func(context.Context, int) error {
+		return errors.New("oops")
+	}))
+}
@@ -8,12 +8,15 @@ import (
 	"net/http"
 	"net/url"
 	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
 )
 
 type payload struct {
 	ID    string `json:"id"`
 	Start string `json:"start"`
 	Stop  string `json:"stop"`
+	Force bool   `json:"force,omitempty"`
 }
 
 // GraphQueuer is a Queuer implementation which queues graph jobs onto a streaming appliance
@@ -22,12 +25,14 @@ type GraphQueuer struct {
 	Client   *http.Client
 }
 
-// Queue enqueues a graph job onto a streaming appliance
+// Queue enqueues a graph job onto a streaming appliance. The job is flagged as forced when the context is,
+// see types.WithForce.
 func (q *GraphQueuer) Queue(ctx context.Context, id string, start, stop time.Time) error {
 	body := payload{
 		ID:    id,
 		Start: start.Format(time.RFC3339Nano),
 		Stop:  stop.Format(time.RFC3339Nano),
+		Force: types.ForceFromContext(ctx),
 	}
 	rawBody, _ := json.Marshal(body)
 	req, err := http.NewRequest(http.MethodPost, q.Endpoint.String(), bytes.NewReader(rawBody))
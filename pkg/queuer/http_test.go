@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
@@ -64,3 +65,23 @@ func TestRTError(t *testing.T) {
 	err := dq.Queue(context.Background(), "graphID", time.Now(), time.Now())
 	assert.NotNil(t, err)
 }
+
+func TestGraphQueuerForce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRT := NewMockRoundTripper(ctrl)
+	mockRT.EXPECT().RoundTrip(gomock.Any()).DoAndReturn(func(r *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Contains(t, string(body), `"force":true`)
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(nil)}, nil
+	})
+
+	endpoint, _ := url.Parse(endpoint)
+	client := &http.Client{Transport: mockRT}
+	dq := GraphQueuer{
+		Client:   client,
+		Endpoint: endpoint,
+	}
+	err := dq.Queue(types.WithForce(context.Background()), "graphID", time.Now(), time.Now())
+	assert.Nil(t, err)
+}
@@ -0,0 +1,4 @@
+// Package render contains the render command, which converts a saved digest into a graph locally, the same
+// way the service does, without storing it.
+//
+package render
@@ -0,0 +1,53 @@
+package render
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"strings"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+)
+
+// Options describe the digest to render, and how its graph is written
+type Options struct {
+	// Input is the path of the digest file. The digest is read from stdin when Input is empty or "-".
+	Input string
+	// Format is the output format of the graph, one of graph.Formats
+	Format string
+	// Countries filters the graph to the edges connected to a node located in one of the countries
+	Countries []string
+}
+
+// ParseArgs parses the command line arguments of the render command. Usage and parsing errors are
+// written to output.
+func ParseArgs(args []string, output io.Writer) (Options, error) {
+	var opts Options
+	var countries string
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.StringVar(&opts.Format, "format", graph.FormatDOT, "output format of the graph, dot or json")
+	flags.StringVar(&countries, "country", "", "comma separated countries to filter the graph edges by")
+	if err := flags.Parse(args); err != nil {
+		return Options{}, err
+	}
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		opts.Input = flags.Arg(0)
+	default:
+		return Options{}, errors.New("at most one digest file is allowed")
+	}
+	if _, err := graph.LookupFormat(opts.Format); err != nil {
+		return Options{}, err
+	}
+	if countries != "" {
+		opts.Countries = strings.Split(countries, ",")
+	}
+	return opts, nil
+}
+
+// Stdin returns true if the digest is read from stdin
+func (o Options) Stdin() bool {
+	return o.Input == "" || o.Input == "-"
+}
@@ -0,0 +1,46 @@
+package render
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseArgs(t *testing.T) {
+	opts, err := ParseArgs([]string{"-format", "json", "-country", "US,de", "digest.txt"}, ioutil.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, Options{Input: "digest.txt", Format: graph.FormatJSON, Countries: []string{"US", "de"}}, opts)
+	assert.False(t, opts.Stdin())
+}
+
+func TestParseArgsDefaults(t *testing.T) {
+	opts, err := ParseArgs(nil, ioutil.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, graph.FormatDOT, opts.Format)
+	assert.Nil(t, opts.Countries)
+	assert.True(t, opts.Stdin())
+
+	opts, err = ParseArgs([]string{"-"}, ioutil.Discard)
+	require.NoError(t, err)
+	assert.True(t, opts.Stdin())
+}
+
+func TestParseArgsInvalid(t *testing.T) {
+	tc := []struct {
+		Name string
+		Args []string
+	}{
+		{"bad_format", []string{"-format", "png"}},
+		{"unknown_flag", []string{"-start", "2019-05-01T00:00:00Z"}},
+		{"multiple_files", []string{"a.txt", "b.txt"}},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			_, err := ParseArgs(tt.Args, ioutil.Discard)
+			assert.Error(t, err)
+		})
+	}
+}
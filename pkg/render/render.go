@@ -0,0 +1,42 @@
+package render
+
+import (
+	"context"
+	"io"
+
+	"github.com/asecurityteam/go-vpcflow"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/grapher"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+// Render converts a digest into a graph with the same Converter and Enrichers as the service, and writes
+// it without storing it. Annotators are not run, since they depend on the graphs in storage.
+type Render struct {
+	Converter vpcflow.Converter
+	Enrichers []types.Enricher
+}
+
+// Run writes the graph of the digest to output, in the format of the options
+func (r *Render) Run(ctx context.Context, digest io.ReadCloser, opts Options, output io.Writer) error {
+	format, err := graph.LookupFormat(opts.Format)
+	if err != nil {
+		return err
+	}
+	converted, err := r.Converter(digest)
+	if err != nil {
+		return err
+	}
+	defer converted.Close()
+	g, err := graph.FromDOT(converted)
+	if err != nil {
+		return err
+	}
+	if err := grapher.Enrich(ctx, g, r.Enrichers); err != nil {
+		return err
+	}
+	if len(opts.Countries) > 0 {
+		g = graph.FilterByCountry(g, opts.Countries)
+	}
+	return format.Encoder(output, g)
+}
@@ -0,0 +1,67 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/asecurityteam/go-vpcflow"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const digest = `2 123456789010 eni-abc123de 172.31.16.139 172.31.16.21 0 80 6 20 1000 1418530010 1818530070 ACCEPT OK
+2 123456789010 eni-abc123de 172.31.16.139 52.95.110.1 0 443 6 40 2000 1418530010 1818530070 ACCEPT OK
+`
+
+func TestRenderDOT(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockEnricher := NewMockEnricher(ctrl)
+	mockEnricher.EXPECT().Enrich(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(map[string]string{"service": "payments"}, nil).Times(3)
+
+	var output bytes.Buffer
+	r := &Render{Converter: vpcflow.DOTConverter, Enrichers: []types.Enricher{mockEnricher}}
+	require.NoError(t, r.Run(context.Background(), ioutil.NopCloser(strings.NewReader(digest)), Options{Format: graph.FormatDOT}, &output))
+	assert.Contains(t, output.String(), `n1723116139 [label="172.31.16.139\nservice=payments" grapherd_service="payments"]`)
+
+	g, err := graph.FromDOT(&output)
+	require.NoError(t, err)
+	assert.Len(t, g.Edges, 2)
+}
+
+func TestRenderFilteredJSON(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockEnricher := NewMockEnricher(ctrl)
+	mockEnricher.EXPECT().Enrich(gomock.Any(), "52.95.110.1", gomock.Any(), gomock.Any()).Return(map[string]string{graph.AttrCountry: "US"}, nil)
+	mockEnricher.EXPECT().Enrich(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).Times(2)
+
+	var output bytes.Buffer
+	r := &Render{Converter: vpcflow.DOTConverter, Enrichers: []types.Enricher{mockEnricher}}
+	opts := Options{Format: graph.FormatJSON, Countries: []string{"us"}}
+	require.NoError(t, r.Run(context.Background(), ioutil.NopCloser(strings.NewReader(digest)), opts, &output))
+	assert.Contains(t, output.String(), `"to":"n52951101"`)
+	assert.NotContains(t, output.String(), `"to":"n172311621"`)
+}
+
+func TestRenderErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	r := &Render{Converter: vpcflow.DOTConverter}
+	assert.Error(t, r.Run(context.Background(), ioutil.NopCloser(strings.NewReader(digest)), Options{Format: "png"}, ioutil.Discard))
+
+	mockEnricher := NewMockEnricher(ctrl)
+	mockEnricher.EXPECT().Enrich(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("oops"))
+	r = &Render{Converter: vpcflow.DOTConverter, Enrichers: []types.Enricher{mockEnricher}}
+	assert.Error(t, r.Run(context.Background(), ioutil.NopCloser(strings.NewReader(digest)), Options{Format: graph.FormatDOT}, ioutil.Discard))
+}
@@ -0,0 +1,3 @@
+// Package retention contains the built in retention job, which deletes the graphs older than the maximum
+// age of their window size class, along with the state objects kept alongside them which are no longer needed.
+package retention
@@ -0,0 +1,62 @@
+package retention
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// anyWindow is the window of the policy applied to the graphs of every window which has no policy of its own
+const anyWindow = "*"
+
+// Policy is the maximum age of the graphs of a window size class. A graph expires once MaxAge has passed
+// since the end of its window.
+type Policy struct {
+	// Window is the duration of the windows of the class, or zero for the graphs of every window which has
+	// no policy of its own
+	Window time.Duration
+	MaxAge time.Duration
+}
+
+// class returns the name of the window size class of the policy
+func (p Policy) class() string {
+	if p.Window == 0 {
+		return anyWindow
+	}
+	return p.Window.String()
+}
+
+// ParsePolicies parses a comma separated list of policies of the form window=maxAge, where each duration is
+// formatted as a Go duration, such as 1h=720h,24h=8760h. A window of * applies to the graphs of every window
+// which has no policy of its own.
+func ParsePolicies(s string) ([]Policy, error) {
+	var policies []Policy
+	windows := make(map[time.Duration]bool)
+	for _, spec := range strings.Split(s, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid retention policy %q", spec)
+		}
+		var window time.Duration
+		if parts[0] != anyWindow {
+			var err error
+			if window, err = time.ParseDuration(parts[0]); err != nil || window <= 0 {
+				return nil, fmt.Errorf("invalid window in retention policy %q", spec)
+			}
+		}
+		maxAge, err := time.ParseDuration(parts[1])
+		if err != nil || maxAge <= 0 {
+			return nil, fmt.Errorf("invalid maximum age in retention policy %q", spec)
+		}
+		if windows[window] {
+			return nil, fmt.Errorf("duplicate retention policy for window %q", parts[0])
+		}
+		windows[window] = true
+		policies = append(policies, Policy{Window: window, MaxAge: maxAge})
+	}
+	return policies, nil
+}
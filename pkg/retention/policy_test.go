@@ -0,0 +1,44 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies(" 1h=720h, 24h=8760h,*=2160h,")
+	require.Nil(t, err)
+	assert.Equal(t, []Policy{
+		{Window: time.Hour, MaxAge: 720 * time.Hour},
+		{Window: 24 * time.Hour, MaxAge: 8760 * time.Hour},
+		{MaxAge: 2160 * time.Hour},
+	}, policies)
+
+	policies, err = ParsePolicies("")
+	require.Nil(t, err)
+	assert.Empty(t, policies)
+}
+
+func TestParsePoliciesInvalid(t *testing.T) {
+	tc := []struct {
+		Name   string
+		Policy string
+	}{
+		{Name: "missing age", Policy: "1h"},
+		{Name: "invalid window", Policy: "hourly=720h"},
+		{Name: "zero window", Policy: "0h=720h"},
+		{Name: "invalid age", Policy: "1h=30d"},
+		{Name: "negative age", Policy: "1h=-1h"},
+		{Name: "duplicate window", Policy: "1h=720h,60m=24h"},
+		{Name: "duplicate any window", Policy: "*=720h,*=24h"},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			_, err := ParsePolicies(tt.Policy)
+			assert.NotNil(t, err)
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package retention
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/logs"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+const (
+	// DefaultInterval is the default amount of time between two runs of the retention job
+	DefaultInterval = time.Hour
+
+	// DefaultRollupMaxAge is the default age after which the rollups of the digests are deleted, which leaves time
+	// for the weekly graphs to be built from them
+	DefaultRollupMaxAge = 8 * 24 * time.Hour
+
+	listPageSize = 1000
+)
+
+// Result counts the graphs expired by a run of the retention job
+type Result struct {
+	// Expired is the number of graphs deleted, or which would have been deleted in dry run mode
+	Expired int
+	// Failed is the number of expired graphs which could not be deleted
+	Failed int
+	// ExpiredStates is the number of state objects deleted, or which would have been deleted in dry run mode
+	ExpiredStates int
+}
+
+// State is a kind of object kept in Storage by the modules of the service, such as the rollups of the
+// digests or the markers of the notified findings, which is deleted once it is older than MaxAge
+type State struct {
+	// Name identifies the state in the metrics
+	Name   string
+	Prefix string
+	MaxAge time.Duration
+	// Time returns the time from which the age of the object stored under key is counted
+	Time func(ctx context.Context, storage types.Storage, key string) (time.Time, error)
+}
+
+// Retention deletes the graphs older than the maximum age of the Policy of their window size class. Graphs
+// are found through the index of the graphs, so the graphs created before their metadata was stored are kept.
+// Graphs whose window has no Policy, when there is no policy for every other window, are kept as well.
+//
+// Each expired graph is deleted along with the outputs stored alongside it, which are named by appending
+// each of the Sidecars to the ID of the graph. In DryRun mode, the expired graphs are only logged and counted.
+// Deleting a graph which was already deleted succeeds, so multiple replicas may run the job against the same
+// Storage.
+//
+// The objects of each of the States are deleted once they are older than the MaxAge of their State, regardless
+// of the Policies.
+type Retention struct {
+	LogProvider  types.LogFn
+	StatProvider types.StatFn
+	Storage      types.Storage
+	Policies     []Policy
+	Sidecars     []string
+	States       []State
+	DryRun       bool
+	// Interval is the amount of time between two runs. Defaults to DefaultInterval.
+	Interval time.Duration
+}
+
+// Run expires graphs on every interval until the context is cancelled
+func (r *Retention) Run(ctx context.Context) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		result, err := r.Expire(ctx, time.Now())
+		if err != nil {
+			r.LogProvider(ctx).Error(logs.DependencyFailure{Dependency: logs.DependencyStorage, Reason: err.Error()})
+		}
+		r.LogProvider(ctx).Info(logs.RetentionRun{
+			Expired:       result.Expired,
+			Failed:        result.Failed,
+			ExpiredStates: result.ExpiredStates,
+			DryRun:        r.DryRun,
+		})
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Expire deletes the graphs and state objects which are expired at now. A graph which could not be deleted is
+// counted as failed, and is retried on the next run.
+func (r *Retention) Expire(ctx context.Context, now time.Time) (Result, error) {
+	var result Result
+	if err := r.expireGraphs(ctx, now, &result); err != nil {
+		return result, err
+	}
+	for _, state := range r.States {
+		if err := r.expireState(ctx, now, state, &result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// expireGraphs deletes the graphs which are expired at now
+func (r *Retention) expireGraphs(ctx context.Context, now time.Time, result *Result) error {
+	if len(r.Policies) == 0 {
+		return nil
+	}
+	// the index is ordered by the start of the windows, and no graph starting after the horizon is expired
+	horizon := now.Add(-r.minAge())
+	after := ""
+	for {
+		keys, err := r.Storage.List(ctx, graph.IndexPrefix, after, listPageSize)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			id, start, stop, err := graph.ParseIndexKey(key)
+			if err != nil {
+				continue
+			}
+			if !start.Before(horizon) {
+				return nil
+			}
+			policy, ok := r.policy(stop.Sub(start))
+			if !ok || !stop.Add(policy.MaxAge).Before(now) {
+				continue
+			}
+			if err := r.expire(ctx, policy, id, start, stop); err != nil {
+				r.LogProvider(ctx).Error(logs.DependencyFailure{Dependency: logs.DependencyStorage, Reason: err.Error()})
+				r.StatProvider(ctx).Count("retention.failed", 1, "window:"+policy.class())
+				result.Failed++
+				continue
+			}
+			result.Expired++
+		}
+		if len(keys) < listPageSize {
+			return nil
+		}
+		after = keys[len(keys)-1]
+	}
+}
+
+// expireState deletes the objects of the state which are expired at now. Objects which could not be read or
+// deleted are retried on the next run.
+func (r *Retention) expireState(ctx context.Context, now time.Time, state State, result *Result) error {
+	after := ""
+	for {
+		keys, err := r.Storage.List(ctx, state.Prefix, after, listPageSize)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			t, err := state.Time(ctx, r.Storage, key)
+			if err != nil {
+				r.LogProvider(ctx).Error(logs.DependencyFailure{Dependency: logs.DependencyStorage, Reason: err.Error()})
+				continue
+			}
+			if !t.Add(state.MaxAge).Before(now) {
+				continue
+			}
+			if !r.DryRun {
+				if err := r.Storage.Delete(ctx, key); err != nil {
+					r.LogProvider(ctx).Error(logs.DependencyFailure{Dependency: logs.DependencyStorage, Reason: err.Error()})
+					continue
+				}
+			}
+			r.StatProvider(ctx).Count("retention.state_expired", 1, "state:"+state.Name, "dry_run:"+strconv.FormatBool(r.DryRun))
+			result.ExpiredStates++
+		}
+		if len(keys) < listPageSize {
+			return nil
+		}
+		after = keys[len(keys)-1]
+	}
+}
+
+// expire deletes a single expired graph, unless in dry run mode
+func (r *Retention) expire(ctx context.Context, policy Policy, id string, start, stop time.Time) error {
+	if !r.DryRun {
+		if err := storage.DeleteGraph(ctx, r.Storage, id, r.Sidecars); err != nil {
+			return err
+		}
+	}
+	r.LogProvider(ctx).Info(logs.ExpiredGraph{
+		ID:     id,
+		Start:  start.Format(time.RFC3339),
+		Stop:   stop.Format(time.RFC3339),
+		DryRun: r.DryRun,
+	})
+	r.StatProvider(ctx).Count("retention.expired", 1, "window:"+policy.class(), "dry_run:"+strconv.FormatBool(r.DryRun))
+	return nil
+}
+
+// policy returns the policy of the window size class of a graph
+func (r *Retention) policy(window time.Duration) (Policy, bool) {
+	var fallback Policy
+	var found bool
+	for _, p := range r.Policies {
+		if p.Window == window {
+			return p, true
+		}
+		if p.Window == 0 {
+			fallback, found = p, true
+		}
+	}
+	return fallback, found
+}
+
+// minAge returns the shortest maximum age of the policies
+func (r *Retention) minAge() time.Duration {
+	min := r.Policies[0].MaxAge
+	for _, p := range r.Policies[1:] {
+		if p.MaxAge < min {
+			min = p.MaxAge
+		}
+	}
+	return min
+}
@@ -0,0 +1,207 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/logevent"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage/storagetest"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/rs/xstats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	now      = time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	policies = []Policy{
+		{Window: time.Hour, MaxAge: 30 * 24 * time.Hour},
+		{Window: 24 * time.Hour, MaxAge: 365 * 24 * time.Hour},
+	}
+)
+
+func testContext() context.Context {
+	return logevent.NewContext(context.Background(), logevent.New(logevent.Config{Output: ioutil.Discard}))
+}
+
+// storeGraph stores a graph with a stats sidecar, along with its metadata and index entry, and returns its ID
+func storeGraph(storage *storagetest.Memory, start time.Time, window time.Duration) string {
+	stop := start.Add(window)
+	id := graph.ID(start, stop)
+	storage.Objects[id] = []byte("digraph{}")
+	storage.Objects[id+".stats.json"] = []byte("{}")
+	storage.Objects[id+graph.MetadataSuffix] = []byte(`{"id":"` + id + `","start":"` + start.Format(time.RFC3339) + `","stop":"` + stop.Format(time.RFC3339) + `"}`)
+	storage.Objects[graph.IndexKey(id, start, stop)] = storage.Objects[id+graph.MetadataSuffix]
+	return id
+}
+
+func newRetention(storage *storagetest.Memory) *Retention {
+	return &Retention{
+		LogProvider:  logevent.FromContext,
+		StatProvider: xstats.FromContext,
+		Storage:      storage,
+		Policies:     policies,
+		Sidecars:     []string{".stats.json"},
+	}
+}
+
+func TestExpire(t *testing.T) {
+	storage := storagetest.NewMemory()
+	expiredHourly := storeGraph(storage, now.Add(-31*24*time.Hour), time.Hour)
+	keptHourly := storeGraph(storage, now.Add(-29*24*time.Hour), time.Hour)
+	keptDaily := storeGraph(storage, now.Add(-300*24*time.Hour), 24*time.Hour)
+	expiredDaily := storeGraph(storage, now.Add(-400*24*time.Hour), 24*time.Hour)
+	// windows without a policy are kept
+	keptWeekly := storeGraph(storage, now.Add(-400*24*time.Hour), 7*24*time.Hour)
+
+	result, err := newRetention(storage).Expire(testContext(), now)
+	require.Nil(t, err)
+	assert.Equal(t, Result{Expired: 2}, result)
+	for _, id := range []string{expiredHourly, expiredDaily} {
+		assert.NotContains(t, storage.Objects, id)
+		assert.NotContains(t, storage.Objects, id+".stats.json")
+		assert.NotContains(t, storage.Objects, id+graph.MetadataSuffix)
+	}
+	for _, id := range []string{keptHourly, keptDaily, keptWeekly} {
+		assert.Contains(t, storage.Objects, id)
+		assert.Contains(t, storage.Objects, id+".stats.json")
+	}
+	keys, _ := storage.List(context.Background(), graph.IndexPrefix, "", listPageSize)
+	assert.Len(t, keys, 3)
+}
+
+func TestExpireAnyWindow(t *testing.T) {
+	storage := storagetest.NewMemory()
+	expiredWeekly := storeGraph(storage, now.Add(-100*24*time.Hour), 7*24*time.Hour)
+	keptHourly := storeGraph(storage, now.Add(-100*24*time.Hour), time.Hour)
+
+	r := newRetention(storage)
+	r.Policies = []Policy{{Window: time.Hour, MaxAge: 365 * 24 * time.Hour}, {MaxAge: 90 * 24 * time.Hour}}
+	result, err := r.Expire(testContext(), now)
+	require.Nil(t, err)
+	assert.Equal(t, Result{Expired: 1}, result)
+	assert.NotContains(t, storage.Objects, expiredWeekly)
+	assert.Contains(t, storage.Objects, keptHourly)
+}
+
+func TestExpireDryRun(t *testing.T) {
+	storage := storagetest.NewMemory()
+	expired := storeGraph(storage, now.Add(-31*24*time.Hour), time.Hour)
+
+	r := newRetention(storage)
+	r.DryRun = true
+	result, err := r.Expire(testContext(), now)
+	require.Nil(t, err)
+	assert.Equal(t, Result{Expired: 1}, result)
+	assert.Contains(t, storage.Objects, expired)
+	assert.Contains(t, storage.Objects, expired+".stats.json")
+}
+
+func TestExpirePages(t *testing.T) {
+	storage := storagetest.NewMemory()
+	start := now.Add(-100 * 24 * time.Hour)
+	for i := 0; i < listPageSize+10; i++ {
+		storeGraph(storage, start.Add(time.Duration(i)*time.Hour), time.Hour)
+	}
+
+	result, err := newRetention(storage).Expire(testContext(), now)
+	require.Nil(t, err)
+	assert.Equal(t, Result{Expired: listPageSize + 10}, result)
+	assert.Empty(t, storage.Objects)
+}
+
+func TestExpireWithoutPolicies(t *testing.T) {
+	storage := storagetest.NewMemory()
+	storage.Err = errors.New("oops")
+
+	r := newRetention(storage)
+	r.Policies = nil
+	result, err := r.Expire(testContext(), now)
+	require.Nil(t, err)
+	assert.Equal(t, Result{}, result)
+}
+
+func TestExpireStorageError(t *testing.T) {
+	storage := storagetest.NewMemory()
+	storage.Err = errors.New("oops")
+
+	_, err := newRetention(storage).Expire(testContext(), now)
+	assert.NotNil(t, err)
+}
+
+// failingStorage fails to delete any object
+type failingStorage struct {
+	*storagetest.Memory
+}
+
+func (s failingStorage) Delete(_ context.Context, key string) error {
+	return errors.New("oops")
+}
+
+func TestExpireDeleteError(t *testing.T) {
+	storage := storagetest.NewMemory()
+	expired := storeGraph(storage, now.Add(-31*24*time.Hour), time.Hour)
+
+	r := newRetention(storage)
+	r.Storage = failingStorage{storage}
+	result, err := r.Expire(testContext(), now)
+	require.Nil(t, err)
+	assert.Equal(t, Result{Failed: 1}, result)
+	assert.Contains(t, storage.Objects, expired)
+}
+
+func TestRunStopsWhenCancelled(t *testing.T) {
+	storage := storagetest.NewMemory()
+	storeGraph(storage, time.Now().Add(-31*24*time.Hour), time.Hour)
+
+	ctx, cancel := context.WithCancel(testContext())
+	cancel()
+	newRetention(storage).Run(ctx)
+	assert.Empty(t, storage.Objects)
+}
+
+func TestExpireStates(t *testing.T) {
+	storage := storagetest.NewMemory()
+	expired := "state/" + now.Add(-25*time.Hour).Format(time.RFC3339)
+	kept := "state/" + now.Add(-23*time.Hour).Format(time.RFC3339)
+	unreadable := "state/unreadable"
+	other := "other/" + now.Add(-25*time.Hour).Format(time.RFC3339)
+	for _, key := range []string{expired, kept, unreadable, other} {
+		storage.Objects[key] = []byte("state")
+	}
+
+	r := newRetention(storage)
+	r.Policies = nil
+	r.States = []State{{
+		Name:   "state",
+		Prefix: "state/",
+		MaxAge: 24 * time.Hour,
+		Time: func(_ context.Context, _ types.Storage, key string) (time.Time, error) {
+			return time.Parse(time.RFC3339, strings.TrimPrefix(key, "state/"))
+		},
+	}}
+
+	r.DryRun = true
+	result, err := r.Expire(testContext(), now)
+	require.Nil(t, err)
+	assert.Equal(t, Result{ExpiredStates: 1}, result)
+	assert.Contains(t, storage.Objects, expired)
+
+	r.DryRun = false
+	result, err = r.Expire(testContext(), now)
+	require.Nil(t, err)
+	assert.Equal(t, Result{ExpiredStates: 1}, result)
+	assert.NotContains(t, storage.Objects, expired)
+	assert.Contains(t, storage.Objects, kept)
+	assert.Contains(t, storage.Objects, unreadable)
+	assert.Contains(t, storage.Objects, other)
+
+	storage.Err = errors.New("oops")
+	_, err = r.Expire(testContext(), now)
+	assert.NotNil(t, err)
+}
@@ -1,20 +1,30 @@
 package grapherd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/asecurityteam/go-vpcflow"
 	"github.com/asecurityteam/transport"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/annotator"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/backfill"
 	"github.com/asecurityteam/vpcflow-grapherd/pkg/digester"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/enricher"
 	"github.com/asecurityteam/vpcflow-grapherd/pkg/grapher"
 	v1 "github.com/asecurityteam/vpcflow-grapherd/pkg/handlers/v1"
 	"github.com/asecurityteam/vpcflow-grapherd/pkg/marker"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/notifier"
 	"github.com/asecurityteam/vpcflow-grapherd/pkg/queuer"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/render"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/retention"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/scheduler"
 	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage"
 	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
 	"github.com/aws/aws-sdk-go/aws"
@@ -24,6 +34,15 @@ import (
 	"github.com/go-chi/chi"
 )
 
+// sidecars are the suffixes of the outputs stored alongside each graph, which are deleted with the graph
+var sidecars = []string{
+	annotator.StatsSuffix,
+	annotator.BaselineSummarySuffix,
+	annotator.PolicyReportSuffix,
+	annotator.ScanReportSuffix,
+	v1.RegenerationSuffix,
+}
+
 // Service is a container for all of the pluggable modules used by the service
 type Service struct {
 	// QueuerHTTPClient is the client to be used with the default Queuer module.
@@ -34,6 +53,10 @@ type Service struct {
 	// If no client is provided, the default client will be used.
 	DigesterHTTPClient *http.Client
 
+	// NotifierHTTPClient is the client to be used with the default Notifier module.
+	// If no client is provided, the default client will be used.
+	NotifierHTTPClient *http.Client
+
 	// Middleware is a list of service middleware to install on the router.
 	Middleware []func(http.Handler) http.Handler
 
@@ -45,26 +68,55 @@ type Service struct {
 	// built in Storage uses S3 as the persistent storage for graph content.
 	Storage types.Storage
 
+	// Codecs are the content encodings available to compress the stored graphs, in addition
+	// to the built in gzip and zstd codecs. The codec used is selected with the GRAPH_STORAGE_COMPRESSION
+	// environment variable, and objects stored with any of the codecs can be read.
+	Codecs []types.Codec
+
 	// Marker is responsible for marking which graph jobs are inprogress. The built in
 	// Marker uses S3 to hold this state.
 	Marker types.Marker
 
 	// Digester is responsible for creating a digest of VPC logs for a given time range.
-	// The built in digester calls out to a digester service.
+	// The built in digester calls out to a digester service, and builds the digests of
+	// windows spanning multiple hours from stored hourly rollups.
 	Digester types.Digester
+
+	// Enrichers annotate the nodes of each graph with additional attributes. If no
+	// enrichers are provided, the built in asset inventory, AWS ip-ranges and GeoIP
+	// enrichers are used when their respective files are configured.
+	Enrichers []types.Enricher
+
+	// Annotators analyze each graph after enrichment, before it is stored. If no annotators
+	// are provided, the built in statistics, baseline and scan annotators are used, along with
+	// the policy annotator when a policy file is configured.
+	Annotators []types.Annotator
+
+	// Notifier is sent the findings of the Annotators for each graph. If no notifier is
+	// provided, the built in webhook notifier is used when webhook URLs are configured.
+	Notifier types.Notifier
+
+	// Schedules are the recurring windows of time graphed by the scheduler. If no schedules
+	// are provided, they are parsed from the SCHEDULES environment variable.
+	Schedules []scheduler.Schedule
+
+	// RetentionPolicies are the maximum ages of the graphs of each window size class, after
+	// which the retention job deletes them. If no policies are provided, they are parsed from
+	// the RETENTION_POLICIES environment variable.
+	RetentionPolicies []retention.Policy
+
+	scheduler          *scheduler.Scheduler
+	retention          *retention.Retention
+	redirectExpiration time.Duration
+	redirectMinSize    int64
+	cacheMaxAge        time.Duration
 }
 
 func (s *Service) init() error {
 	var err error
-	storageClient, err := createS3Client(mustEnv("GRAPH_STORAGE_BUCKET_REGION"))
-	if err != nil {
-		return err
-	}
-	progressClient, err := createS3Client(mustEnv("GRAPH_PROGRESS_BUCKET_REGION"))
-	if err != nil {
+	if err = s.initGrapher(); err != nil {
 		return err
 	}
-
 	if s.Queuer == nil {
 		streamApplianceEndpoint := mustEnv("STREAM_APPLIANCE_ENDPOINT")
 		streamApplianceURL, err := url.Parse(streamApplianceEndpoint)
@@ -79,18 +131,117 @@ func (s *Service) init() error {
 			Endpoint: streamApplianceURL,
 		}
 	}
+	if s.Schedules == nil {
+		if s.Schedules, err = scheduler.ParseSchedules(os.Getenv("SCHEDULES")); err != nil {
+			return err
+		}
+	}
+	s.scheduler = &scheduler.Scheduler{
+		LogProvider: types.LoggerFromContext,
+		Schedules:   s.Schedules,
+		Storage:     s.Storage,
+		Queuer:      s.Queuer,
+		Marker:      s.Marker,
+	}
+	if expiration := os.Getenv("GRAPH_REDIRECT_EXPIRATION"); expiration != "" {
+		if s.redirectExpiration, err = time.ParseDuration(expiration); err != nil {
+			return err
+		}
+		// presigned URLs are valid for at most a week
+		if s.redirectExpiration <= 0 || s.redirectExpiration > 7*24*time.Hour {
+			return fmt.Errorf("invalid graph redirect expiration %s", expiration)
+		}
+	}
+	if minSize := os.Getenv("GRAPH_REDIRECT_MIN_SIZE"); minSize != "" {
+		if s.redirectMinSize, err = strconv.ParseInt(minSize, 10, 64); err != nil {
+			return err
+		}
+	}
+	if maxAge := os.Getenv("GRAPH_CACHE_MAX_AGE"); maxAge != "" {
+		if s.cacheMaxAge, err = time.ParseDuration(maxAge); err != nil {
+			return err
+		}
+	}
+	if s.RetentionPolicies == nil {
+		if s.RetentionPolicies, err = retention.ParsePolicies(os.Getenv("RETENTION_POLICIES")); err != nil {
+			return err
+		}
+	}
+	s.retention = &retention.Retention{
+		LogProvider:  types.LoggerFromContext,
+		StatProvider: types.StatFromContext,
+		Storage:      s.Storage,
+		Policies:     s.RetentionPolicies,
+		Sidecars:     sidecars,
+	}
+	if interval := os.Getenv("RETENTION_INTERVAL"); interval != "" {
+		if s.retention.Interval, err = time.ParseDuration(interval); err != nil {
+			return err
+		}
+	}
+	if dryRun := os.Getenv("RETENTION_DRY_RUN"); dryRun != "" {
+		if s.retention.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			return err
+		}
+	}
+	rollupMaxAge := retention.DefaultRollupMaxAge
+	if maxAge := os.Getenv("RETENTION_ROLLUP_MAX_AGE"); maxAge != "" {
+		if rollupMaxAge, err = time.ParseDuration(maxAge); err != nil {
+			return err
+		}
+	}
+	// the markers of the notified findings are no longer needed once the dedup window has passed
+	notifiedMaxAge := notifier.DefaultDedupWindow
+	if dedup, ok := s.Notifier.(*notifier.Dedup); ok && dedup.Window > 0 {
+		notifiedMaxAge = dedup.Window
+	}
+	s.retention.States = []retention.State{
+		{Name: "rollup", Prefix: digester.RollupPrefix, MaxAge: rollupMaxAge, Time: digester.RollupTime},
+		{Name: "notified", Prefix: notifier.NotifiedPrefix, MaxAge: notifiedMaxAge, Time: notifier.NotifiedTime},
+	}
+	return nil
+}
+
+// initGrapher initializes the modules used to create graphs, which does not require the Queuer
+func (s *Service) initGrapher() error {
+	var err error
+	storageClient, err := createS3Client(mustEnv("GRAPH_STORAGE_BUCKET_REGION"))
+	if err != nil {
+		return err
+	}
+	progressClient, err := createS3Client(mustEnv("GRAPH_PROGRESS_BUCKET_REGION"))
+	if err != nil {
+		return err
+	}
+
 	if s.Storage == nil {
 		progressTimeoutStr := mustEnv("GRAPH_PROGRESS_TIMEOUT")
 		progressTimeoutInt, err := strconv.Atoi(progressTimeoutStr)
 		if err != nil {
 			return err
 		}
+		codecs := append([]types.Codec{storage.Gzip{}, storage.Zstd{}}, s.Codecs...)
+		var codec types.Codec
+		if compression := os.Getenv("GRAPH_STORAGE_COMPRESSION"); compression != "" {
+			for _, c := range codecs {
+				if c.Encoding() == compression {
+					codec = c
+				}
+			}
+			if codec == nil {
+				return fmt.Errorf("unsupported graph storage compression %s", compression)
+			}
+		}
 		s.Storage = &storage.InProgress{
 			Bucket: mustEnv("GRAPH_PROGRESS_BUCKET"),
 			Client: progressClient,
-			Storage: &storage.S3{
-				Bucket: mustEnv("GRAPH_STORAGE_BUCKET"),
-				Client: storageClient,
+			Storage: &storage.Compressed{
+				Storage: &storage.S3{
+					Bucket: mustEnv("GRAPH_STORAGE_BUCKET"),
+					Client: storageClient,
+				},
+				Codec:  codec,
+				Codecs: codecs,
 			},
 			Timeout: time.Millisecond * time.Duration(progressTimeoutInt),
 		}
@@ -120,16 +271,174 @@ func (s *Service) init() error {
 		if s.DigesterHTTPClient == nil {
 			s.DigesterHTTPClient = defaultHTTPClient()
 		}
-		s.Digester = &digester.HTTP{
-			Client:          s.DigesterHTTPClient,
-			Endpoint:        digesterURL,
-			PollTimeout:     time.Duration(durationMs) * time.Millisecond,
-			PollingInterval: time.Duration(intervalMs) * time.Millisecond,
+		s.Digester = &digester.Rollup{
+			Storage: s.Storage,
+			Digester: &digester.HTTP{
+				Client:          s.DigesterHTTPClient,
+				Endpoint:        digesterURL,
+				PollTimeout:     time.Duration(durationMs) * time.Millisecond,
+				PollingInterval: time.Duration(intervalMs) * time.Millisecond,
+			},
+		}
+	}
+	if err = s.initEnrichers(); err != nil {
+		return err
+	}
+	if s.Annotators == nil {
+		windowDays, err := optionalIntEnv("BASELINE_WINDOW_DAYS")
+		if err != nil {
+			return err
+		}
+		volumeFactor, err := optionalFloatEnv("BASELINE_VOLUME_FACTOR")
+		if err != nil {
+			return err
+		}
+		baseline := &annotator.Baseline{
+			Storage:      s.Storage,
+			Window:       time.Duration(windowDays) * 24 * time.Hour,
+			VolumeFactor: volumeFactor,
+		}
+		scan := &annotator.Scan{Storage: s.Storage}
+		if scan.DestinationThreshold, err = optionalIntEnv("SCAN_DESTINATION_THRESHOLD"); err != nil {
+			return err
+		}
+		if scan.PortThreshold, err = optionalIntEnv("SCAN_PORT_THRESHOLD"); err != nil {
+			return err
+		}
+		if scan.RejectRatio, err = optionalFloatEnv("SCAN_REJECT_RATIO"); err != nil {
+			return err
+		}
+		s.Annotators = append(s.Annotators, &annotator.Stats{Storage: s.Storage}, baseline, scan)
+		if policyFile := os.Getenv("POLICY_FILE"); policyFile != "" {
+			policy, err := annotator.LoadPolicy(policyFile)
+			if err != nil {
+				return err
+			}
+			s.Annotators = append(s.Annotators, &annotator.Policy{Storage: s.Storage, Policy: policy})
+		}
+	}
+	if s.Notifier == nil {
+		if webhookURLs := os.Getenv("NOTIFIER_WEBHOOK_URLS"); webhookURLs != "" {
+			var endpoints []*url.URL
+			for _, raw := range strings.Split(webhookURLs, ",") {
+				endpoint, err := url.Parse(strings.TrimSpace(raw))
+				if err != nil {
+					return err
+				}
+				endpoints = append(endpoints, endpoint)
+			}
+			windowHours, err := optionalIntEnv("NOTIFIER_DEDUP_WINDOW_HOURS")
+			if err != nil {
+				return err
+			}
+			if s.NotifierHTTPClient == nil {
+				s.NotifierHTTPClient = defaultHTTPClient()
+			}
+			s.Notifier = &notifier.Dedup{
+				Storage: s.Storage,
+				Window:  time.Duration(windowHours) * time.Hour,
+				Notifier: &notifier.Webhook{
+					Endpoints: endpoints,
+					Client:    s.NotifierHTTPClient,
+					Secret:    []byte(os.Getenv("NOTIFIER_WEBHOOK_SECRET")),
+				},
+			}
 		}
 	}
 	return nil
 }
 
+// initEnrichers initializes the Enrichers, which only depend on local files
+func (s *Service) initEnrichers() error {
+	if s.Enrichers == nil {
+		if inventoryFile := os.Getenv("INVENTORY_FILE"); inventoryFile != "" {
+			inventory, err := enricher.LoadInventory(inventoryFile)
+			if err != nil {
+				return err
+			}
+			s.Enrichers = append(s.Enrichers, inventory)
+		}
+		if ipRangesFile := os.Getenv("AWS_IP_RANGES_FILE"); ipRangesFile != "" {
+			ranges, err := enricher.LoadIPRanges(ipRangesFile)
+			if err != nil {
+				return err
+			}
+			s.Enrichers = append(s.Enrichers, ranges)
+		}
+		countryDatabase := os.Getenv("GEOIP_COUNTRY_DATABASE")
+		asnDatabase := os.Getenv("GEOIP_ASN_DATABASE")
+		if countryDatabase != "" || asnDatabase != "" {
+			geoIP, err := enricher.LoadGeoIP(countryDatabase, asnDatabase)
+			if err != nil {
+				return err
+			}
+			s.Enrichers = append(s.Enrichers, geoIP)
+		}
+	}
+	return nil
+}
+
+// RunScheduler queues the graphs of the configured schedules until the context is cancelled. It must be
+// called after BindRoutes, and returns immediately when no schedules are configured.
+func (s *Service) RunScheduler(ctx context.Context) {
+	if s.scheduler == nil || len(s.scheduler.Schedules) == 0 {
+		return
+	}
+	s.scheduler.Run(ctx)
+}
+
+// RunRetention deletes the graphs older than the configured retention policies, along with the expired rollups
+// and notification markers, until the context is cancelled. It must be called after BindRoutes.
+func (s *Service) RunRetention(ctx context.Context) {
+	if s.retention == nil {
+		return
+	}
+	s.retention.Run(ctx)
+}
+
+// Backfill graphs the windows of the options by calling the Digester and Grapher directly, and writes its
+// progress to output. Unlike BindRoutes, it does not require a Queuer.
+func (s *Service) Backfill(ctx context.Context, opts backfill.Options, output io.Writer) error {
+	if err := s.initGrapher(); err != nil {
+		return err
+	}
+	b := &backfill.Backfill{
+		Storage:     s.Storage,
+		Marker:      s.Marker,
+		Digester:    s.Digester,
+		Grapher:     s.grapher(),
+		Concurrency: opts.Concurrency,
+		Output:      output,
+	}
+	_, err := b.Run(ctx, opts.Windows())
+	return err
+}
+
+// Render writes the graph of the digest to output, converted and enriched in the same way as the graphs of
+// the service. Unlike BindRoutes, it only requires the configuration of the Enrichers.
+func (s *Service) Render(ctx context.Context, digest io.ReadCloser, opts render.Options, output io.Writer) error {
+	if err := s.initEnrichers(); err != nil {
+		return err
+	}
+	r := &render.Render{
+		Converter: vpcflow.DOTConverter,
+		Enrichers: s.Enrichers,
+	}
+	return r.Run(ctx, digest, opts, output)
+}
+
+// grapher returns the Grapher which digests are converted, enriched, annotated and stored with
+func (s *Service) grapher() types.Grapher {
+	return &grapher.DOT{
+		Converter:   vpcflow.DOTConverter,
+		Storage:     s.Storage,
+		Enrichers:   s.Enrichers,
+		Annotators:  s.Annotators,
+		Notifier:    s.Notifier,
+		LogProvider: types.LoggerFromContext,
+	}
+}
+
 // BindRoutes binds the service handlers to the provided router
 func (s *Service) BindRoutes(router chi.Router) error {
 	if err := s.init(); err != nil {
@@ -141,20 +450,106 @@ func (s *Service) BindRoutes(router chi.Router) error {
 		Queuer:       s.Queuer,
 		Storage:      s.Storage,
 		Marker:       s.Marker,
+		Sidecars:     sidecars,
+
+		RedirectExpiration: s.redirectExpiration,
+		RedirectMinSize:    s.redirectMinSize,
+		CacheMaxAge:        s.cacheMaxAge,
 	}
 	produceHandler := &v1.Produce{
 		LogProvider:  types.LoggerFromContext,
 		StatProvider: types.StatFromContext,
 		Digester:     s.Digester,
 		Marker:       s.Marker,
-		Grapher: &grapher.DOT{
-			Converter: vpcflow.DOTConverter,
-			Storage:   s.Storage,
-		},
+		Grapher:      s.grapher(),
+	}
+	source := &grapher.Source{
+		Storage:   s.Storage,
+		Digester:  s.Digester,
+		Converter: vpcflow.DOTConverter,
+		Enrichers: s.Enrichers,
+	}
+	diffHandler := &v1.Diff{
+		LogProvider:  types.LoggerFromContext,
+		StatProvider: types.StatFromContext,
+		Source:       source,
+	}
+	neighborhoodHandler := &v1.Neighborhood{
+		LogProvider:  types.LoggerFromContext,
+		StatProvider: types.StatFromContext,
+		Source:       source,
+	}
+	pathsHandler := &v1.Paths{
+		LogProvider:  types.LoggerFromContext,
+		StatProvider: types.StatFromContext,
+		Source:       source,
+	}
+	suggestionsHandler := &v1.Suggestions{
+		LogProvider:  types.LoggerFromContext,
+		StatProvider: types.StatFromContext,
+		Source:       source,
+	}
+	graphsHandler := &v1.Graphs{
+		LogProvider:  types.LoggerFromContext,
+		StatProvider: types.StatFromContext,
+		Storage:      s.Storage,
+	}
+	temporalHandler := &v1.Temporal{
+		LogProvider:  types.LoggerFromContext,
+		StatProvider: types.StatFromContext,
+		Source:       source,
+		Storage:      s.Storage,
+		Queuer:       s.Queuer,
+		Marker:       s.Marker,
+	}
+	baselineHandler := &v1.Report{
+		LogProvider:  types.LoggerFromContext,
+		StatProvider: types.StatFromContext,
+		Storage:      s.Storage,
+		Suffix:       annotator.BaselineSummarySuffix,
+	}
+	statsHandler := &v1.Report{
+		LogProvider:  types.LoggerFromContext,
+		StatProvider: types.StatFromContext,
+		Storage:      s.Storage,
+		Suffix:       annotator.StatsSuffix,
+	}
+	policyHandler := &v1.Report{
+		LogProvider:  types.LoggerFromContext,
+		StatProvider: types.StatFromContext,
+		Storage:      s.Storage,
+		Suffix:       annotator.PolicyReportSuffix,
+	}
+	scanHandler := &v1.Report{
+		LogProvider:  types.LoggerFromContext,
+		StatProvider: types.StatFromContext,
+		Storage:      s.Storage,
+		Suffix:       annotator.ScanReportSuffix,
+	}
+	regenerationsHandler := &v1.Report{
+		LogProvider:  types.LoggerFromContext,
+		StatProvider: types.StatFromContext,
+		Storage:      s.Storage,
+		Suffix:       v1.RegenerationSuffix,
 	}
 	router.Use(s.Middleware...)
 	router.Post("/", grapherHandler.Post)
 	router.Get("/", grapherHandler.Get)
+	router.Head("/", grapherHandler.Get)
+	router.Delete("/", grapherHandler.Delete)
+	router.Get("/graphs", graphsHandler.ServeHTTP)
+	router.Delete("/graphs/{id}", grapherHandler.DeleteByID)
+	router.Post("/batch", grapherHandler.Batch)
+	router.Get("/diff", diffHandler.ServeHTTP)
+	router.Get("/neighborhood", neighborhoodHandler.ServeHTTP)
+	router.Get("/paths", pathsHandler.ServeHTTP)
+	router.Get("/suggestions", suggestionsHandler.ServeHTTP)
+	router.Get("/temporal", temporalHandler.ServeHTTP)
+	router.Get("/baseline", baselineHandler.ServeHTTP)
+	router.Get("/stats", statsHandler.ServeHTTP)
+	router.Get("/policy", policyHandler.ServeHTTP)
+	router.Get("/scans", scanHandler.ServeHTTP)
+	router.Get("/regenerations", regenerationsHandler.ServeHTTP)
 	router.Post("/{topic}/{event}", produceHandler.ServeHTTP)
 	return nil
 }
@@ -167,6 +562,24 @@ func mustEnv(key string) string {
 	return val
 }
 
+// optionalIntEnv returns the integer value of an environment variable, or zero if it is not set
+func optionalIntEnv(key string) (int, error) {
+	val := os.Getenv(key)
+	if val == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(val)
+}
+
+// optionalFloatEnv returns the float value of an environment variable, or zero if it is not set
+func optionalFloatEnv(key string) (float64, error) {
+	val := os.Getenv(key)
+	if val == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(val, 64)
+}
+
 func createS3Client(region string) (*s3.S3, error) {
 	useIAM := mustEnv("USE_IAM")
 	useIAMFlag, err := strconv.ParseBool(useIAM)
@@ -1,11 +1,18 @@
 package grapherd
 
 import (
+	"bytes"
+	"context"
+	"io/ioutil"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/backfill"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/render"
 	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -62,23 +69,127 @@ func TestServiceInitSuccess(t *testing.T) {
 		}
 	}()
 
-	// set required test environment variables
-	os.Setenv("USE_IAM", "true")
-	os.Setenv("GRAPH_STORAGE_BUCKET_REGION", "n/a")
-	os.Setenv("GRAPH_PROGRESS_BUCKET_REGION", "n/a")
-	os.Setenv("STREAM_APPLIANCE_ENDPOINT", "n/a")
-	os.Setenv("GRAPH_PROGRESS_TIMEOUT", "1")
-	os.Setenv("GRAPH_PROGRESS_BUCKET", "n/a")
-	os.Setenv("GRAPH_STORAGE_BUCKET", "n/a")
-	os.Setenv("DIGESTER_ENDPOINT", "n/a")
-	os.Setenv("DIGESTER_POLLING_TIMEOUT", "1")
-	os.Setenv("DIGESTER_POLLING_INTERVAL", "1")
+	setRequiredEnv()
 
 	s := &Service{}
 	require.Nil(t, s.init())
 }
 
-func TestServiceBindRoutesSuccess(t *testing.T) {
+func TestServiceInitInvalidInventory(t *testing.T) {
+	// save current environment variables, and restore them
+	// after the test ends
+	environ := os.Environ()
+	os.Clearenv()
+	defer func() {
+		for _, e := range environ {
+			envPair := strings.Split(e, "=")
+			os.Setenv(envPair[0], envPair[1])
+		}
+	}()
+
+	setRequiredEnv()
+	os.Setenv("INVENTORY_FILE", "/does/not/exist.json")
+
+	s := &Service{}
+	require.NotNil(t, s.init())
+}
+
+func TestServiceInitInvalidIPRanges(t *testing.T) {
+	// save current environment variables, and restore them
+	// after the test ends
+	environ := os.Environ()
+	os.Clearenv()
+	defer func() {
+		for _, e := range environ {
+			envPair := strings.Split(e, "=")
+			os.Setenv(envPair[0], envPair[1])
+		}
+	}()
+
+	setRequiredEnv()
+	os.Setenv("AWS_IP_RANGES_FILE", "/does/not/exist.json")
+
+	s := &Service{}
+	require.NotNil(t, s.init())
+}
+
+func TestServiceInitInvalidGeoIP(t *testing.T) {
+	// save current environment variables, and restore them
+	// after the test ends
+	environ := os.Environ()
+	os.Clearenv()
+	defer func() {
+		for _, e := range environ {
+			envPair := strings.Split(e, "=")
+			os.Setenv(envPair[0], envPair[1])
+		}
+	}()
+
+	setRequiredEnv()
+	os.Setenv("GEOIP_ASN_DATABASE", "/does/not/exist.mmdb")
+
+	s := &Service{}
+	require.NotNil(t, s.init())
+}
+
+func TestServiceInitInvalidBaselineWindow(t *testing.T) {
+	// save current environment variables, and restore them
+	// after the test ends
+	environ := os.Environ()
+	os.Clearenv()
+	defer func() {
+		for _, e := range environ {
+			envPair := strings.Split(e, "=")
+			os.Setenv(envPair[0], envPair[1])
+		}
+	}()
+
+	setRequiredEnv()
+	os.Setenv("BASELINE_WINDOW_DAYS", "two weeks")
+
+	s := &Service{}
+	require.NotNil(t, s.init())
+}
+
+func TestServiceInitInvalidPolicy(t *testing.T) {
+	// save current environment variables, and restore them
+	// after the test ends
+	environ := os.Environ()
+	os.Clearenv()
+	defer func() {
+		for _, e := range environ {
+			envPair := strings.Split(e, "=")
+			os.Setenv(envPair[0], envPair[1])
+		}
+	}()
+
+	setRequiredEnv()
+	os.Setenv("POLICY_FILE", "/does/not/exist.yaml")
+
+	s := &Service{}
+	require.NotNil(t, s.init())
+}
+
+func TestServiceInitInvalidScanThreshold(t *testing.T) {
+	// save current environment variables, and restore them
+	// after the test ends
+	environ := os.Environ()
+	os.Clearenv()
+	defer func() {
+		for _, e := range environ {
+			envPair := strings.Split(e, "=")
+			os.Setenv(envPair[0], envPair[1])
+		}
+	}()
+
+	setRequiredEnv()
+	os.Setenv("SCAN_REJECT_RATIO", "half")
+
+	s := &Service{}
+	require.NotNil(t, s.init())
+}
+
+func TestServiceInvalidNotifierDedupWindow(t *testing.T) {
 	environ := os.Environ()
 	os.Clearenv()
 	defer func() {
@@ -88,7 +199,90 @@ func TestServiceBindRoutesSuccess(t *testing.T) {
 		}
 	}()
 
-	// set required test environment variables
+	setRequiredEnv()
+	os.Setenv("NOTIFIER_WEBHOOK_URLS", "http://localhost/hook")
+	os.Setenv("NOTIFIER_DEDUP_WINDOW_HOURS", "day")
+
+	s := &Service{}
+	require.NotNil(t, s.init())
+}
+
+func TestServiceInvalidSchedules(t *testing.T) {
+	environ := os.Environ()
+	os.Clearenv()
+	defer func() {
+		for _, e := range environ {
+			envPair := strings.Split(e, "=")
+			os.Setenv(envPair[0], envPair[1])
+		}
+	}()
+
+	setRequiredEnv()
+	os.Setenv("SCHEDULES", "hourly=1h")
+
+	s := &Service{}
+	require.NotNil(t, s.init())
+}
+
+func TestServiceStorageCompression(t *testing.T) {
+	environ := os.Environ()
+	os.Clearenv()
+	defer func() {
+		for _, e := range environ {
+			envPair := strings.Split(e, "=")
+			os.Setenv(envPair[0], envPair[1])
+		}
+	}()
+
+	setRequiredEnv()
+	os.Setenv("GRAPH_STORAGE_COMPRESSION", "gzip")
+	require.Nil(t, (&Service{}).init())
+
+	os.Setenv("GRAPH_STORAGE_COMPRESSION", "zstd")
+	require.Nil(t, (&Service{}).init())
+
+	// other encodings are only available when their codec is provided
+	os.Setenv("GRAPH_STORAGE_COMPRESSION", "br")
+	require.NotNil(t, (&Service{}).init())
+}
+
+func TestServiceInvalidSettings(t *testing.T) {
+	tc := []struct {
+		Name  string
+		Key   string
+		Value string
+	}{
+		{Name: "policies", Key: "RETENTION_POLICIES", Value: "1h=30d"},
+		{Name: "interval", Key: "RETENTION_INTERVAL", Value: "hourly"},
+		{Name: "dry run", Key: "RETENTION_DRY_RUN", Value: "maybe"},
+		{Name: "rollup max age", Key: "RETENTION_ROLLUP_MAX_AGE", Value: "a week"},
+		{Name: "redirect expiration", Key: "GRAPH_REDIRECT_EXPIRATION", Value: "soon"},
+		{Name: "redirect expiration too long", Key: "GRAPH_REDIRECT_EXPIRATION", Value: "169h"},
+		{Name: "redirect size", Key: "GRAPH_REDIRECT_MIN_SIZE", Value: "1MB"},
+		{Name: "cache max age", Key: "GRAPH_CACHE_MAX_AGE", Value: "a day"},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			environ := os.Environ()
+			os.Clearenv()
+			defer func() {
+				for _, e := range environ {
+					envPair := strings.Split(e, "=")
+					os.Setenv(envPair[0], envPair[1])
+				}
+			}()
+
+			setRequiredEnv()
+			os.Setenv(tt.Key, tt.Value)
+
+			s := &Service{}
+			require.NotNil(t, s.init())
+		})
+	}
+}
+
+// set required test environment variables
+func setRequiredEnv() {
 	os.Setenv("USE_IAM", "true")
 	os.Setenv("GRAPH_STORAGE_BUCKET_REGION", "n/a")
 	os.Setenv("GRAPH_PROGRESS_BUCKET_REGION", "n/a")
@@ -99,8 +293,82 @@ func TestServiceBindRoutesSuccess(t *testing.T) {
 	os.Setenv("DIGESTER_ENDPOINT", "n/a")
 	os.Setenv("DIGESTER_POLLING_TIMEOUT", "1")
 	os.Setenv("DIGESTER_POLLING_INTERVAL", "1")
+}
+
+func TestServiceBindRoutesSuccess(t *testing.T) {
+	environ := os.Environ()
+	os.Clearenv()
+	defer func() {
+		for _, e := range environ {
+			envPair := strings.Split(e, "=")
+			os.Setenv(envPair[0], envPair[1])
+		}
+	}()
+
+	setRequiredEnv()
 
 	router := chi.NewMux()
 	s := &Service{}
 	require.Nil(t, s.BindRoutes(router))
+	// without any schedule, the scheduler returns immediately
+	s.RunScheduler(context.Background())
+	// without any retention policy, the retention job only expires the rollups and notification markers
+	assert.Empty(t, s.retention.Policies)
+	assert.Len(t, s.retention.States, 2)
+}
+
+func TestServiceBackfillWithoutQueuer(t *testing.T) {
+	environ := os.Environ()
+	os.Clearenv()
+	defer func() {
+		for _, e := range environ {
+			envPair := strings.Split(e, "=")
+			os.Setenv(envPair[0], envPair[1])
+		}
+	}()
+
+	setRequiredEnv()
+	os.Unsetenv("STREAM_APPLIANCE_ENDPOINT")
+
+	// the context is cancelled before any window is graphed
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	opts := backfill.Options{Start: start, Stop: start.Add(2 * time.Hour), Step: time.Hour, Concurrency: 1}
+	s := &Service{}
+	require.Equal(t, context.Canceled, s.Backfill(ctx, opts, ioutil.Discard))
+	require.Nil(t, s.Queuer)
+}
+
+func TestServiceRenderWithoutAWS(t *testing.T) {
+	environ := os.Environ()
+	os.Clearenv()
+	defer func() {
+		for _, e := range environ {
+			envPair := strings.Split(e, "=")
+			os.Setenv(envPair[0], envPair[1])
+		}
+	}()
+
+	digest := "2 123456789010 eni-abc123de 172.31.16.139 172.31.16.21 0 80 6 20 1000 1418530010 1818530070 ACCEPT OK\n"
+	var output bytes.Buffer
+	s := &Service{}
+	require.Nil(t, s.Render(context.Background(), ioutil.NopCloser(strings.NewReader(digest)), render.Options{Format: "dot"}, &output))
+	require.Contains(t, output.String(), "n1723116139 -> n172311621")
+	require.Nil(t, s.Storage)
+}
+
+func TestServiceRenderInvalidInventory(t *testing.T) {
+	environ := os.Environ()
+	os.Clearenv()
+	defer func() {
+		for _, e := range environ {
+			envPair := strings.Split(e, "=")
+			os.Setenv(envPair[0], envPair[1])
+		}
+	}()
+
+	os.Setenv("INVENTORY_FILE", "/does/not/exist.json")
+	s := &Service{}
+	require.NotNil(t, s.Render(context.Background(), ioutil.NopCloser(strings.NewReader("")), render.Options{Format: "dot"}, ioutil.Discard))
 }
@@ -0,0 +1,4 @@
+// Package scheduler contains the built in scheduler, which queues the graphs of recurring
+// windows of time without any external trigger.
+//
+package scheduler
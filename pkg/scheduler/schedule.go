@@ -0,0 +1,60 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule describes a recurring window of time to graph. Windows last Interval, and are aligned on
+// multiples of Interval since the zero time, shifted by Offset. A window is only due once Lag has passed
+// since its end, which leaves time for its flow logs to be delivered.
+type Schedule struct {
+	Name     string
+	Interval time.Duration
+	Lag      time.Duration
+	Offset   time.Duration
+}
+
+// Latest returns the stop time of the latest window of the schedule which is due at now
+func (s Schedule) Latest(now time.Time) time.Time {
+	return now.UTC().Add(-s.Lag - s.Offset).Truncate(s.Interval).Add(s.Offset)
+}
+
+// ParseSchedules parses a comma separated list of schedules of the form name=interval:lag[:offset], where
+// each duration is formatted as a Go duration, such as hourly=1h:15m,daily=24h:1h
+func ParseSchedules(s string) ([]Schedule, error) {
+	var schedules []Schedule
+	names := make(map[string]bool)
+	for _, spec := range strings.Split(s, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid schedule %q", spec)
+		}
+		durations := strings.Split(parts[1], ":")
+		if len(durations) < 2 || len(durations) > 3 {
+			return nil, fmt.Errorf("invalid schedule %q", spec)
+		}
+		values := make([]time.Duration, 3)
+		for idx, d := range durations {
+			v, err := time.ParseDuration(d)
+			if err != nil || v < 0 {
+				return nil, fmt.Errorf("invalid duration %q in schedule %q", d, spec)
+			}
+			values[idx] = v
+		}
+		if values[0] < time.Minute || values[2] >= values[0] {
+			return nil, fmt.Errorf("invalid interval in schedule %q", spec)
+		}
+		if names[parts[0]] {
+			return nil, fmt.Errorf("duplicate schedule %q", parts[0])
+		}
+		names[parts[0]] = true
+		schedules = append(schedules, Schedule{Name: parts[0], Interval: values[0], Lag: values[1], Offset: values[2]})
+	}
+	return schedules, nil
+}
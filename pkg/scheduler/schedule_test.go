@@ -0,0 +1,49 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedules(t *testing.T) {
+	schedules, err := ParseSchedules("hourly=1h:15m, daily=24h:1h:2h,")
+	require.Nil(t, err)
+	assert.Equal(t, []Schedule{
+		{Name: "hourly", Interval: time.Hour, Lag: 15 * time.Minute},
+		{Name: "daily", Interval: 24 * time.Hour, Lag: time.Hour, Offset: 2 * time.Hour},
+	}, schedules)
+
+	schedules, err = ParseSchedules("")
+	assert.Nil(t, err)
+	assert.Empty(t, schedules)
+
+	for _, invalid := range []string{
+		"hourly",
+		"=1h:15m",
+		"hourly=1h",
+		"hourly=1h:15m:0s:0s",
+		"hourly=1h:fifteen",
+		"hourly=1h:-15m",
+		"hourly=30s:0s",
+		"hourly=1h:0s:1h",
+		"hourly=1h:15m,hourly=2h:15m",
+	} {
+		_, err := ParseSchedules(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestScheduleLatest(t *testing.T) {
+	now := time.Date(2019, 5, 1, 10, 20, 0, 0, time.UTC)
+	hourly := Schedule{Interval: time.Hour, Lag: 15 * time.Minute}
+	assert.Equal(t, time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC), hourly.Latest(now))
+	assert.Equal(t, time.Date(2019, 5, 1, 9, 0, 0, 0, time.UTC), hourly.Latest(now.Add(-10*time.Minute)))
+
+	daily := Schedule{Interval: 24 * time.Hour, Lag: time.Hour, Offset: 8 * time.Hour}
+	assert.Equal(t, time.Date(2019, 5, 1, 8, 0, 0, 0, time.UTC), daily.Latest(now))
+	assert.Equal(t, time.Date(2019, 4, 30, 8, 0, 0, 0, time.UTC), daily.Latest(now.Add(-2*time.Hour)))
+	assert.Equal(t, time.UTC, daily.Latest(now.In(time.FixedZone("PDT", -7*3600))).Location())
+}
@@ -0,0 +1,143 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/logs"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+const (
+	// DefaultTick is the default amount of time between two checks of the schedules
+	DefaultTick = time.Minute
+
+	// DefaultMaxCatchUp is the default number of missed windows of a schedule queued on a single tick
+	DefaultMaxCatchUp = 100
+
+	schedulePrefix = "schedule/"
+)
+
+// state is the persisted progress of a schedule
+type state struct {
+	// Last is the stop time of the latest window queued for the schedule
+	Last time.Time `json:"last"`
+}
+
+// Scheduler queues the graphs of recurring windows of time. The stop time of the latest window queued for each
+// schedule is kept in Storage, so that the windows missed while the service was down are queued once it is back,
+// up to MaxCatchUp windows per tick. Schedules which have never run start with their latest window.
+//
+// When multiple replicas share the same Storage, each schedule is run while holding its Lock, so that a replica
+// only queues the windows which were not queued by another one. Windows whose graph already exists or is in
+// progress are skipped as well. A replica which holds the lock of a schedule for longer than the lock duration
+// may lose it to another replica, so a window may still be queued twice, and then graphed twice.
+type Scheduler struct {
+	LogProvider types.LogFn
+	Schedules   []Schedule
+	Storage     types.Storage
+	Queuer      types.Queuer
+	Marker      types.Marker
+	// Lock serializes the runs of each schedule across replicas. Defaults to a storage.Lock of Storage.
+	Lock *storage.Lock
+	// Tick is the amount of time between two checks of the schedules. Defaults to DefaultTick.
+	Tick time.Duration
+	// MaxCatchUp is the number of windows of a schedule queued on a single tick. Defaults to DefaultMaxCatchUp.
+	MaxCatchUp int
+}
+
+// Run checks the schedules on every tick until the context is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	tick := s.Tick
+	if tick <= 0 {
+		tick = DefaultTick
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		for _, schedule := range s.Schedules {
+			if err := s.RunSchedule(ctx, schedule, time.Now()); err != nil {
+				s.LogProvider(ctx).Error(logs.DependencyFailure{Dependency: logs.DependencyScheduler, Reason: err.Error()})
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunSchedule queues the windows of the schedule which are due at now, and were not queued yet
+func (s *Scheduler) RunSchedule(ctx context.Context, schedule Schedule, now time.Time) error {
+	key := schedulePrefix + schedule.Name + ".json"
+	return s.lock().Do(ctx, key, func() error {
+		st := &state{}
+		if _, err := storage.LoadJSON(ctx, s.Storage, key, st); err != nil {
+			return err
+		}
+		latest := schedule.Latest(now)
+		next := latest.Add(-schedule.Interval)
+		if !st.Last.IsZero() {
+			next = st.Last.UTC()
+		}
+		var queueErr error
+		for queued := 0; next.Before(latest) && queued < s.maxCatchUp(); queued++ {
+			stop := next.Add(schedule.Interval)
+			if queueErr = s.queue(ctx, schedule, next, stop); queueErr != nil {
+				break
+			}
+			st.Last = stop
+			next = stop
+		}
+		if err := storage.StoreJSON(ctx, s.Storage, key, st); err != nil {
+			return err
+		}
+		return queueErr
+	})
+}
+
+// queue queues the graph of a single window, unless it already exists or is in progress
+func (s *Scheduler) queue(ctx context.Context, schedule Schedule, start, stop time.Time) error {
+	id := graph.ID(start, stop)
+	exists, err := s.Storage.Exists(ctx, id)
+	switch err.(type) {
+	case nil:
+	case types.ErrInProgress:
+		return nil
+	default:
+		return err
+	}
+	if exists {
+		return nil
+	}
+	if err := s.Queuer.Queue(ctx, id, start, stop); err != nil {
+		return err
+	}
+	if err := s.Marker.Mark(ctx, id); err != nil {
+		return err
+	}
+	s.LogProvider(ctx).Info(logs.ScheduledGraph{
+		ID:       id,
+		Schedule: schedule.Name,
+		Start:    start.Format(time.RFC3339),
+		Stop:     stop.Format(time.RFC3339),
+	})
+	return nil
+}
+
+func (s *Scheduler) lock() *storage.Lock {
+	if s.Lock == nil {
+		return &storage.Lock{Storage: s.Storage}
+	}
+	return s.Lock
+}
+
+func (s *Scheduler) maxCatchUp() int {
+	if s.MaxCatchUp <= 0 {
+		return DefaultMaxCatchUp
+	}
+	return s.MaxCatchUp
+}
@@ -0,0 +1,150 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/logevent"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage/storagetest"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var hourly = Schedule{Name: "hourly", Interval: time.Hour, Lag: 15 * time.Minute}
+
+func testContext() context.Context {
+	return logevent.NewContext(context.Background(), logevent.New(logevent.Config{Output: ioutil.Discard}))
+}
+
+func testLock(memory *storagetest.Memory) *storage.Lock {
+	return &storage.Lock{Storage: memory, Wait: time.Millisecond, Settle: time.Millisecond}
+}
+
+func storedState(t *testing.T, memory *storagetest.Memory, name string) state {
+	var st state
+	require.Nil(t, json.Unmarshal(memory.Objects[schedulePrefix+name+".json"], &st))
+	return st
+}
+
+func TestRunScheduleFirstRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2019, 5, 1, 10, 20, 0, 0, time.UTC)
+	start, stop := now.Add(-80*time.Minute).Truncate(time.Hour), now.Truncate(time.Hour)
+	id := graph.ID(start, stop)
+	memory := storagetest.NewMemory()
+	mockQueuer := NewMockQueuer(ctrl)
+	mockQueuer.EXPECT().Queue(gomock.Any(), id, start, stop).Return(nil)
+	mockMarker := NewMockMarker(ctrl)
+	mockMarker.EXPECT().Mark(gomock.Any(), id).Return(nil)
+
+	s := &Scheduler{LogProvider: logevent.FromContext, Storage: memory, Queuer: mockQueuer, Marker: mockMarker, Lock: testLock(memory)}
+	require.Nil(t, s.RunSchedule(testContext(), hourly, now))
+	assert.Equal(t, stop, storedState(t, memory, "hourly").Last)
+
+	// nothing is due until the lag of the next window has passed
+	require.Nil(t, s.RunSchedule(testContext(), hourly, now.Add(50*time.Minute)))
+}
+
+func TestRunScheduleCatchUp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2019, 5, 1, 10, 20, 0, 0, time.UTC)
+	last := time.Date(2019, 5, 1, 5, 0, 0, 0, time.UTC)
+	memory := storagetest.NewMemory()
+	memory.Objects[schedulePrefix+"hourly.json"], _ = json.Marshal(state{Last: last})
+	// the graph of the first missed window already exists
+	memory.Objects[graph.ID(last, last.Add(time.Hour))] = []byte("digraph{}")
+
+	mockQueuer := NewMockQueuer(ctrl)
+	mockMarker := NewMockMarker(ctrl)
+	for start := last.Add(time.Hour); start.Before(last.Add(3 * time.Hour)); start = start.Add(time.Hour) {
+		id := graph.ID(start, start.Add(time.Hour))
+		mockQueuer.EXPECT().Queue(gomock.Any(), id, start, start.Add(time.Hour)).Return(nil)
+		mockMarker.EXPECT().Mark(gomock.Any(), id).Return(nil)
+	}
+
+	s := &Scheduler{LogProvider: logevent.FromContext, Storage: memory, Queuer: mockQueuer, Marker: mockMarker, Lock: testLock(memory), MaxCatchUp: 3}
+	require.Nil(t, s.RunSchedule(testContext(), hourly, now))
+	assert.Equal(t, last.Add(3*time.Hour), storedState(t, memory, "hourly").Last)
+}
+
+func TestRunScheduleReplicas(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2019, 5, 1, 10, 20, 0, 0, time.UTC)
+	start, stop := now.Add(-80*time.Minute).Truncate(time.Hour), now.Truncate(time.Hour)
+	id := graph.ID(start, stop)
+	memory := storagetest.NewMemory()
+	// the window is queued by a single replica
+	mockQueuer := NewMockQueuer(ctrl)
+	mockQueuer.EXPECT().Queue(gomock.Any(), id, start, stop).Return(nil)
+	mockMarker := NewMockMarker(ctrl)
+	mockMarker.EXPECT().Mark(gomock.Any(), id).Return(nil)
+
+	ctx := testContext()
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		s := &Scheduler{LogProvider: logevent.FromContext, Storage: memory, Queuer: mockQueuer, Marker: mockMarker, Lock: testLock(memory)}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = s.RunSchedule(ctx, hourly, now)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, []error{nil, nil}, errs)
+	assert.Equal(t, stop, storedState(t, memory, "hourly").Last)
+	_, locked := memory.Objects[schedulePrefix+"hourly.json.lock.json"]
+	assert.False(t, locked)
+}
+
+func TestRunScheduleErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2019, 5, 1, 10, 20, 0, 0, time.UTC)
+	memory := storagetest.NewMemory()
+	memory.Err = errors.New("oops")
+	s := &Scheduler{LogProvider: logevent.FromContext, Storage: memory, Lock: testLock(memory)}
+	assert.NotNil(t, s.RunSchedule(testContext(), hourly, now))
+
+	memory.Err = nil
+	mockQueuer := NewMockQueuer(ctrl)
+	mockQueuer.EXPECT().Queue(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("oops"))
+	s.Queuer = mockQueuer
+	assert.NotNil(t, s.RunSchedule(testContext(), hourly, now))
+	_, ran := memory.Objects[schedulePrefix+"hourly.json"]
+	assert.True(t, ran)
+	assert.True(t, storedState(t, memory, "hourly").Last.IsZero())
+
+	mockQueuer.EXPECT().Queue(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	mockMarker := NewMockMarker(ctrl)
+	mockMarker.EXPECT().Mark(gomock.Any(), gomock.Any()).Return(errors.New("oops"))
+	s.Marker = mockMarker
+	assert.NotNil(t, s.RunSchedule(testContext(), hourly, now))
+}
+
+func TestRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	memory := storagetest.NewMemory()
+	memory.Err = errors.New("oops")
+	ctx, cancel := context.WithCancel(testContext())
+	cancel()
+	s := &Scheduler{LogProvider: logevent.FromContext, Storage: memory, Schedules: []Schedule{hourly}}
+	s.Run(ctx)
+}
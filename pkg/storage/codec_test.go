@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecs(t *testing.T) {
+	tc := []struct {
+		Name     string
+		Codec    types.Codec
+		Encoding string
+	}{
+		{Name: "gzip", Codec: Gzip{}, Encoding: "gzip"},
+		{Name: "zstd", Codec: Zstd{}, Encoding: "zstd"},
+	}
+	for _, tt := range tc {
+		t.Run(tt.Name, func(t *testing.T) {
+			assert.Equal(t, tt.Encoding, tt.Codec.Encoding())
+
+			var buf bytes.Buffer
+			w, err := tt.Codec.NewWriter(&buf)
+			require.Nil(t, err)
+			_, err = w.Write([]byte(graphContent))
+			require.Nil(t, err)
+			require.Nil(t, w.Close())
+			assert.NotEqual(t, graphContent, buf.String())
+
+			r, err := tt.Codec.NewReader(&buf)
+			require.Nil(t, err)
+			defer r.Close()
+			b, err := ioutil.ReadAll(r)
+			require.Nil(t, err)
+			assert.Equal(t, graphContent, string(b))
+
+			// a corrupt stream fails either when the reader is created or when it is read
+			r, err = tt.Codec.NewReader(bytes.NewReader([]byte("not compressed")))
+			if err == nil {
+				_, err = ioutil.ReadAll(r)
+				r.Close()
+			}
+			assert.NotNil(t, err)
+		})
+	}
+}
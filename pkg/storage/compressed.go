@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+// Compressed is an implementation of Storage which is intended to decorate the S3 implementation.
+//
+// The decorator compresses the objects with the Codec before storing them, and records their content encoding
+// alongside them. Objects are decompressed by Get, using the Codec matching their encoding, unless the context
+// accepts their encoding, see types.WithAcceptEncoding, in which case they are returned as stored.
+//
+// If no Codec is provided, objects are stored as is. Objects stored with other encodings, such as before
+// the Codec was changed, can still be read when their Codec is one of the Codecs.
+type Compressed struct {
+	types.Storage
+	Codec  types.Codec
+	Codecs []types.Codec
+}
+
+// Get returns the object for the given key, decompressed unless the context accepts its encoding
+func (s *Compressed) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	stored, err := s.Storage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	encoding := types.ContentEncoding(stored)
+	if encoding == "" || types.AcceptsEncoding(ctx, encoding) {
+		return stored, nil
+	}
+	codec := s.codec(encoding)
+	if codec == nil {
+		stored.Close()
+		return nil, fmt.Errorf("unsupported content encoding %s of %s", encoding, key)
+	}
+	r, err := codec.NewReader(stored)
+	if err != nil {
+		stored.Close()
+		return nil, err
+	}
+	return &decodedReadCloser{ReadCloser: r, stored: stored}, nil
+}
+
+// Store compresses the object with the Codec, and stores it along with its content encoding
+func (s *Compressed) Store(ctx context.Context, key string, data io.ReadCloser) error {
+	if s.Codec == nil || types.ContentEncoding(data) != "" {
+		return s.Storage.Store(ctx, key, data)
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.compress(pw, data))
+	}()
+	err := s.Storage.Store(ctx, key, types.EncodedReadCloser{ReadCloser: pr, Encoding: s.Codec.Encoding()})
+	// unblock the compression if the object was not fully read
+	pr.CloseWithError(io.ErrClosedPipe)
+	return err
+}
+
+// Presign returns a URL from which the object is downloaded as stored until it expires, if the decorated
+// Storage is a types.Presigner. Otherwise, an error of type types.ErrNotPresignable is returned.
+func (s *Compressed) Presign(ctx context.Context, key string, expires time.Duration) (types.Presigned, error) {
+	presigner, ok := s.Storage.(types.Presigner)
+	if !ok {
+		return types.Presigned{}, types.ErrNotPresignable{Key: key}
+	}
+	return presigner.Presign(ctx, key, expires)
+}
+
+func (s *Compressed) compress(w io.Writer, data io.Reader) error {
+	cw, err := s.Codec.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(cw, data); err != nil {
+		cw.Close()
+		return err
+	}
+	return cw.Close()
+}
+
+func (s *Compressed) codec(encoding string) types.Codec {
+	if s.Codec != nil && s.Codec.Encoding() == encoding {
+		return s.Codec
+	}
+	for _, c := range s.Codecs {
+		if c.Encoding() == encoding {
+			return c
+		}
+	}
+	return nil
+}
+
+// decodedReadCloser closes both the decoding reader and the stored object it reads from
+type decodedReadCloser struct {
+	io.ReadCloser
+	stored io.Closer
+}
+
+func (r *decodedReadCloser) Close() error {
+	r.ReadCloser.Close()
+	return r.stored.Close()
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const graphContent = "digraph {\n\tn1 -> n2;\n}\n"
+
+func gzipped(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(s))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+	return buf.Bytes()
+}
+
+func encoded(b []byte, encoding string) io.ReadCloser {
+	return types.EncodedReadCloser{ReadCloser: ioutil.NopCloser(bytes.NewReader(b)), Encoding: encoding}
+}
+
+func TestCompressedStore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Store(gomock.Any(), key, gomock.Any()).DoAndReturn(func(_ context.Context, _ string, data io.ReadCloser) error {
+		assert.Equal(t, "gzip", types.ContentEncoding(data))
+		r, err := gzip.NewReader(data)
+		require.Nil(t, err)
+		b, err := ioutil.ReadAll(r)
+		require.Nil(t, err)
+		assert.Equal(t, graphContent, string(b))
+		return nil
+	})
+
+	storage := &Compressed{Storage: mockStorage, Codec: Gzip{}}
+	assert.Nil(t, storage.Store(context.Background(), key, ioutil.NopCloser(bytes.NewReader([]byte(graphContent)))))
+}
+
+func TestCompressedStoreError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the storage fails before reading the whole object, which must not block the compression
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Store(gomock.Any(), key, gomock.Any()).Return(errors.New("oops"))
+
+	storage := &Compressed{Storage: mockStorage, Codec: Gzip{}}
+	assert.NotNil(t, storage.Store(context.Background(), key, ioutil.NopCloser(bytes.NewReader([]byte(graphContent)))))
+}
+
+func TestCompressedStoreWithoutCodec(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	data := ioutil.NopCloser(bytes.NewReader([]byte(graphContent)))
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Store(gomock.Any(), key, data).Return(nil)
+
+	storage := &Compressed{Storage: mockStorage}
+	assert.Nil(t, storage.Store(context.Background(), key, data))
+}
+
+func TestCompressedGet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Get(gomock.Any(), key).Return(encoded(gzipped(t, graphContent), "gzip"), nil)
+
+	// objects stored with a previous codec are still decoded
+	storage := &Compressed{Storage: mockStorage, Codecs: []types.Codec{Gzip{}}}
+	r, err := storage.Get(context.Background(), key)
+	require.Nil(t, err)
+	defer r.Close()
+	assert.Equal(t, "", types.ContentEncoding(r))
+	b, _ := ioutil.ReadAll(r)
+	assert.Equal(t, graphContent, string(b))
+}
+
+func TestCompressedGetAccepted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	compressed := gzipped(t, graphContent)
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Get(gomock.Any(), key).Return(encoded(compressed, "gzip"), nil)
+
+	storage := &Compressed{Storage: mockStorage, Codec: Gzip{}}
+	r, err := storage.Get(types.WithAcceptEncoding(context.Background(), "br", "gzip"), key)
+	require.Nil(t, err)
+	defer r.Close()
+	assert.Equal(t, "gzip", types.ContentEncoding(r))
+	b, _ := ioutil.ReadAll(r)
+	assert.Equal(t, compressed, b)
+}
+
+func TestCompressedGetUncompressed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Get(gomock.Any(), key).Return(ioutil.NopCloser(bytes.NewReader([]byte(graphContent))), nil)
+
+	storage := &Compressed{Storage: mockStorage, Codec: Gzip{}}
+	r, err := storage.Get(context.Background(), key)
+	require.Nil(t, err)
+	defer r.Close()
+	b, _ := ioutil.ReadAll(r)
+	assert.Equal(t, graphContent, string(b))
+}
+
+func TestCompressedGetError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := NewMockStorage(ctrl)
+	storage := &Compressed{Storage: mockStorage, Codec: Gzip{}}
+
+	mockStorage.EXPECT().Get(gomock.Any(), key).Return(nil, types.ErrNotFound{ID: key})
+	_, err := storage.Get(context.Background(), key)
+	assert.IsType(t, types.ErrNotFound{}, err)
+
+	mockStorage.EXPECT().Get(gomock.Any(), key).Return(encoded([]byte("compressed"), "br"), nil)
+	_, err = storage.Get(context.Background(), key)
+	assert.NotNil(t, err)
+
+	mockStorage.EXPECT().Get(gomock.Any(), key).Return(encoded([]byte("not gzip"), "gzip"), nil)
+	_, err = storage.Get(context.Background(), key)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+// DeleteGraph removes a graph from the storage, along with the outputs stored alongside it, which are named
+// by appending each of the sidecar suffixes to the ID of the graph.
+//
+// The sidecars are removed before the graph, and the index entry and metadata of the graph are removed last,
+// so that a deletion which fails part way can be retried by anything which finds the graph through the index.
+// Deleting a graph which does not exist succeeds.
+func DeleteGraph(ctx context.Context, storage types.Storage, id string, sidecars []string) error {
+	metadata, err := LoadMetadata(ctx, storage, id)
+	if err != nil {
+		return err
+	}
+	for _, suffix := range sidecars {
+		if err := storage.Delete(ctx, id+suffix); err != nil {
+			return err
+		}
+	}
+	if err := storage.Delete(ctx, id); err != nil {
+		return err
+	}
+	// graphs created before their metadata was stored have no index entry
+	if metadata == nil {
+		return nil
+	}
+	if err := storage.Delete(ctx, graph.IndexKey(id, metadata.Start, metadata.Stop)); err != nil {
+		return err
+	}
+	return storage.Delete(ctx, id+graph.MetadataSuffix)
+}
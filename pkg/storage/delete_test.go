@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteGraph(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(time.Hour)
+	id := graph.ID(start, stop)
+	metadata, _ := json.Marshal(graph.Metadata{ID: id, Start: start, Stop: stop})
+	storage := NewMockStorage(ctrl)
+	gomock.InOrder(
+		storage.EXPECT().Get(gomock.Any(), id+graph.MetadataSuffix).Return(ioutil.NopCloser(bytes.NewReader(metadata)), nil),
+		storage.EXPECT().Delete(gomock.Any(), id+".stats.json").Return(nil),
+		storage.EXPECT().Delete(gomock.Any(), id).Return(nil),
+		storage.EXPECT().Delete(gomock.Any(), graph.IndexKey(id, start, stop)).Return(nil),
+		storage.EXPECT().Delete(gomock.Any(), id+graph.MetadataSuffix).Return(nil),
+	)
+	assert.Nil(t, DeleteGraph(context.Background(), storage, id, []string{".stats.json"}))
+}
+
+func TestDeleteGraphWithoutMetadata(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := NewMockStorage(ctrl)
+	gomock.InOrder(
+		storage.EXPECT().Get(gomock.Any(), "abc"+graph.MetadataSuffix).Return(nil, types.ErrNotFound{ID: "abc"}),
+		storage.EXPECT().Delete(gomock.Any(), "abc.stats.json").Return(nil),
+		storage.EXPECT().Delete(gomock.Any(), "abc").Return(nil),
+	)
+	assert.Nil(t, DeleteGraph(context.Background(), storage, "abc", []string{".stats.json"}))
+}
+
+func TestDeleteGraphError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := NewMockStorage(ctrl)
+	storage.EXPECT().Get(gomock.Any(), "abc"+graph.MetadataSuffix).Return(nil, errors.New("oops"))
+	assert.NotNil(t, DeleteGraph(context.Background(), storage, "abc", nil))
+
+	storage.EXPECT().Get(gomock.Any(), "abc"+graph.MetadataSuffix).Return(ioutil.NopCloser(bytes.NewReader([]byte("{"))), nil)
+	assert.NotNil(t, DeleteGraph(context.Background(), storage, "abc", nil))
+
+	// the graph is kept when one of its sidecars could not be deleted
+	storage.EXPECT().Get(gomock.Any(), "abc"+graph.MetadataSuffix).Return(nil, types.ErrNotFound{ID: "abc"})
+	storage.EXPECT().Delete(gomock.Any(), "abc.stats.json").Return(errors.New("oops"))
+	assert.NotNil(t, DeleteGraph(context.Background(), storage, "abc", []string{".stats.json"}))
+}
@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// Gzip is the Codec of the gzip content encoding
+type Gzip struct{}
+
+// Encoding returns gzip
+func (Gzip) Encoding() string {
+	return "gzip"
+}
+
+// NewWriter returns a writer which compresses to w
+func (Gzip) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+// NewReader returns a reader which decompresses r
+func (Gzip) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
@@ -18,6 +18,9 @@ const inProgressSuffix = "_in_progress"
 //
 // The decorator will check if a graph is in progress, and if so, will return types.ErrInProgress.
 // On a successful Store operation, the decorator will remove the graph's "in progress" status.
+//
+// A graph which is in progress but was already stored is being regenerated. Its previous version
+// is still returned by Get until the new one is stored, while Exists reports it as in progress.
 type InProgress struct {
 	Bucket  string
 	Timeout time.Duration
@@ -27,16 +30,21 @@ type InProgress struct {
 
 // Get returns the graph for the given key.
 //
-// If the graph is in the process of being created, an error will be returned of type types.ErrInProgress
+// If the graph is in the process of being created, an error will be returned of type types.ErrInProgress,
+// unless a previous version of the graph exists, in which case the previous version is returned
 func (s *InProgress) Get(ctx context.Context, key string) (io.ReadCloser, error) {
 	inProgress, err := s.isInProgress(ctx, key)
 	if err != nil {
 		return nil, err
 	}
-	if inProgress {
+	if !inProgress {
+		return s.Storage.Get(ctx, key)
+	}
+	previous, err := s.Storage.Get(ctx, key)
+	if _, ok := err.(types.ErrNotFound); ok {
 		return nil, types.ErrInProgress{Key: key}
 	}
-	return s.Storage.Get(ctx, key)
+	return previous, err
 }
 
 // Exists returns true if the graph exists, but does not download the graph body.
@@ -53,6 +61,40 @@ func (s *InProgress) Exists(ctx context.Context, key string) (bool, error) {
 	return s.Storage.Exists(ctx, key)
 }
 
+// Delete removes the graph along with its "in progress" status, which clears the status of a graph
+// whose creation failed without removing it.
+func (s *InProgress) Delete(ctx context.Context, key string) error {
+	_, err := s.Client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key + inProgressSuffix),
+	})
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	return s.Storage.Delete(ctx, key)
+}
+
+// Presign returns a URL from which the graph is downloaded until it expires, if the decorated Storage is a
+// types.Presigner. Otherwise, an error of type types.ErrNotPresignable is returned.
+//
+// If the graph is in the process of being created, an error will be returned of type types.ErrInProgress,
+// unless a previous version of the graph exists, in which case the URL of the previous version is returned
+func (s *InProgress) Presign(ctx context.Context, key string, expires time.Duration) (types.Presigned, error) {
+	presigner, ok := s.Storage.(types.Presigner)
+	if !ok {
+		return types.Presigned{}, types.ErrNotPresignable{Key: key}
+	}
+	inProgress, err := s.isInProgress(ctx, key)
+	if err != nil {
+		return types.Presigned{}, err
+	}
+	presigned, err := presigner.Presign(ctx, key, expires)
+	if _, ok := err.(types.ErrNotFound); ok && inProgress {
+		return types.Presigned{}, types.ErrInProgress{Key: key}
+	}
+	return presigned, err
+}
+
 func (s *InProgress) isInProgress(ctx context.Context, key string) (bool, error) {
 	res, err := s.Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(s.Bucket),
@@ -14,6 +14,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetNotInProgress(t *testing.T) {
@@ -79,11 +80,14 @@ func TestGetInProgressBeforeTimeout(t *testing.T) {
 
 	mockClient := NewMockS3API(ctrl)
 	mockClient.EXPECT().GetObjectWithContext(gomock.Any(), expectedInput).Return(getOutput, nil)
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Get(gomock.Any(), key).Return(nil, types.ErrNotFound{ID: key})
 
 	ip := &InProgress{
 		Timeout: time.Hour,
 		Bucket:  bucket,
 		Client:  mockClient,
+		Storage: mockStorage,
 	}
 	_, err := ip.Get(context.Background(), key)
 	assert.NotNil(t, err)
@@ -91,6 +95,32 @@ func TestGetInProgressBeforeTimeout(t *testing.T) {
 	assert.True(t, ok)
 }
 
+func TestGetInProgressPreviousVersion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	getOutput := &s3.GetObjectOutput{
+		Body: ioutil.NopCloser(bytes.NewBufferString(time.Now().Format(time.RFC3339))),
+	}
+	mockClient := NewMockS3API(ctrl)
+	mockClient.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any()).Return(getOutput, nil)
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Get(gomock.Any(), key).Return(ioutil.NopCloser(bytes.NewReader([]byte("previous"))), nil)
+
+	// a graph being regenerated is still served from its previous version
+	ip := &InProgress{
+		Timeout: time.Hour,
+		Bucket:  bucket,
+		Client:  mockClient,
+		Storage: mockStorage,
+	}
+	res, err := ip.Get(context.Background(), key)
+	require.Nil(t, err)
+	defer res.Close()
+	data, _ := ioutil.ReadAll(res)
+	assert.Equal(t, "previous", string(data))
+}
+
 func TestGetInProgressAfterTimeout(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -221,3 +251,42 @@ func TestExistsInProgressAfterTimeout(t *testing.T) {
 	assert.Nil(t, err)
 	assert.True(t, exists)
 }
+
+func TestDeleteInProgress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := NewMockS3API(ctrl)
+	mockClient.EXPECT().DeleteObjectWithContext(gomock.Any(), &s3.DeleteObjectInput{
+		Key:    aws.String(key + "_in_progress"),
+		Bucket: aws.String(bucket),
+	}).Return(nil, nil)
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Delete(gomock.Any(), key).Return(nil)
+
+	ip := &InProgress{
+		Bucket:  bucket,
+		Client:  mockClient,
+		Storage: mockStorage,
+	}
+	assert.Nil(t, ip.Delete(context.Background(), key))
+}
+
+func TestDeleteInProgressErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := NewMockS3API(ctrl)
+	mockClient.EXPECT().DeleteObjectWithContext(gomock.Any(), gomock.Any()).Return(nil, errors.New("oops"))
+	ip := &InProgress{
+		Bucket: bucket,
+		Client: mockClient,
+	}
+	assert.NotNil(t, ip.Delete(context.Background(), key))
+
+	mockClient.EXPECT().DeleteObjectWithContext(gomock.Any(), gomock.Any()).Return(nil, nil)
+	mockStorage := NewMockStorage(ctrl)
+	mockStorage.EXPECT().Delete(gomock.Any(), key).Return(errors.New("oops"))
+	ip.Storage = mockStorage
+	assert.NotNil(t, ip.Delete(context.Background(), key))
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+// LoadJSON decodes the JSON document stored under key into v. It returns false, with no error,
+// if the document does not exist.
+func LoadJSON(ctx context.Context, storage types.Storage, key string, v interface{}) (bool, error) {
+	r, err := storage.Get(ctx, key)
+	switch err.(type) {
+	case nil:
+	case types.ErrNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+	defer r.Close()
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// StoreJSON encodes v as JSON and stores it under key
+func StoreJSON(ctx context.Context, storage types.Storage, key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return storage.Store(ctx, key, ioutil.NopCloser(bytes.NewReader(b)))
+}
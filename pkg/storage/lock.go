@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultLockDuration is the default amount of time after which a lock which was not released expires
+	DefaultLockDuration = time.Minute
+
+	// DefaultLockWait is the default amount of time between two attempts to acquire a held lock
+	DefaultLockWait = 200 * time.Millisecond
+
+	// DefaultLockSettle is the default amount of time a claim is left to settle before it is read back
+	DefaultLockSettle = 200 * time.Millisecond
+
+	lockSuffix = ".lock.json"
+)
+
+// lease identifies the holder of a lock
+type lease struct {
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires"`
+}
+
+// Lock serializes the read-modify-write updates of the objects of Storage across the replicas sharing it. The
+// lock of an object is a lease stored alongside it, which a replica claims when it is not held by another one.
+// Each claim is read back once it has settled, so that the replica whose claim was stored last wins concurrent
+// claims. A lease expires after Duration, so that a replica which fails while it holds the lock does not block
+// the others.
+type Lock struct {
+	Storage types.Storage
+	// Duration is how long a lock is held at most. Defaults to DefaultLockDuration.
+	Duration time.Duration
+	// Wait is the amount of time between two attempts to acquire a held lock. Defaults to DefaultLockWait.
+	Wait time.Duration
+	// Settle is how long a claim is left to settle before it is read back. Defaults to DefaultLockSettle.
+	Settle time.Duration
+}
+
+// Do calls fn while holding the lock of the object stored under key. It waits until the lock is acquired, or
+// returns the error of the context once it is done.
+func (l *Lock) Do(ctx context.Context, key string, fn func() error) error {
+	owner := uuid.New().String()
+	lockKey := key + lockSuffix
+	if err := l.acquire(ctx, lockKey, owner); err != nil {
+		return err
+	}
+	err := fn()
+	if releaseErr := l.release(ctx, lockKey, owner); err == nil {
+		err = releaseErr
+	}
+	return err
+}
+
+// acquire claims the lock stored under lockKey for owner, once it is free or expired
+func (l *Lock) acquire(ctx context.Context, lockKey, owner string) error {
+	for {
+		current, err := l.load(ctx, lockKey)
+		if err != nil {
+			return err
+		}
+		if current == nil || !current.Expires.After(time.Now()) {
+			claimed, err := l.claim(ctx, lockKey, owner)
+			if err != nil || claimed {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(durationOrDefault(l.Wait, DefaultLockWait)):
+		}
+	}
+}
+
+// claim stores a lease for owner, and reports whether it is still held by owner once it has settled
+func (l *Lock) claim(ctx context.Context, lockKey, owner string) (bool, error) {
+	b, err := json.Marshal(lease{Owner: owner, Expires: time.Now().Add(durationOrDefault(l.Duration, DefaultLockDuration))})
+	if err != nil {
+		return false, err
+	}
+	if err := l.Storage.Store(ctx, lockKey, ioutil.NopCloser(bytes.NewReader(b))); err != nil {
+		return false, err
+	}
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-time.After(durationOrDefault(l.Settle, DefaultLockSettle)):
+	}
+	claimed, err := l.load(ctx, lockKey)
+	if err != nil {
+		return false, err
+	}
+	return claimed != nil && claimed.Owner == owner, nil
+}
+
+// release deletes the lease stored under lockKey, unless it expired and was claimed by another owner
+func (l *Lock) release(ctx context.Context, lockKey, owner string) error {
+	current, err := l.load(ctx, lockKey)
+	if err != nil || current == nil || current.Owner != owner {
+		return err
+	}
+	return l.Storage.Delete(ctx, lockKey)
+}
+
+// load returns the lease stored under lockKey, or nil if there is none
+func (l *Lock) load(ctx context.Context, lockKey string) (*lease, error) {
+	stored, err := l.Storage.Get(ctx, lockKey)
+	switch err.(type) {
+	case nil:
+		defer stored.Close()
+	case types.ErrNotFound:
+		return nil, nil
+	default:
+		return nil, err
+	}
+	current := &lease{}
+	if err := json.NewDecoder(stored).Decode(current); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+func durationOrDefault(d, fallback time.Duration) time.Duration {
+	if d <= 0 {
+		return fallback
+	}
+	return d
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLock(storage *storagetest.Memory) *Lock {
+	return &Lock{Storage: storage, Wait: time.Millisecond, Settle: 20 * time.Millisecond}
+}
+
+func TestLockDo(t *testing.T) {
+	storage := storagetest.NewMemory()
+	called := false
+	err := newTestLock(storage).Do(context.Background(), key, func() error {
+		called = true
+		assert.Contains(t, storage.Objects, key+lockSuffix)
+		return nil
+	})
+	require.Nil(t, err)
+	assert.True(t, called)
+	assert.NotContains(t, storage.Objects, key+lockSuffix)
+
+	err = newTestLock(storage).Do(context.Background(), key, func() error { return errors.New("oops") })
+	assert.NotNil(t, err)
+	assert.NotContains(t, storage.Objects, key+lockSuffix)
+}
+
+func TestLockWaitsForExpiry(t *testing.T) {
+	storage := storagetest.NewMemory()
+	expires := time.Now().Add(50 * time.Millisecond)
+	storage.Objects[key+lockSuffix], _ = json.Marshal(lease{Owner: "other", Expires: expires})
+
+	err := newTestLock(storage).Do(context.Background(), key, func() error {
+		assert.False(t, time.Now().Before(expires))
+		return nil
+	})
+	require.Nil(t, err)
+}
+
+func TestLockContextDone(t *testing.T) {
+	storage := storagetest.NewMemory()
+	storage.Objects[key+lockSuffix], _ = json.Marshal(lease{Owner: "other", Expires: time.Now().Add(time.Hour)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := newTestLock(storage).Do(ctx, key, func() error {
+		t.Fatal("the lock is held by another owner")
+		return nil
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Contains(t, storage.Objects, key+lockSuffix)
+}
+
+func TestLockStorageError(t *testing.T) {
+	storage := storagetest.NewMemory()
+	storage.Err = errors.New("oops")
+	err := newTestLock(storage).Do(context.Background(), key, func() error { return nil })
+	assert.NotNil(t, err)
+}
+
+func TestLockSerializesUpdates(t *testing.T) {
+	storage := storagetest.NewMemory()
+	storage.Objects[key] = []byte("0")
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := newTestLock(storage).Do(context.Background(), key, func() error {
+				r, _ := storage.Get(context.Background(), key)
+				var count int
+				assert.Nil(t, json.NewDecoder(r).Decode(&count))
+				return storage.Store(context.Background(), key, ioutil.NopCloser(strings.NewReader(strconv.Itoa(count+1))))
+			})
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, "5", string(storage.Objects[key]))
+}
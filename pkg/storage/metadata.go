@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+// LoadMetadata returns the metadata stored alongside a graph, or nil if none is stored, such as for the graphs
+// created before their metadata was stored
+func LoadMetadata(ctx context.Context, storage types.Storage, id string) (*graph.Metadata, error) {
+	stored, err := storage.Get(ctx, id+graph.MetadataSuffix)
+	switch err.(type) {
+	case nil:
+		defer stored.Close()
+	case types.ErrNotFound:
+		return nil, nil
+	default:
+		return nil, err
+	}
+	metadata := &graph.Metadata{}
+	if err := json.NewDecoder(stored).Decode(metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
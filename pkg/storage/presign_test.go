@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectPresign sets up the S3 mock to presign the GetObject requests with a real client, which needs no network
+func expectPresign(t *testing.T, mockS3 *MockS3API, metadata map[string]*string) {
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-west-2"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.Nil(t, err)
+	client := s3.New(sess)
+	mockS3.EXPECT().HeadObjectWithContext(gomock.Any(), &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key + ".dot"),
+	}).Return(&s3.HeadObjectOutput{ContentLength: aws.Int64(42), Metadata: metadata}, nil)
+	mockS3.EXPECT().GetObjectRequest(gomock.Any()).DoAndReturn(func(input *s3.GetObjectInput) (*request.Request, *s3.GetObjectOutput) {
+		return client.GetObjectRequest(input)
+	})
+}
+
+func TestPresign(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockS3 := NewMockS3API(ctrl)
+	expectPresign(t, mockS3, map[string]*string{"Encoding": aws.String("gzip")})
+
+	storage := &S3{Bucket: bucket, Client: mockS3}
+	presigned, err := storage.Presign(context.Background(), key, 5*time.Minute)
+	require.Nil(t, err)
+	assert.Equal(t, int64(42), presigned.Size)
+	assert.Equal(t, "gzip", presigned.Encoding)
+	u, err := url.Parse(presigned.URL)
+	require.Nil(t, err)
+	assert.Contains(t, u.Path, key+".dot")
+	assert.Equal(t, "300", u.Query().Get("X-Amz-Expires"))
+	assert.Equal(t, "gzip", u.Query().Get("response-content-encoding"))
+}
+
+func TestPresignNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockS3 := NewMockS3API(ctrl)
+	mockS3.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any()).Return(nil, awserr.New("NotFound", "", errors.New("")))
+
+	storage := &S3{Bucket: bucket, Client: mockS3}
+	_, err := storage.Presign(context.Background(), key, time.Minute)
+	assert.IsType(t, types.ErrNotFound{}, err)
+}
+
+func TestPresignInProgress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	marker := &s3.GetObjectOutput{
+		Body: ioutil.NopCloser(bytes.NewBufferString(time.Now().Format(time.RFC3339))),
+	}
+	mockClient := NewMockS3API(ctrl)
+	mockClient.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any()).Return(marker, nil)
+	mockS3 := NewMockS3API(ctrl)
+	mockS3.EXPECT().HeadObjectWithContext(gomock.Any(), gomock.Any()).Return(nil, awserr.New("NotFound", "", errors.New("")))
+
+	ip := &InProgress{
+		Bucket:  bucket,
+		Client:  mockClient,
+		Storage: &Compressed{Storage: &S3{Bucket: bucket, Client: mockS3}},
+		Timeout: time.Minute,
+	}
+	_, err := ip.Presign(context.Background(), key, time.Minute)
+	assert.IsType(t, types.ErrInProgress{}, err)
+}
+
+func TestPresignInProgressPreviousVersion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	marker := &s3.GetObjectOutput{
+		Body: ioutil.NopCloser(bytes.NewBufferString(time.Now().Format(time.RFC3339))),
+	}
+	mockClient := NewMockS3API(ctrl)
+	mockClient.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any()).Return(marker, nil)
+	mockS3 := NewMockS3API(ctrl)
+	expectPresign(t, mockS3, nil)
+
+	ip := &InProgress{
+		Bucket:  bucket,
+		Client:  mockClient,
+		Storage: &Compressed{Storage: &S3{Bucket: bucket, Client: mockS3}},
+		Timeout: time.Minute,
+	}
+	presigned, err := ip.Presign(context.Background(), key, time.Minute)
+	require.Nil(t, err)
+	assert.NotEmpty(t, presigned.URL)
+	assert.Empty(t, presigned.Encoding)
+}
+
+func TestPresignNotPresignable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ip := &InProgress{Bucket: bucket, Client: NewMockS3API(ctrl), Storage: NewMockStorage(ctrl)}
+	_, err := ip.Presign(context.Background(), key, time.Minute)
+	assert.IsType(t, types.ErrNotPresignable{}, err)
+
+	compressed := &Compressed{Storage: NewMockStorage(ctrl)}
+	_, err = compressed.Presign(context.Background(), key, time.Minute)
+	assert.IsType(t, types.ErrNotPresignable{}, err)
+}
@@ -3,7 +3,10 @@ package storage
 import (
 	"context"
 	"io"
+	"path"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
 	"github.com/aws/aws-sdk-go/aws"
@@ -14,9 +17,21 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
 )
 
-const keySuffix = ".dot"
+const (
+	keySuffix = ".dot"
 
-// S3 implements the Storage interface and uses S3 as the backing store for graph
+	// encodingMetadata is the user metadata which records the content encoding of an object. The Content-Encoding
+	// of the object is not used, since the HTTP client of the AWS SDK transparently decodes gzip responses.
+	encodingMetadata = "encoding"
+)
+
+// S3 implements the Storage interface and uses S3 as the backing store for graph.
+//
+// Graphs are stored with a .dot extension. Keys which already carry an extension, such as
+// those used for JSON documents stored alongside a graph, are stored as is.
+//
+// The content encoding of the objects stored with a reader implementing types.Encoded is recorded in
+// their metadata, and the readers returned by Get for these objects implement types.Encoded.
 type S3 struct {
 	Bucket   string
 	Client   s3iface.S3API
@@ -24,17 +39,20 @@ type S3 struct {
 	once     sync.Once
 }
 
-// Get returns the graph for the given key. The graph is returned as a gzipped payload.
+// Get returns the graph for the given key, as it was stored.
 // It is the caller's responsibility to call Close on the Reader when done.
 func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
 	input := &s3.GetObjectInput{
 		Bucket: aws.String(s.Bucket),
-		Key:    aws.String(key + keySuffix),
+		Key:    aws.String(objectKey(key)),
 	}
 	res, err := s.Client.GetObjectWithContext(ctx, input)
 	if err != nil {
 		return nil, parseNotFound(err, key)
 	}
+	if encoding := contentEncoding(res.Metadata); encoding != "" {
+		return types.EncodedReadCloser{ReadCloser: res.Body, Encoding: encoding}, nil
+	}
 	return res.Body, nil
 }
 
@@ -42,7 +60,7 @@ func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
 func (s *S3) Exists(ctx context.Context, key string) (bool, error) {
 	input := &s3.HeadObjectInput{
 		Bucket: aws.String(s.Bucket),
-		Key:    aws.String(key + keySuffix),
+		Key:    aws.String(objectKey(key)),
 	}
 	_, err := s.Client.HeadObjectWithContext(ctx, input)
 	if err == nil {
@@ -59,20 +77,101 @@ func (s *S3) Store(ctx context.Context, key string, data io.ReadCloser) error {
 	// lazily initialize uploader with the s3 client
 	s.once.Do(s.initUploader)
 
-	_, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+	input := &s3manager.UploadInput{
 		Bucket: aws.String(s.Bucket),
-		Key:    aws.String(key + keySuffix),
+		Key:    aws.String(objectKey(key)),
 		Body:   data,
+	}
+	if encoding := types.ContentEncoding(data); encoding != "" {
+		input.Metadata = map[string]*string{encodingMetadata: aws.String(encoding)}
+	}
+	_, err := s.uploader.UploadWithContext(ctx, input)
+	return err
+}
+
+// Delete removes the graph. Deleting a graph which does not exist is not an error.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	_, err := s.Client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(objectKey(key)),
 	})
+	if isNotFound(err) {
+		return nil
+	}
 	return err
 }
 
+// List returns up to limit keys which start with prefix, in lexicographical order, after the key after.
+// Keys are returned as stored, including the extension of graphs.
+func (s *S3) List(ctx context.Context, prefix string, after string, limit int) ([]string, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(s.Bucket),
+		Prefix:  aws.String(prefix),
+		MaxKeys: aws.Int64(int64(limit)),
+	}
+	if after != "" {
+		input.StartAfter = aws.String(after)
+	}
+	res, err := s.Client.ListObjectsV2WithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(res.Contents))
+	for _, object := range res.Contents {
+		keys = append(keys, aws.StringValue(object.Key))
+	}
+	return keys, nil
+}
+
+// Presign returns a URL from which the graph is downloaded until it expires. The Content-Encoding of the
+// download is set to the content encoding the graph is stored with.
+func (s *S3) Presign(ctx context.Context, key string, expires time.Duration) (types.Presigned, error) {
+	head, err := s.Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(objectKey(key)),
+	})
+	if err != nil {
+		return types.Presigned{}, parseNotFound(err, key)
+	}
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(objectKey(key)),
+	}
+	encoding := contentEncoding(head.Metadata)
+	if encoding != "" {
+		input.ResponseContentEncoding = aws.String(encoding)
+	}
+	req, _ := s.Client.GetObjectRequest(input)
+	u, err := req.Presign(expires)
+	if err != nil {
+		return types.Presigned{}, err
+	}
+	return types.Presigned{URL: u, Size: aws.Int64Value(head.ContentLength), Encoding: encoding}, nil
+}
+
 func (s *S3) initUploader() {
 	if s.uploader == nil {
 		s.uploader = s3manager.NewUploaderWithClient(s.Client)
 	}
 }
 
+func objectKey(key string) string {
+	if path.Ext(key) != "" {
+		return key
+	}
+	return key + keySuffix
+}
+
+// contentEncoding returns the content encoding recorded in the metadata of an object
+func contentEncoding(metadata map[string]*string) string {
+	for name, value := range metadata {
+		if strings.EqualFold(name, encodingMetadata) {
+			return aws.StringValue(value)
+		}
+	}
+	return ""
+}
+
 func isNotFound(err error) bool {
 	aErr, ok := err.(awserr.Error)
 	return ok && (aErr.Code() == s3.ErrCodeNoSuchKey || aErr.Code() == "NotFound") // NotFound is an undocumented error code with no provided constant
@@ -49,6 +49,31 @@ func TestGet(t *testing.T) {
 	assert.Equal(t, string(expectedBody), string(data))
 }
 
+func TestGetWithExtension(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	expectedInput := &s3.GetObjectInput{
+		Key:    aws.String(key + ".stats.json"),
+		Bucket: aws.String(bucket),
+	}
+	output := &s3.GetObjectOutput{
+		Body: ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+	}
+
+	mockS3 := NewMockS3API(ctrl)
+	mockS3.EXPECT().GetObjectWithContext(gomock.Any(), expectedInput).Return(output, nil)
+
+	storage := &S3{
+		Bucket: bucket,
+		Client: mockS3,
+	}
+
+	r, err := storage.Get(context.Background(), key+".stats.json")
+	assert.Nil(t, err)
+	defer r.Close()
+}
+
 func TestGetNotFound(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -212,3 +237,128 @@ func TestStoreError(t *testing.T) {
 	err := storage.Store(context.Background(), key, input)
 	assert.NotNil(t, err)
 }
+
+func TestDelete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := NewMockS3API(ctrl)
+	mockClient.EXPECT().DeleteObjectWithContext(gomock.Any(), &s3.DeleteObjectInput{
+		Key:    aws.String(key + ".dot"),
+		Bucket: aws.String(bucket),
+	}).Return(nil, nil)
+	mockClient.EXPECT().DeleteObjectWithContext(gomock.Any(), &s3.DeleteObjectInput{
+		Key:    aws.String(key + ".stats.json"),
+		Bucket: aws.String(bucket),
+	}).Return(nil, awserr.New(s3.ErrCodeNoSuchKey, "", errors.New("")))
+
+	storage := &S3{
+		Bucket: bucket,
+		Client: mockClient,
+	}
+	assert.Nil(t, storage.Delete(context.Background(), key))
+	// a missing object is already deleted
+	assert.Nil(t, storage.Delete(context.Background(), key+".stats.json"))
+}
+
+func TestDeleteError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := NewMockS3API(ctrl)
+	mockClient.EXPECT().DeleteObjectWithContext(gomock.Any(), gomock.Any()).Return(nil, errors.New("oops"))
+
+	storage := &S3{
+		Bucket: bucket,
+		Client: mockClient,
+	}
+	assert.NotNil(t, storage.Delete(context.Background(), key))
+}
+
+func TestList(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := NewMockS3API(ctrl)
+	mockClient.EXPECT().ListObjectsV2WithContext(gomock.Any(), &s3.ListObjectsV2Input{
+		Bucket:  aws.String(bucket),
+		Prefix:  aws.String("graphs/"),
+		MaxKeys: aws.Int64(2),
+	}).Return(&s3.ListObjectsV2Output{Contents: []*s3.Object{{Key: aws.String("graphs/a.json")}, {Key: aws.String("graphs/b.json")}}}, nil)
+	mockClient.EXPECT().ListObjectsV2WithContext(gomock.Any(), &s3.ListObjectsV2Input{
+		Bucket:     aws.String(bucket),
+		Prefix:     aws.String("graphs/"),
+		MaxKeys:    aws.Int64(2),
+		StartAfter: aws.String("graphs/b.json"),
+	}).Return(&s3.ListObjectsV2Output{}, nil)
+
+	storage := &S3{
+		Bucket: bucket,
+		Client: mockClient,
+	}
+	keys, err := storage.List(context.Background(), "graphs/", "", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"graphs/a.json", "graphs/b.json"}, keys)
+	keys, err = storage.List(context.Background(), "graphs/", "graphs/b.json", 2)
+	assert.Nil(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestListError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := NewMockS3API(ctrl)
+	mockClient.EXPECT().ListObjectsV2WithContext(gomock.Any(), gomock.Any()).Return(nil, errors.New("oops"))
+
+	storage := &S3{
+		Bucket: bucket,
+		Client: mockClient,
+	}
+	_, err := storage.List(context.Background(), "graphs/", "", 10)
+	assert.NotNil(t, err)
+}
+
+func TestGetEncoded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	output := &s3.GetObjectOutput{
+		Body:     ioutil.NopCloser(bytes.NewReader([]byte("compressed"))),
+		Metadata: map[string]*string{"Encoding": aws.String("gzip")},
+	}
+	mockS3 := NewMockS3API(ctrl)
+	mockS3.EXPECT().GetObjectWithContext(gomock.Any(), gomock.Any()).Return(output, nil)
+
+	storage := &S3{
+		Bucket: bucket,
+		Client: mockS3,
+	}
+
+	r, err := storage.Get(context.Background(), key)
+	assert.Nil(t, err)
+	defer r.Close()
+	assert.Equal(t, "gzip", types.ContentEncoding(r))
+}
+
+func TestStoreEncoded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUploader := NewMockUploaderAPI(ctrl)
+	mockUploader.EXPECT().UploadWithContext(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, input *s3manager.UploadInput) (interface{}, error) {
+		assert.Equal(t, map[string]*string{"encoding": aws.String("gzip")}, input.Metadata)
+		return &s3manager.UploadOutput{}, nil
+	})
+
+	storage := &S3{
+		Bucket:   bucket,
+		uploader: mockUploader,
+	}
+
+	storage.once.Do(func() {}) // trigger the once call
+
+	input := types.EncodedReadCloser{ReadCloser: ioutil.NopCloser(bytes.NewReader([]byte("compressed"))), Encoding: "gzip"}
+	err := storage.Store(context.Background(), key, input)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,99 @@
+// Package storagetest provides an in memory types.Storage for the tests of the modules which keep their
+// state in the graph storage.
+package storagetest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/types"
+)
+
+// Memory is an in memory types.Storage whose objects can be inspected and seeded by tests. Every operation
+// fails with Err when it is set.
+type Memory struct {
+	Objects map[string][]byte
+	Err     error
+	lock    sync.Mutex
+}
+
+// NewMemory returns an empty Memory storage
+func NewMemory() *Memory {
+	return &Memory{Objects: make(map[string][]byte)}
+}
+
+// Get returns the object stored under key
+func (s *Memory) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	b, ok := s.Objects[key]
+	if !ok {
+		return nil, types.ErrNotFound{ID: key}
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+// Exists reports whether an object is stored under key
+func (s *Memory) Exists(_ context.Context, key string) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.Err != nil {
+		return false, s.Err
+	}
+	_, ok := s.Objects[key]
+	return ok, nil
+}
+
+// Store stores the data under key
+func (s *Memory) Store(_ context.Context, key string, data io.ReadCloser) error {
+	b, err := ioutil.ReadAll(data)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	if err != nil {
+		return err
+	}
+	s.Objects[key] = b
+	return nil
+}
+
+// Delete removes the object stored under key
+func (s *Memory) Delete(_ context.Context, key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	delete(s.Objects, key)
+	return nil
+}
+
+// List returns, in order, up to limit keys which begin with prefix and sort after the given key
+func (s *Memory) List(_ context.Context, prefix string, after string, limit int) ([]string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	var keys []string
+	for key := range s.Objects {
+		if strings.HasPrefix(key, prefix) && key > after {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys, nil
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Zstd is the Codec of the zstd content encoding
+type Zstd struct{}
+
+// Encoding returns zstd
+func (Zstd) Encoding() string {
+	return "zstd"
+}
+
+// NewWriter returns a writer which compresses to w
+func (Zstd) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+}
+
+// NewReader returns a reader which decompresses r. Closing the reader releases the decoder.
+func (Zstd) NewReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
@@ -0,0 +1,20 @@
+package types
+
+import (
+	"context"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+)
+
+// Annotator provides an interface for analyzing a graph before it is stored. Annotators may add
+// attributes to the nodes and edges of the graph, and return any findings made during the analysis.
+type Annotator interface {
+	Annotate(ctx context.Context, id string, g *graph.Graph) ([]Finding, error)
+}
+
+// Committer is implemented by the Annotators which learn from the graphs they annotate. Commit is called once
+// the annotated graph is stored, so that what they learn only reflects the stored graphs. Committing the same
+// graph again, such as when it is regenerated, replaces what was learned from it.
+type Committer interface {
+	Commit(ctx context.Context, id string, g *graph.Graph) error
+}
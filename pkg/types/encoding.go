@@ -0,0 +1,59 @@
+package types
+
+import (
+	"context"
+	"io"
+)
+
+// Codec compresses and decompresses the objects stored with a content encoding
+type Codec interface {
+	// Encoding is the name of the content encoding, as used in the Content-Encoding HTTP header
+	Encoding() string
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// Encoded is implemented by the readers whose content is encoded, such as those passed to Storage.Store to
+// store an encoded object, or returned by Storage.Get for an object which is returned as stored
+type Encoded interface {
+	ContentEncoding() string
+}
+
+// EncodedReadCloser is an io.ReadCloser whose content is encoded with Encoding
+type EncodedReadCloser struct {
+	io.ReadCloser
+	Encoding string
+}
+
+// ContentEncoding returns the content encoding of the reader
+func (r EncodedReadCloser) ContentEncoding() string {
+	return r.Encoding
+}
+
+// ContentEncoding returns the content encoding of a reader, or an empty string if it is not encoded
+func ContentEncoding(r io.Reader) string {
+	if e, ok := r.(Encoded); ok {
+		return e.ContentEncoding()
+	}
+	return ""
+}
+
+type acceptEncodingKey struct{}
+
+// WithAcceptEncoding returns a context which accepts the given content encodings from Storage.Get, such that
+// an object stored with one of them may be returned as stored rather than decoded. The reader returned for
+// such an object implements Encoded.
+func WithAcceptEncoding(ctx context.Context, encodings ...string) context.Context {
+	return context.WithValue(ctx, acceptEncodingKey{}, encodings)
+}
+
+// AcceptsEncoding returns true if the context accepts the content encoding
+func AcceptsEncoding(ctx context.Context, encoding string) bool {
+	encodings, _ := ctx.Value(acceptEncodingKey{}).([]string)
+	for _, e := range encodings {
+		if e == encoding {
+			return true
+		}
+	}
+	return false
+}
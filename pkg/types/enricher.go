@@ -0,0 +1,14 @@
+package types
+
+import (
+	"context"
+	"time"
+)
+
+// Enricher provides an interface for annotating graph nodes with additional attributes. Because
+// addresses are reused over time, the time range in which the address was observed is also provided.
+type Enricher interface {
+	// Enrich returns the attributes known for addr between start and stop. An empty result
+	// indicates that nothing is known about the address.
+	Enrich(ctx context.Context, addr string, start, stop time.Time) (map[string]string, error)
+}
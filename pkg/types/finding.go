@@ -0,0 +1,18 @@
+package types
+
+import "time"
+
+// Finding is a notable observation made while analyzing a graph
+type Finding struct {
+	// Key identifies the observation independently of the graph in which it was made, such that
+	// the same observation made in overlapping windows shares the same key.
+	Key         string            `json:"key"`
+	Kind        string            `json:"kind"`
+	GraphID     string            `json:"graphID"`
+	Start       time.Time         `json:"start"`
+	Stop        time.Time         `json:"stop"`
+	Source      string            `json:"source,omitempty"`
+	Destination string            `json:"destination,omitempty"`
+	Description string            `json:"description"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+}
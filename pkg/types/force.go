@@ -0,0 +1,19 @@
+package types
+
+import (
+	"context"
+)
+
+type forceKey struct{}
+
+// WithForce returns a context which flags the graph being queued or produced as a forced regeneration of an
+// existing graph. Modules which cache intermediate results, such as digests, bypass their cache when forced.
+func WithForce(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceKey{}, true)
+}
+
+// ForceFromContext returns true if the context flags a forced regeneration
+func ForceFromContext(ctx context.Context) bool {
+	force, _ := ctx.Value(forceKey{}).(bool)
+	return force
+}
@@ -3,9 +3,10 @@ package types
 import (
 	"context"
 	"io"
+	"time"
 )
 
-// Grapher provides an interface for creating graphs for a provided digest
+// Grapher provides an interface for creating graphs for a provided digest of the window from start to stop
 type Grapher interface {
-	Graph(context.Context, string, io.ReadCloser) error
+	Graph(ctx context.Context, id string, start, stop time.Time, digest io.ReadCloser) error
 }
@@ -0,0 +1,22 @@
+package types
+
+import "context"
+
+// Notifier provides an interface for pushing the findings made while analyzing a graph
+type Notifier interface {
+	Notify(ctx context.Context, findings []Finding) error
+}
+
+type withoutNotifyKey struct{}
+
+// WithoutNotify returns a context which flags the graphs produced with it as historical, such as the graphs of
+// a backfill, whose findings are not sent to the Notifier
+func WithoutNotify(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutNotifyKey{}, true)
+}
+
+// NotifyFromContext returns false if the context flags that findings are not sent to the Notifier
+func NotifyFromContext(ctx context.Context) bool {
+	without, _ := ctx.Value(withoutNotifyKey{}).(bool)
+	return !without
+}
@@ -0,0 +1,16 @@
+package types
+
+import (
+	"context"
+	"time"
+
+	"github.com/asecurityteam/vpcflow-grapherd/pkg/graph"
+)
+
+// GraphSource provides an interface for loading the graph of a window of time for analysis
+type GraphSource interface {
+	// Load returns the graph identified by id, covering start to stop. If the graph has not been
+	// stored, it is built from a new digest of the window. If start and stop are zero, only a stored
+	// graph is returned and an error of type ErrNotFound is returned if it does not exist.
+	Load(ctx context.Context, id string, start, stop time.Time) (*graph.Graph, error)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"time"
 )
 
 // ErrNotFound represents a resource lookup that failed due to a missing record.
@@ -15,6 +16,16 @@ func (e ErrNotFound) Error() string {
 	return fmt.Sprintf("digest %s was not found", e.ID)
 }
 
+// ErrNotPresignable indicates that the storage cannot presign the URL of an object, such as when the
+// Storage it decorates is not a Presigner
+type ErrNotPresignable struct {
+	Key string
+}
+
+func (e ErrNotPresignable) Error() string {
+	return fmt.Sprintf("digest %s cannot be presigned", e.Key)
+}
+
 // Storage is an interface for accessing created digests. It is the caller's responsibility to call Close on the Reader when done.
 type Storage interface {
 	// Get returns the digest for the given key.
@@ -25,4 +36,28 @@ type Storage interface {
 
 	// Store stores the digest
 	Store(ctx context.Context, key string, data io.ReadCloser) error
+
+	// Delete removes the digest. Deleting a digest which does not exist is not an error.
+	Delete(ctx context.Context, key string) error
+
+	// List returns up to limit keys which start with prefix, in lexicographical order, after the key after.
+	// An empty after lists from the first key.
+	List(ctx context.Context, prefix string, after string, limit int) ([]string, error)
+}
+
+// Presigned is a short lived URL from which a stored object is downloaded directly
+type Presigned struct {
+	URL string
+	// Size is the size of the object as stored, in bytes
+	Size int64
+	// Encoding is the content encoding the object is stored with, if any
+	Encoding string
+}
+
+// Presigner is an optional capability of a Storage, which provides the URLs to download objects directly
+// from the backing store rather than through the service
+type Presigner interface {
+	// Presign returns a URL from which the object is downloaded until it expires. An error of type ErrNotFound
+	// is returned if the object does not exist.
+	Presign(ctx context.Context, key string, expires time.Duration) (Presigned, error)
 }